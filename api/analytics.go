@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/analytics"
+	"github.com/nmdra/Semantic-Search/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+const maxAnalyticsWindow = 90 * 24 * time.Hour
+
+type AnalyticsHandler struct {
+	Service *analytics.Service
+}
+
+type FeedbackRequest struct {
+	EventID string `json:"event_id"`
+	ISBN    string `json:"isbn"`
+	Type    string `json:"type"`
+}
+
+// POST /feedback
+func (h *AnalyticsHandler) Feedback(c echo.Context) error {
+	var req FeedbackRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if err := h.Service.RecordFeedback(c.Request().Context(), req.EventID, req.ISBN, req.Type); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+// GET /admin/analytics/top-queries?window=24h&limit=20
+func (h *AnalyticsHandler) TopQueries(c echo.Context) error {
+	window, limit, err := windowAndLimit(c)
+	if err != nil {
+		return err
+	}
+	rows, err := h.Service.TopQueries(c.Request().Context(), window, limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, rows)
+}
+
+// GET /admin/analytics/zero-results?window=24h&limit=20
+func (h *AnalyticsHandler) ZeroResults(c echo.Context) error {
+	window, limit, err := windowAndLimit(c)
+	if err != nil {
+		return err
+	}
+	rows, err := h.Service.ZeroResultQueries(c.Request().Context(), window, limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, rows)
+}
+
+// GET /admin/analytics/low-scores?window=24h&threshold=0.5&limit=20
+func (h *AnalyticsHandler) LowScores(c echo.Context) error {
+	window, limit, err := windowAndLimit(c)
+	if err != nil {
+		return err
+	}
+	threshold := 0.5
+	if v := c.QueryParam("threshold"); v != "" {
+		threshold, err = strconv.ParseFloat(v, 64)
+		if err != nil || threshold < -1 || threshold > 1 {
+			return service.Validation("invalid_threshold", "threshold must be a similarity between -1 and 1")
+		}
+	}
+	rows, err := h.Service.LowScoreQueries(c.Request().Context(), window, threshold, limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, rows)
+}
+
+// GET /admin/analytics/latency?window=24h
+func (h *AnalyticsHandler) Latency(c echo.Context) error {
+	window, _, err := windowAndLimit(c)
+	if err != nil {
+		return err
+	}
+	rows, err := h.Service.Latency(c.Request().Context(), window)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, rows)
+}
+
+// windowAndLimit reads the window (a Go duration or whole days such as "7d";
+// default 24h) and limit (1-500; default 20) query parameters.
+func windowAndLimit(c echo.Context) (time.Duration, int32, error) {
+	window := 24 * time.Hour
+	if v := c.QueryParam("window"); v != "" {
+		var err error
+		window, err = parseWindow(v)
+		if err != nil || window <= 0 || window > maxAnalyticsWindow {
+			return 0, 0, service.Validation("invalid_window", "window must be a duration such as 24h or 7d, at most 90d")
+		}
+	}
+
+	limit := 20
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			return 0, 0, service.Validation("invalid_limit", "limit must be between 1 and 500")
+		}
+		limit = n
+	}
+	return window, int32(limit), nil
+}
+
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
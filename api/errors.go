@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/nmdra/Semantic-Search/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Problem is an RFC 9457 problem details body. Code is a stable,
+// machine-readable identifier clients can switch on.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+
+	Errors []service.FieldError `json:"errors,omitempty"`
+}
+
+const problemContentType = "application/problem+json"
+
+var kindStatus = map[service.Kind]int{
+	service.KindNotFound:    http.StatusNotFound,
+	service.KindConflict:    http.StatusConflict,
+	service.KindValidation:  http.StatusUnprocessableEntity,
+	service.KindUnavailable: http.StatusServiceUnavailable,
+	service.KindRateLimited: http.StatusTooManyRequests,
+}
+
+// NewHTTPErrorHandler returns an Echo error handler that renders every error
+// as problem+json. Domain errors keep their code and message; anything else
+// is logged and reported as a generic 500 so internals never reach clients.
+func NewHTTPErrorHandler(logger *slog.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		problem := toProblem(err)
+		problem.Instance = c.Request().URL.Path
+
+		if problem.Status >= http.StatusInternalServerError {
+			logger.Error("Request failed", "path", c.Path(), "status", problem.Status, "error", err)
+		} else {
+			logger.Debug("Request rejected", "path", c.Path(), "status", problem.Status, "error", err)
+		}
+
+		var writeErr error
+		if c.Request().Method == http.MethodHead {
+			writeErr = c.NoContent(problem.Status)
+		} else {
+			// c.JSON keeps an explicitly set content type.
+			c.Response().Header().Set(echo.HeaderContentType, problemContentType)
+			writeErr = c.JSON(problem.Status, problem)
+		}
+		if writeErr != nil {
+			logger.Warn("Failed to write error response", "error", writeErr)
+		}
+	}
+}
+
+func toProblem(err error) Problem {
+	if domainErr, ok := service.AsError(err); ok {
+		status, known := kindStatus[domainErr.Kind]
+		if !known {
+			return internalProblem()
+		}
+		return Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(status),
+			Status: status,
+			Detail: domainErr.Message,
+			Code:   domainErr.Code,
+			Errors: domainErr.Fields,
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusRequestTimeout),
+			Status: http.StatusRequestTimeout,
+			Detail: "request canceled or timed out",
+			Code:   "request_timeout",
+		}
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		detail, _ := httpErr.Message.(string)
+		return Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(httpErr.Code),
+			Status: httpErr.Code,
+			Detail: detail,
+			Code:   statusCode(httpErr.Code),
+		}
+	}
+
+	return internalProblem()
+}
+
+func internalProblem() Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusInternalServerError),
+		Status: http.StatusInternalServerError,
+		Detail: "internal server error",
+		Code:   "internal_error",
+	}
+}
+
+// statusCode derives a code such as "not_found" from a status text.
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
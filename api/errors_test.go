@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nmdra/Semantic-Search/internal/service"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{
+			name:       "Conflict",
+			err:        service.Conflict("book_exists", "book with isbn 123 already exists"),
+			wantStatus: http.StatusConflict,
+			wantCode:   "book_exists",
+			wantDetail: "book with isbn 123 already exists",
+		},
+		{
+			name:       "Wrapped not found",
+			err:        fmt.Errorf("lookup: %w", service.NotFound("book_not_found", "no such book")),
+			wantStatus: http.StatusNotFound,
+			wantCode:   "book_not_found",
+			wantDetail: "no such book",
+		},
+		{
+			name:       "Validation",
+			err:        service.Validation("empty_query", "search query cannot be empty"),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "empty_query",
+		},
+		{
+			name:       "Upstream unavailable hides cause",
+			err:        service.Unavailable("embedding_unavailable", "embedding service unavailable", errors.New("dial tcp 10.0.0.1:443: refused")),
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "embedding_unavailable",
+			wantDetail: "embedding service unavailable",
+		},
+		{
+			name:       "Rate limited",
+			err:        service.RateLimited("embedding_rate_limited", "slow down", nil),
+			wantStatus: http.StatusTooManyRequests,
+			wantCode:   "embedding_rate_limited",
+		},
+		{
+			name:       "Echo HTTP error",
+			err:        echo.ErrNotFound,
+			wantStatus: http.StatusNotFound,
+			wantCode:   "not_found",
+		},
+		{
+			name:       "Internal error is not leaked",
+			err:        errors.New("pq: relation \"books\" does not exist"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal_error",
+			wantDetail: "internal server error",
+		},
+	}
+
+	handler := NewHTTPErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	e := echo.New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/search/semantic", nil)
+			rec := httptest.NewRecorder()
+
+			handler(tt.err, e.NewContext(req, rec))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, problemContentType, rec.Header().Get(echo.HeaderContentType))
+
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, tt.wantStatus, problem.Status)
+			assert.Equal(t, tt.wantCode, problem.Code)
+			assert.Equal(t, "/search/semantic", problem.Instance)
+			if tt.wantDetail != "" {
+				assert.Equal(t, tt.wantDetail, problem.Detail)
+			}
+		})
+	}
+}
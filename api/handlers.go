@@ -1,90 +1,367 @@
 package api
 
 import (
+	"github.com/nmdra/Semantic-Search/internal/analytics"
 	"github.com/nmdra/Semantic-Search/internal/service"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("github.com/nmdra/Semantic-Search/api")
+
 type BookHandler struct {
 	Service *service.BookService
+	// AsyncIngest makes POST /books enqueue the embedding instead of waiting for it.
+	AsyncIngest bool
+	// Analytics records every search; optional.
+	Analytics *analytics.Recorder
 }
 
+// Search analytics headers. The tenant is supplied by the caller (typically a
+// gateway); the event ID is returned so clients can send feedback.
+const (
+	HeaderTenant        = "X-Tenant-ID"
+	HeaderSearchEventID = "X-Search-Event-Id"
+)
+
 type AddBookRequest struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Isbn        string `json:"isbn"`
+	// Language is optional: an ISO 639-1 code such as "es".
+	Language string `json:"language"`
+
+	Author        string   `json:"author"`
+	Genres        []string `json:"genres"`
+	PublishedYear int      `json:"published_year"`
 }
 
-// POST /books
+// Errors returned from handlers are rendered by the HTTPErrorHandler from
+// NewHTTPErrorHandler.
+
+// POST /books[?upsert=true|?async=true]
 func (h *BookHandler) AddBook(c echo.Context) error {
-	ctx := c.Request().Context()
+	ctx, span := tracer.Start(c.Request().Context(), "BookHandler.AddBook")
+	defer span.End()
 	var req AddBookRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "invalid request"})
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
 	}
 
-	select {
-	case <-ctx.Done():
-		return c.JSON(http.StatusRequestTimeout, echo.Map{"error": "request canceled or timed out"})
-	default:
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	book := service.BookInput{
+		ISBN:        req.Isbn,
+		Title:       req.Title,
+		Description: req.Description,
+		Language:    req.Language,
+
+		Author:        req.Author,
+		Genres:        req.Genres,
+		PublishedYear: req.PublishedYear,
+	}
+
+	async := h.AsyncIngest || c.QueryParam("async") == "true"
+	if async && c.QueryParam("upsert") == "true" {
+		result, err := h.Service.UpsertBookAsync(ctx, book)
+		if err != nil {
+			return err
+		}
+		if result.EmbeddingStatus == service.EmbeddingPending {
+			statusURL := "/books/" + result.ISBN + "/status"
+			c.Response().Header().Set(echo.HeaderLocation, statusURL)
+			return c.JSON(http.StatusAccepted, echo.Map{
+				"id":               result.ID,
+				"isbn":             result.ISBN,
+				"status":           result.Outcome,
+				"embedding_status": result.EmbeddingStatus,
+				"status_url":       statusURL,
+			})
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+
+	if async {
+		isbn, err := h.Service.AddBookAsync(ctx, book)
+		if err != nil {
+			return err
+		}
+		statusURL := "/books/" + isbn + "/status"
+		c.Response().Header().Set(echo.HeaderLocation, statusURL)
+		return c.JSON(http.StatusAccepted, echo.Map{
+			"status":     service.EmbeddingPending,
+			"isbn":       isbn,
+			"status_url": statusURL,
+		})
+	}
+
+	if c.QueryParam("upsert") == "true" {
+		result, err := h.Service.UpsertBook(ctx, book)
+		if err != nil {
+			return err
+		}
+		if result.Outcome == service.UpsertCreated {
+			return c.JSON(http.StatusCreated, result)
+		}
+		return c.JSON(http.StatusOK, result)
 	}
 
-	err := h.Service.AddBook(ctx, req.Isbn, req.Title, req.Description)
+	err := h.Service.AddBook(ctx, book)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		return err
 	}
 
 	return c.JSON(http.StatusCreated, echo.Map{"status": "book added"})
 }
 
-// GET /search/vector?q=
+// GET /search/vector?q=[&diversity=0.3][&collapse=work][&facets=genre,author][&genre=&author=&lang=&decade=]
 func (h *BookHandler) SearchBooks(c echo.Context) error {
-	ctx := c.Request().Context()
+	ctx, span := tracer.Start(c.Request().Context(), "BookHandler.SearchBooks")
+	defer span.End()
 	query := c.QueryParam("q")
 	if query == "" {
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "missing query"})
+		return service.Validation("missing_query", "query parameter q is required")
+	}
+	opts, err := searchOptions(c)
+	if err != nil {
+		return err
 	}
 
-	select {
-	case <-ctx.Done():
-		return c.JSON(http.StatusRequestTimeout, echo.Map{"error": "request canceled or timed out"})
-	default:
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	results, err := h.Service.SearchBooks(ctx, query)
+	start := time.Now()
+	results, err := h.Service.SearchBooks(ctx, query, opts)
 	if err != nil {
-		if ctx.Err() != nil {
-			return c.JSON(http.StatusRequestTimeout, echo.Map{"error": "request timed out"})
-		}
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
+		return err
 	}
 
+	event := analytics.Event{Query: query, Mode: analytics.ModeSemantic, Degraded: results.Degraded}
+	for _, r := range results.Results {
+		event.ResultISBNs = append(event.ResultISBNs, r.ISBN)
+	}
+	if len(results.Results) > 0 && !results.Degraded {
+		event.TopScore = &results.Results[0].Similarity
+	}
+	results.EventID = h.recordSearch(c, event, start)
+
 	return c.JSON(http.StatusOK, results)
 }
 
-// GET /search/text?q=
+// GET /search/text?q=[&lang=es][&collapse=work][&facets=genre,author][&genre=&author=&decade=]
+//
+// The response is always an object holding results, and facets when asked
+// for, like /search/vector. Clients of the original endpoint, which returned
+// a plain array, must read the results field.
 func (h *BookHandler) FullTextSearch(c echo.Context) error {
-	ctx := c.Request().Context()
+	ctx, span := tracer.Start(c.Request().Context(), "BookHandler.FullTextSearch")
+	defer span.End()
 	query := c.QueryParam("q")
 	if query == "" {
-		return c.JSON(http.StatusBadRequest, echo.Map{"error": "missing query"})
+		return service.Validation("missing_query", "query parameter q is required")
+	}
+	opts, err := searchOptions(c)
+	if err != nil {
+		return err
 	}
 
-	select {
-	case <-ctx.Done():
-		return c.JSON(http.StatusRequestTimeout, echo.Map{"error": "request canceled or timed out"})
-	default:
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	results, err := h.Service.FullTextSearch(ctx, query)
+	start := time.Now()
+	results, err := h.Service.FullTextSearch(ctx, query, opts)
 	if err != nil {
-		if ctx.Err() != nil {
-			return c.JSON(http.StatusRequestTimeout, echo.Map{"error": "request timed out"})
+		return err
+	}
+
+	event := analytics.Event{Query: query, Mode: analytics.ModeText}
+	for _, r := range results.Results {
+		event.ResultISBNs = append(event.ResultISBNs, r.Isbn.String)
+	}
+	h.recordSearch(c, event, start)
+
+	return c.JSON(http.StatusOK, results)
+}
+
+// GET /search/fuzzy?q=hary+poter[&threshold=0.4]
+func (h *BookHandler) FuzzySearch(c echo.Context) error {
+	ctx, span := tracer.Start(c.Request().Context(), "BookHandler.FuzzySearch")
+	defer span.End()
+	query := c.QueryParam("q")
+	if query == "" {
+		return service.Validation("missing_query", "query parameter q is required")
+	}
+
+	var threshold float64
+	if v := c.QueryParam("threshold"); v != "" {
+		var err error
+		threshold, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return service.Validation("invalid_threshold", "threshold must be a number")
 		}
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": err.Error()})
 	}
 
+	start := time.Now()
+	results, err := h.Service.FuzzySearch(ctx, query, threshold)
+	if err != nil {
+		return err
+	}
+
+	event := analytics.Event{Query: query, Mode: analytics.ModeFuzzy}
+	for _, r := range results {
+		event.ResultISBNs = append(event.ResultISBNs, r.Isbn.String)
+	}
+	h.recordSearch(c, event, start)
+
 	return c.JSON(http.StatusOK, results)
 }
+
+// GET /suggest?q=pri&limit=8
+//
+// Suggestions are not recorded as searches: they fire on every keystroke.
+func (h *BookHandler) Suggest(c echo.Context) error {
+	ctx, span := tracer.Start(c.Request().Context(), "BookHandler.Suggest")
+	defer span.End()
+
+	limit := 8
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 20 {
+			return service.Validation("invalid_limit", "limit must be between 1 and 20")
+		}
+		limit = n
+	}
+
+	suggestions, err := h.Service.Suggest(ctx, c.QueryParam("q"), limit)
+	if err != nil {
+		return err
+	}
+
+	// Let browsers and CDNs absorb repeated keystrokes.
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=60")
+	return c.JSON(http.StatusOK, suggestions)
+}
+
+// DELETE /books/:isbn
+func (h *BookHandler) DeleteBook(c echo.Context) error {
+	ctx, span := tracer.Start(c.Request().Context(), "BookHandler.DeleteBook")
+	defer span.End()
+
+	if err := h.Service.DeleteBook(ctx, c.Param("isbn")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// recordSearch completes and queues a search event and returns its ID, or ""
+// if analytics are disabled or the event was dropped.
+func (h *BookHandler) recordSearch(c echo.Context, event analytics.Event, start time.Time) string {
+	if h.Analytics == nil {
+		return ""
+	}
+
+	event.ID = analytics.NewEventID()
+	event.Time = start
+	event.Latency = time.Since(start)
+	event.Tenant = c.Request().Header.Get(HeaderTenant)
+	if !h.Analytics.Record(event) {
+		return ""
+	}
+
+	id := event.ID.String()
+	c.Response().Header().Set(HeaderSearchEventID, id)
+	return id
+}
+
+// GET /admin/books/duplicates?max_distance=0.2[&after=0][&limit=500]
+func (h *BookHandler) Duplicates(c echo.Context) error {
+	ctx, span := tracer.Start(c.Request().Context(), "BookHandler.Duplicates")
+	defer span.End()
+
+	var maxDistance float64
+	if v := c.QueryParam("max_distance"); v != "" {
+		var err error
+		maxDistance, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return service.Validation("invalid_max_distance", "max_distance must be a number")
+		}
+	}
+	var after int32
+	if v := c.QueryParam("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return service.Validation("invalid_after", "after must be a book ID")
+		}
+		after = int32(n)
+	}
+	limit := 500
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 5000 {
+			return service.Validation("invalid_limit", "limit must be between 1 and 5000")
+		}
+		limit = n
+	}
+
+	page, err := h.Service.SuspectedDuplicates(ctx, maxDistance, after, limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// GET /books/:isbn/status
+func (h *BookHandler) GetBookStatus(c echo.Context) error {
+	ctx, span := tracer.Start(c.Request().Context(), "BookHandler.GetBookStatus")
+	defer span.End()
+
+	status, err := h.Service.GetBookStatus(ctx, c.Param("isbn"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// searchOptions reads the facets to count and the drill-down filters shared
+// by the search endpoints.
+func searchOptions(c echo.Context) (service.SearchOptions, error) {
+	var opts service.SearchOptions
+	var err error
+	if v := c.QueryParam("facets"); v != "" {
+		if opts.Facets, err = service.ParseFacets(v); err != nil {
+			return service.SearchOptions{}, err
+		}
+	}
+	if v := c.QueryParam("decade"); v != "" {
+		if opts.Filters.Decade, err = service.ParseDecade(v); err != nil {
+			return service.SearchOptions{}, err
+		}
+	}
+	if v := c.QueryParam("diversity"); v != "" {
+		diversity, err := strconv.ParseFloat(v, 64)
+		if err != nil || diversity < 0 || diversity > 1 {
+			return service.SearchOptions{}, service.Validation("invalid_diversity", "diversity must be between 0 and 1")
+		}
+		opts.Diversity = &diversity
+	}
+	switch v := c.QueryParam("collapse"); v {
+	case "", service.CollapseWork:
+		opts.Collapse = v
+	default:
+		return service.SearchOptions{}, service.Validation("invalid_collapse", "collapse must be \"work\"")
+	}
+	opts.Filters.Genre = c.QueryParam("genre")
+	opts.Filters.Author = c.QueryParam("author")
+	opts.Filters.Language = c.QueryParam("lang")
+	return opts, nil
+}
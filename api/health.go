@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/nmdra/Semantic-Search/internal/health"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HealthHandler struct {
+	Checker *health.Checker
+}
+
+// GET /healthz
+func (h *HealthHandler) Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{"status": health.StatusUp})
+}
+
+// GET /readyz
+func (h *HealthHandler) Readiness(c echo.Context) error {
+	report := h.Checker.Run(c.Request().Context())
+	if !report.Healthy() {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/idempotency"
+	"github.com/nmdra/Semantic-Search/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	// idempotencyStoreTimeout bounds saving the response once the request
+	// context may already be done.
+	idempotencyStoreTimeout = 2 * time.Second
+)
+
+// Idempotency replays the stored response for requests that repeat an
+// Idempotency-Key header. Requests without the header pass through.
+// Responses with a 5xx status are not stored, so the client can retry them.
+func Idempotency(store *idempotency.Store, logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return service.Validation("invalid_idempotency_key", "Idempotency-Key must be at most 255 characters")
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "failed to read request body")
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := c.Request().Context()
+			stored, err := store.Begin(ctx, key, requestHash(c.Request(), body))
+			switch {
+			case errors.Is(err, idempotency.ErrInFlight):
+				return service.Conflict("idempotency_key_in_flight", err.Error())
+			case errors.Is(err, idempotency.ErrMismatch):
+				return service.Validation("idempotency_key_reused", err.Error())
+			case err != nil:
+				return err
+			case stored != nil:
+				c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+				return c.Blob(stored.StatusCode, stored.ContentType, stored.Body)
+			}
+
+			rec := &bodyRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = rec
+
+			// Render errors here rather than in the outer error handler so the
+			// problem+json body is captured too.
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			// The request context may already be done (timeout, disconnect).
+			storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+			defer cancel()
+
+			status := c.Response().Status
+			if status >= http.StatusInternalServerError || status == http.StatusRequestTimeout {
+				if err := store.Release(storeCtx, key); err != nil {
+					logger.Warn("Failed to release idempotency key", "key", key, "error", err)
+				}
+				return nil
+			}
+
+			err = store.Complete(storeCtx, key, idempotency.Response{
+				StatusCode:  status,
+				ContentType: c.Response().Header().Get(echo.HeaderContentType),
+				Body:        rec.buf.Bytes(),
+			})
+			if err != nil {
+				logger.Warn("Failed to store idempotent response", "key", key, "error", err)
+			}
+			return nil
+		}
+	}
+}
+
+// requestHash fingerprints the request a key is used with, so a key cannot
+// silently be reused for a different payload.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type bodyRecorder struct {
+	http.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.buf.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/nmdra/Semantic-Search/internal/service"
+	"github.com/nmdra/Semantic-Search/internal/webhook"
+
+	"github.com/labstack/echo/v4"
+)
+
+type WebhookHandler struct {
+	Service *webhook.Service
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+// POST /admin/webhooks
+func (h *WebhookHandler) Create(c echo.Context) error {
+	var req CreateWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	sub, err := h.Service.Create(c.Request().Context(), req.URL, req.Secret, req.EventTypes)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, sub)
+}
+
+// GET /admin/webhooks
+func (h *WebhookHandler) List(c echo.Context) error {
+	subs, err := h.Service.List(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, subs)
+}
+
+// DELETE /admin/webhooks/:id
+func (h *WebhookHandler) Delete(c echo.Context) error {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		return err
+	}
+	if err := h.Service.Delete(c.Request().Context(), int32(id)); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// POST /admin/webhooks/:id/replay replays every dead delivery of a subscription.
+func (h *WebhookHandler) ReplayDead(c echo.Context) error {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		return err
+	}
+	n, err := h.Service.ReplayDead(c.Request().Context(), int32(id))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, echo.Map{"replayed": n})
+}
+
+// GET /admin/webhooks/deliveries?status=dead&limit=50
+func (h *WebhookHandler) Deliveries(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status == "" {
+		status = webhook.StatusDead
+	}
+	limit := 50
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			return service.Validation("invalid_limit", "limit must be between 1 and 500")
+		}
+		limit = n
+	}
+
+	deliveries, err := h.Service.Deliveries(c.Request().Context(), status, int32(limit))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// POST /admin/webhooks/deliveries/:id/replay
+func (h *WebhookHandler) ReplayDelivery(c echo.Context) error {
+	id, err := parseID(c.Param("id"))
+	if err != nil {
+		return err
+	}
+	if err := h.Service.ReplayDelivery(c.Request().Context(), id); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, echo.Map{"status": webhook.StatusPending})
+}
+
+func parseID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || id < 1 {
+		return 0, service.Validation("invalid_id", "id must be a positive integer")
+	}
+	return id, nil
+}
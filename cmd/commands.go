@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/analytics"
+	"github.com/nmdra/Semantic-Search/internal/db"
+	"github.com/nmdra/Semantic-Search/internal/embed"
+	"github.com/nmdra/Semantic-Search/internal/repository"
+	"github.com/nmdra/Semantic-Search/internal/vectorindex"
+	"github.com/nmdra/Semantic-Search/internal/vectorstore"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// runCommand runs an admin subcommand given after the flags.
+func runCommand(ctx context.Context, cfg config, args []string, logger *slog.Logger) error {
+	switch args[0] {
+	case "cache":
+		return runCacheCommand(ctx, cfg, args[1:], logger)
+	case "report":
+		return runReportCommand(ctx, cfg, args[1:], logger)
+	case "index":
+		return runIndexCommand(ctx, cfg, args[1:], logger)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runCacheCommand inspects or clears the shared embedding cache selected by
+// -cache-backend. In-process LRU tiers of running servers are not affected;
+// their entries expire with -cache-ttl.
+func runCacheCommand(ctx context.Context, cfg config, args []string, logger *slog.Logger) error {
+	if len(args) != 1 || (args[0] != "stats" && args[0] != "flush") {
+		return errors.New("usage: cache stats|flush")
+	}
+
+	dbpool, err := db.NewPool(ctx, cfg.db.dsn, logger)
+	if err != nil {
+		return err
+	}
+	defer dbpool.Close()
+
+	var redisClient *redis.Client
+	if cfg.db.redis != "" {
+		redisClient = db.NewRedisClient(cfg.db.redis, logger)
+		defer func() { _ = redisClient.Close() }()
+	}
+
+	cache, err := newEmbeddingCache(cfg, redisClient, repository.New(dbpool))
+	if err != nil {
+		return err
+	}
+	admin, ok := cache.(embed.AdminCache)
+	if !ok {
+		return errors.New("no shared embedding cache configured")
+	}
+
+	if args[0] == "flush" {
+		n, err := admin.Flush(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Flushed %d cached embeddings\n", n)
+		return nil
+	}
+
+	stats, err := admin.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAMESPACE\tENTRIES\tBYTES\tEXPIRED")
+	var total embed.CacheStats
+	for _, s := range stats {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", s.Namespace, s.Entries, s.Bytes, s.Expired)
+		total.Entries += s.Entries
+		total.Bytes += s.Bytes
+		total.Expired += s.Expired
+	}
+	_, _ = fmt.Fprintf(w, "total\t%d\t%d\t%d\n", total.Entries, total.Bytes, total.Expired)
+	return w.Flush()
+}
+
+// runReportCommand prints search quality reports. "ctr" lists the queries
+// whose results are clicked least often.
+func runReportCommand(ctx context.Context, cfg config, args []string, logger *slog.Logger) error {
+	if len(args) == 0 || args[0] != "ctr" {
+		return errors.New("usage: report ctr [-window 168h] [-min-searches 5] [-limit 20]")
+	}
+
+	fs := flag.NewFlagSet("report ctr", flag.ContinueOnError)
+	window := fs.Duration("window", 7*24*time.Hour, "Trailing window to report on")
+	minSearches := fs.Int64("min-searches", 5, "Ignore queries searched fewer times")
+	limit := fs.Int("limit", 20, "Maximum queries to list")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	dbpool, err := db.NewPool(ctx, cfg.db.dsn, logger)
+	if err != nil {
+		return err
+	}
+	defer dbpool.Close()
+
+	svc := &analytics.Service{Queries: repository.New(dbpool)}
+	rows, err := svc.WorstClickThrough(ctx, *window, *minSearches, int32(*limit))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "QUERY\tSEARCHES\tCLICKED\tCTR")
+	for _, r := range rows {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%.1f%%\n", r.Query, r.Searches, r.Clicked, 100*r.CTR)
+	}
+	return w.Flush()
+}
+
+const indexUsage = "usage: index stats | index create|drop vector|halfvec|binary|matryoshka | index bench [-rows 0] [-queries 100] [-k 10] [-ef-search 100] [-modes vector,halfvec,binary,matryoshka] | index snapshot -out file [-m 16] [-ef-construction 64]"
+
+// runIndexCommand manages the vector indexes that -vector-index selects
+// from, benchmarks them against each other on a copy of the embeddings, and
+// exports the embeddings to an in-process vectorstore snapshot.
+func runIndexCommand(ctx context.Context, cfg config, args []string, logger *slog.Logger) error {
+	if len(args) == 0 {
+		return errors.New(indexUsage)
+	}
+
+	var mode vectorindex.Mode
+	switch args[0] {
+	case "stats", "bench", "snapshot":
+	case "create", "drop":
+		if len(args) != 2 {
+			return errors.New(indexUsage)
+		}
+		var err error
+		if mode, err = vectorindex.ParseMode(args[1]); err != nil {
+			return err
+		}
+	default:
+		return errors.New(indexUsage)
+	}
+
+	var opts vectorindex.BenchOptions
+	if args[0] == "bench" {
+		fs := flag.NewFlagSet("index bench", flag.ContinueOnError)
+		fs.IntVar(&opts.Rows, "rows", 0, "Embedded books to copy for the benchmark (0 means all)")
+		fs.IntVar(&opts.Queries, "queries", 100, "Books sampled as queries")
+		fs.IntVar(&opts.K, "k", 10, "Neighbours per query for recall")
+		fs.IntVar(&opts.EfSearch, "ef-search", 100, "hnsw.ef_search during the queries")
+		modes := fs.String("modes", "vector,halfvec,binary,matryoshka", "Comma-separated modes to compare")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		for _, name := range strings.Split(*modes, ",") {
+			m, err := vectorindex.ParseMode(strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+			opts.Modes = append(opts.Modes, m)
+		}
+	}
+
+	var out string
+	var storeOpts vectorstore.Options
+	if args[0] == "snapshot" {
+		fs := flag.NewFlagSet("index snapshot", flag.ContinueOnError)
+		fs.StringVar(&out, "out", "", "Snapshot file to write")
+		fs.IntVar(&storeOpts.M, "m", 16, "HNSW links per node")
+		fs.IntVar(&storeOpts.EfConstruction, "ef-construction", 64, "HNSW candidate list size while building")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if out == "" {
+			return errors.New(indexUsage)
+		}
+	}
+
+	dbpool, err := db.NewPool(ctx, cfg.db.dsn, logger)
+	if err != nil {
+		return err
+	}
+	defer dbpool.Close()
+
+	switch args[0] {
+	case "create":
+		logger.Info("Building vector index, this can take a while", "mode", mode, "index", mode.IndexName())
+		if err := vectorindex.Create(ctx, dbpool, mode); err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", mode.IndexName())
+		return nil
+	case "drop":
+		if err := vectorindex.Drop(ctx, dbpool, mode); err != nil {
+			return err
+		}
+		fmt.Printf("Dropped %s\n", mode.IndexName())
+		return nil
+	case "bench":
+		results, err := vectorindex.Bench(ctx, dbpool, opts)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(w, "MODE\tROWS\tBUILD\tSIZE\tRECALL@%d\tP50\tP95\n", opts.K)
+		for _, r := range results {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%.3f\t%s\t%s\n",
+				r.Mode, r.Rows, r.BuildTime.Round(time.Millisecond), r.IndexBytes, r.Recall, r.P50, r.P95)
+		}
+		return w.Flush()
+	case "snapshot":
+		n, err := exportSnapshot(ctx, repository.New(dbpool), storeOpts, out)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d books to %s\n", n, out)
+		return nil
+	}
+
+	statuses, err := vectorindex.Stat(ctx, dbpool)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "MODE\tINDEX\tSTATE\tBYTES")
+	for _, s := range statuses {
+		state := "missing"
+		switch {
+		case s.Exists && s.Valid:
+			state = "ready"
+		case s.Exists:
+			state = "invalid"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", s.Mode, s.Mode.IndexName(), state, s.Bytes)
+	}
+	return w.Flush()
+}
+
+// snapshotPageSize is the number of books read per query while exporting.
+const snapshotPageSize = 1000
+
+// exportSnapshot loads every embedded book into an in-process store and
+// saves it to path, keeping the Postgres IDs.
+func exportSnapshot(ctx context.Context, q *repository.Queries, opts vectorstore.Options, path string) (int, error) {
+	store := vectorstore.NewMemory(opts)
+	var after int32
+	for {
+		rows, err := q.ListEmbeddedBooks(ctx, repository.ListEmbeddedBooksParams{
+			After:      after,
+			MaxResults: snapshotPageSize,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to list books: %w", err)
+		}
+		for _, row := range rows {
+			err := store.Add(vectorstore.Book{
+				ID:            row.ID,
+				ISBN:          row.Isbn.String,
+				Title:         row.Title,
+				Description:   row.Description,
+				Embedding:     row.Embedding.Slice(),
+				WorkID:        row.WorkID.Int32,
+				Language:      row.Language,
+				Author:        row.Author.String,
+				Genres:        row.Genres,
+				PublishedYear: int(row.PublishedYear.Int32),
+			})
+			if err != nil {
+				return 0, err
+			}
+			after = row.ID
+		}
+		if len(rows) < snapshotPageSize {
+			break
+		}
+	}
+	if err := store.SaveFile(path); err != nil {
+		return 0, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return store.Len(), nil
+}
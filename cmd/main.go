@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"flag"
 	"fmt"
@@ -9,30 +10,92 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"time"
 
 	"github.com/nmdra/Semantic-Search/api"
+	"github.com/nmdra/Semantic-Search/internal/analytics"
 	"github.com/nmdra/Semantic-Search/internal/db"
 	"github.com/nmdra/Semantic-Search/internal/embed"
+	"github.com/nmdra/Semantic-Search/internal/health"
+	"github.com/nmdra/Semantic-Search/internal/idempotency"
+	"github.com/nmdra/Semantic-Search/internal/lang"
 	"github.com/nmdra/Semantic-Search/internal/repository"
 	"github.com/nmdra/Semantic-Search/internal/service"
+	"github.com/nmdra/Semantic-Search/internal/telemetry"
+	"github.com/nmdra/Semantic-Search/internal/vectorindex"
+	"github.com/nmdra/Semantic-Search/internal/vectorstore"
+	"github.com/nmdra/Semantic-Search/internal/webhook"
+	"github.com/nmdra/Semantic-Search/internal/worker"
 
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/lmittmann/tint"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 	"golang.org/x/time/rate"
 )
 
 type config struct {
-	port     int
-	apiKey   string
-	migrate  bool
-	logLevel string
-	db       struct {
+	port       int
+	apiKey     string
+	adminToken string
+	migrate    bool
+	logLevel   string
+	db         struct {
 		dsn   string
 		redis string
 	}
+	otel struct {
+		exporter string
+		endpoint string
+		insecure bool
+	}
+	ready struct {
+		probeEmbed bool // perform a real embedding call in /readyz, reused for a while
+	}
+	search struct {
+		fallback  bool // degrade semantic search to full-text when embedding fails
+		analytics bool // record every search in search_events
+		// feedbackWeight blends result feedback into semantic ranking; 0 disables
+		feedbackWeight float64
+		diversity      float64           // default MMR diversity of semantic results; 0 disables
+		highlight      service.Highlight // full-text headline markers
+		fuzzyThreshold float64           // default word similarity for /search/fuzzy
+		vectorIndex    vectorindex.Mode  // index that semantic search runs on
+		vectorSnapshot string            // in-process vector store serving semantic search instead
+	}
+	language struct {
+		fallback string // text search configuration when none is given or detected
+		detect   bool   // guess the language of books ingested without one
+	}
+	ingest struct {
+		async   bool                // store books as pending and embed them in the background
+		workers int                 // embedding worker goroutines; 0 disables the worker
+		works   service.WorkMatcher // groups editions of the same work
+	}
+	cache struct {
+		backend  string // shared embedding cache: auto|redis|postgres|none
+		lruSize  int    // in-process LRU entries; 0 disables the local tier
+		ttl      time.Duration
+		encoding string // float32|float16
+	}
+	webhooks struct {
+		dispatchers  int // webhook sender goroutines; 0 disables delivery
+		maxAttempts  int
+		allowPrivate bool          // deliver to loopback and private addresses
+		retention    time.Duration // how long delivered and dead events are kept
+	}
+	embed struct {
+		maxAttempts      int
+		retryBaseDelay   time.Duration
+		retryMaxDelay    time.Duration
+		attemptTimeout   time.Duration
+		breakerThreshold int
+		breakerCooldown  time.Duration
+	}
 }
 
 func main() {
@@ -48,12 +111,37 @@ func main() {
 		return
 	}
 
+	ctx := context.Background()
+
+	if flag.NArg() > 0 {
+		if err := runCommand(ctx, cfg, flag.Args(), logger); err != nil {
+			logger.Error("Command failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if cfg.apiKey == "" {
 		logger.Error("Gemini API key is required")
 		os.Exit(1)
 	}
 
-	ctx := context.Background()
+	shutdownTracing, err := telemetry.Setup(ctx, telemetry.Config{
+		Exporter: cfg.otel.exporter,
+		Endpoint: cfg.otel.endpoint,
+		Insecure: cfg.otel.insecure,
+	})
+	if err != nil {
+		logger.Error("Tracing setup failed", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warn("Failed to flush traces", "error", err)
+		}
+	}()
 
 	dbpool, err := db.NewPool(ctx, cfg.db.dsn, logger)
 	if err != nil {
@@ -63,25 +151,156 @@ func main() {
 	logger.Info("Connected to PostgreSQL")
 	defer dbpool.Close()
 
-	embedder, err := newEmbedder(ctx, cfg, logger)
+	if cfg.search.vectorSnapshot == "" {
+		warnMissingVectorIndex(ctx, dbpool, cfg.search.vectorIndex, logger)
+	}
+
+	var redisClient *redis.Client
+	if cfg.db.redis != "" {
+		redisClient = db.NewRedisClient(cfg.db.redis, logger)
+		defer func() { _ = redisClient.Close() }()
+	}
+
+	repo := repository.New(dbpool)
+
+	embedCache, err := newEmbeddingCache(cfg, redisClient, repo)
 	if err != nil {
-		logger.Error("Failed to setup embedder", "error", err)
+		logger.Error("Failed to setup embedding cache", "error", err)
 		os.Exit(1)
 	}
 
-	repo := repository.New(dbpool)
+	gemini, err := embed.NewGeminiEmbedder(ctx, logger, cfg.apiKey)
+	if err != nil {
+		logger.Error("Failed to setup embedder", "error", err)
+		os.Exit(1)
+	}
+	embedder, err := newEmbedder(cfg, gemini, embedCache, logger)
+	if err != nil {
+		logger.Error("Failed to setup embedder", "error", err)
+		os.Exit(1)
+	}
 	bookService := &service.BookService{
 		Embedder:   embedder,
 		Repository: repo,
 		Logger:     logger,
+		DB:         dbpool,
+
+		FallbackToText: cfg.search.fallback,
+		FeedbackWeight: cfg.search.feedbackWeight,
+		Diversity:      cfg.search.diversity,
+		Highlight:      cfg.search.highlight,
+
+		DefaultLanguage: cfg.language.fallback,
+		DetectLanguage:  cfg.language.detect,
+		FuzzyThreshold:  cfg.search.fuzzyThreshold,
+		Works:           cfg.ingest.works,
+		VectorIndex:     cfg.search.vectorIndex,
+	}
+	if cfg.search.vectorSnapshot != "" {
+		store, err := vectorstore.LoadFile(cfg.search.vectorSnapshot)
+		if err != nil {
+			logger.Error("Failed to load vector snapshot", "path", cfg.search.vectorSnapshot, "error", err)
+			os.Exit(1)
+		}
+		bookService.Vectors = store
+		logger.Info("Serving semantic search from vector snapshot", "path", cfg.search.vectorSnapshot, "books", store.Len())
 	}
 	bookHandler := &api.BookHandler{
-		Service: bookService,
+		Service:     bookService,
+		AsyncIngest: cfg.ingest.async,
+	}
+
+	// Background jobs run until the HTTP server has shut down.
+	bgCtx, stopBackground := context.WithCancel(ctx)
+	var background sync.WaitGroup
+
+	idempotencyStore := &idempotency.Store{Queries: repo, TTL: 24 * time.Hour}
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		runPurge(bgCtx, "idempotency keys", time.Hour, idempotencyStore.Purge, logger)
+	}()
+
+	if pgCache, ok := embedCache.(*embed.PostgresCache); ok {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			runPurge(bgCtx, "embedding cache", 10*time.Minute, pgCache.Purge, logger)
+		}()
+	}
+
+	if cfg.ingest.workers > 0 {
+		embeddingWorker := &worker.EmbeddingWorker{
+			Queries:     repo,
+			Embedder:    embedder,
+			Logger:      logger,
+			DB:          dbpool,
+			Works:       cfg.ingest.works,
+			Concurrency: cfg.ingest.workers,
+		}
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			embeddingWorker.Run(bgCtx)
+		}()
+	}
+
+	if cfg.webhooks.dispatchers > 0 {
+		dispatcher := &webhook.Dispatcher{
+			Queries:     repo,
+			Client:      webhook.NewClient(15*time.Second, cfg.webhooks.allowPrivate),
+			Logger:      logger,
+			Concurrency: cfg.webhooks.dispatchers,
+			MaxAttempts: cfg.webhooks.maxAttempts,
+		}
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			dispatcher.Run(bgCtx)
+		}()
+	}
+	webhookService := &webhook.Service{
+		Queries:      repo,
+		AllowPrivate: cfg.webhooks.allowPrivate,
+		Retention:    cfg.webhooks.retention,
+	}
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		runPurge(bgCtx, "webhook events", time.Hour, webhookService.Purge, logger)
+	}()
+	webhookHandler := &api.WebhookHandler{Service: webhookService}
+
+	var recorder *analytics.Recorder
+	if cfg.search.analytics {
+		recorder = &analytics.Recorder{Queries: repo, Logger: logger}
+		bookHandler.Analytics = recorder
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			recorder.Run(bgCtx)
+		}()
+	}
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		runEvery(bgCtx, 5*time.Minute, func(ctx context.Context) {
+			if err := repo.RefreshPopularQueries(ctx); err != nil {
+				logger.Warn("Failed to refresh popular queries", "error", err)
+			}
+		})
+	}()
+	analyticsHandler := &api.AnalyticsHandler{Service: &analytics.Service{Queries: repo, Recorder: recorder}}
+
+	healthHandler := &api.HealthHandler{
+		Checker: newReadinessChecker(cfg, dbpool, redisClient, gemini, logger),
 	}
 
 	e := echo.New()
 	e.HideBanner = true
+	e.HTTPErrorHandler = api.NewHTTPErrorHandler(logger)
 
+	e.Use(otelecho.Middleware(telemetry.ServiceName))
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.RateLimiter(
@@ -98,9 +317,33 @@ func main() {
 	e.GET("/ping", func(c echo.Context) error {
 		return c.String(200, "pong")
 	})
+	e.GET("/healthz", healthHandler.Liveness)
+	e.GET("/readyz", healthHandler.Readiness)
 	e.GET("/search/semantic", bookHandler.SearchBooks)
 	e.GET("/search/text", bookHandler.FullTextSearch)
-	e.POST("/books", bookHandler.AddBook)
+	e.GET("/search/fuzzy", bookHandler.FuzzySearch)
+	e.GET("/suggest", bookHandler.Suggest)
+	e.POST("/feedback", analyticsHandler.Feedback)
+	e.POST("/books", bookHandler.AddBook, api.Idempotency(idempotencyStore, logger))
+	e.DELETE("/books/:isbn", bookHandler.DeleteBook)
+	e.GET("/books/:isbn/status", bookHandler.GetBookStatus)
+
+	if cfg.adminToken == "" {
+		logger.Warn("No admin token configured, /admin endpoints are disabled")
+	} else {
+		admin := e.Group("/admin", adminAuth(cfg.adminToken))
+		admin.POST("/webhooks", webhookHandler.Create)
+		admin.GET("/webhooks", webhookHandler.List)
+		admin.DELETE("/webhooks/:id", webhookHandler.Delete)
+		admin.POST("/webhooks/:id/replay", webhookHandler.ReplayDead)
+		admin.GET("/webhooks/deliveries", webhookHandler.Deliveries)
+		admin.POST("/webhooks/deliveries/:id/replay", webhookHandler.ReplayDelivery)
+		admin.GET("/analytics/top-queries", analyticsHandler.TopQueries)
+		admin.GET("/analytics/zero-results", analyticsHandler.ZeroResults)
+		admin.GET("/analytics/low-scores", analyticsHandler.LowScores)
+		admin.GET("/analytics/latency", analyticsHandler.Latency)
+		admin.GET("/books/duplicates", bookHandler.Duplicates)
+	}
 
 	// e.Logger.Fatal(e.Start(fmt.Sprintf(":%d", cfg.port)))
 
@@ -127,6 +370,9 @@ func main() {
 	} else {
 		logger.Info("Server shut down cleanly. Goodbye!")
 	}
+
+	stopBackground()
+	background.Wait()
 }
 
 func loadConfig() config {
@@ -138,6 +384,8 @@ Semantic Search API Server
 
 Usage:
   semantic-search-api [flags]
+  semantic-search-api [flags] cache stats|flush
+  semantic-search-api [flags] report ctr [-window 168h] [-min-searches 5] [-limit 20]
 
 Flags:
 `)
@@ -153,7 +401,41 @@ Flags:
 	flag.StringVar(&cfg.db.dsn, "db-dsn", defaultDSN, "PostgreSQL DSN (or set DATABASE_URL env)")
 	flag.StringVar(&cfg.db.redis, "redis", defaultRedisURL, "Redis URL (optional, or set REDIS_URL env)")
 	flag.BoolVar(&cfg.migrate, "migrate", false, "Run DB migrations and exit")
+	flag.IntVar(&cfg.embed.maxAttempts, "embed-max-attempts", 3, "Maximum attempts per embedding call, including the first")
+	flag.DurationVar(&cfg.embed.retryBaseDelay, "embed-retry-base", 200*time.Millisecond, "Initial backoff between embedding retries")
+	flag.DurationVar(&cfg.embed.retryMaxDelay, "embed-retry-max", 2*time.Second, "Maximum backoff (and Retry-After) between embedding retries")
+	flag.DurationVar(&cfg.embed.attemptTimeout, "embed-timeout", 2*time.Second, "Deadline for each embedding API call")
+	flag.IntVar(&cfg.embed.breakerThreshold, "embed-breaker-threshold", 5, "Consecutive failed embedding calls before the circuit opens")
+	flag.DurationVar(&cfg.embed.breakerCooldown, "embed-breaker-cooldown", 10*time.Second, "How long the embedding circuit stays open before a probe")
+	flag.BoolVar(&cfg.ingest.async, "async-ingest", false, "Accept POST /books with 202 and embed in the background (per request: ?async=true)")
+	flag.IntVar(&cfg.ingest.workers, "workers", 2, "Background embedding workers (0 disables)")
+	flag.StringVar(&cfg.cache.backend, "cache-backend", "auto", "Shared embedding cache (auto|redis|postgres|none); auto uses Redis if configured, else Postgres")
+	flag.IntVar(&cfg.cache.lruSize, "cache-lru-size", 1024, "In-process embedding cache entries in front of the shared cache (0 disables)")
+	flag.DurationVar(&cfg.cache.ttl, "cache-ttl", 24*time.Hour, "Lifetime of cached query embeddings")
+	flag.StringVar(&cfg.cache.encoding, "cache-encoding", "float32", "Cached vector encoding (float32|float16)")
+	flag.IntVar(&cfg.webhooks.dispatchers, "webhook-dispatchers", 1, "Background webhook senders (0 disables delivery)")
+	flag.IntVar(&cfg.webhooks.maxAttempts, "webhook-max-attempts", 8, "Delivery attempts before a webhook is dead-lettered")
+	flag.BoolVar(&cfg.webhooks.allowPrivate, "webhook-allow-private", false, "Allow webhook endpoints on loopback and private addresses (development only)")
+	flag.DurationVar(&cfg.webhooks.retention, "webhook-retention", 7*24*time.Hour, "How long delivered and dead webhook events are kept")
+	flag.Float64Var(&cfg.ingest.works.MaxDistance, "work-distance", service.DefaultWorkDistance, "Maximum cosine distance between the embeddings of two editions of one work")
+	flag.Float64Var(&cfg.ingest.works.MinTitleSimilarity, "work-title-similarity", service.DefaultWorkTitleSimilarity, "Minimum trigram similarity (0-1] between the normalised titles of two editions of one work")
+	flag.StringVar(&cfg.adminToken, "admin-token", os.Getenv("ADMIN_TOKEN"), "Bearer token for /admin endpoints, which are disabled without one (or set ADMIN_TOKEN env)")
+	flag.BoolVar(&cfg.search.fallback, "search-fallback", true, "Fall back to full-text search when the query cannot be embedded")
+	flag.BoolVar(&cfg.search.analytics, "search-analytics", true, "Record searches in search_events for /admin/analytics")
+	flag.Float64Var(&cfg.search.feedbackWeight, "feedback-weight", 0, "Weight (0-1) of click and relevance feedback in semantic ranking (0 disables)")
+	flag.Float64Var(&cfg.search.diversity, "search-diversity", 0, "Default diversity (0-1) of semantic results; requests override it with ?diversity= (0 disables)")
+	flag.Float64Var(&cfg.search.fuzzyThreshold, "fuzzy-threshold", service.DefaultFuzzyThreshold, "Minimum word similarity (0-1] between a /search/fuzzy query and a title")
+	vectorIndex := flag.String("vector-index", string(vectorindex.Vector), "Index semantic search runs on (vector|halfvec|binary|matryoshka); build it first with the index command")
+	flag.StringVar(&cfg.search.vectorSnapshot, "vector-snapshot", "", "Serve semantic search from an in-process HNSW store loaded from this file, written by index snapshot, instead of pgvector")
+	flag.StringVar(&cfg.search.highlight.Start, "highlight-start", "<b>", "Marker inserted before matched terms in full-text headlines")
+	flag.StringVar(&cfg.search.highlight.Stop, "highlight-stop", "</b>", "Marker inserted after matched terms in full-text headlines")
+	flag.StringVar(&cfg.language.fallback, "default-language", lang.Default, "Language of books ingested without one, and of text searches without ?lang= (ISO 639-1 code or text search configuration)")
+	flag.BoolVar(&cfg.language.detect, "detect-language", false, "Guess the language of books ingested without one from their title and description")
+	flag.BoolVar(&cfg.ready.probeEmbed, "ready-probe-embed", false, "Include a Gemini embedding call, bypassing the caches, in /readyz")
 	flag.StringVar(&cfg.logLevel, "loglevel", "info", "Log level (debug|info|warn|error)")
+	flag.StringVar(&cfg.otel.exporter, "otel-exporter", "none", "Trace exporter (none|otlp|stdout)")
+	flag.StringVar(&cfg.otel.endpoint, "otel-endpoint", "", "OTLP/HTTP collector host:port or URL (defaults to OTEL_EXPORTER_OTLP_ENDPOINT env)")
+	flag.BoolVar(&cfg.otel.insecure, "otel-insecure", false, "Send traces to the collector without TLS")
 
 	flag.Parse()
 
@@ -162,7 +444,53 @@ Flags:
 		os.Exit(1)
 	}
 
-	if cfg.apiKey == "" {
+	if cfg.search.feedbackWeight < 0 || cfg.search.feedbackWeight > 1 {
+		fmt.Fprintln(os.Stderr, "Error: --feedback-weight must be between 0 and 1")
+		os.Exit(1)
+	}
+
+	if config, ok := lang.Config(cfg.language.fallback); ok {
+		cfg.language.fallback = config
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: unsupported --default-language %q\n", cfg.language.fallback)
+		os.Exit(1)
+	}
+
+	if cfg.search.diversity < 0 || cfg.search.diversity > 1 {
+		fmt.Fprintln(os.Stderr, "Error: --search-diversity must be between 0 and 1")
+		os.Exit(1)
+	}
+
+	if cfg.search.fuzzyThreshold <= 0 || cfg.search.fuzzyThreshold > 1 {
+		fmt.Fprintln(os.Stderr, "Error: --fuzzy-threshold must be greater than 0 and at most 1")
+		os.Exit(1)
+	}
+
+	if cfg.ingest.works.MaxDistance <= 0 || cfg.ingest.works.MaxDistance > 2 {
+		fmt.Fprintln(os.Stderr, "Error: --work-distance must be greater than 0 and at most 2")
+		os.Exit(1)
+	}
+
+	if cfg.ingest.works.MinTitleSimilarity <= 0 || cfg.ingest.works.MinTitleSimilarity > 1 {
+		fmt.Fprintln(os.Stderr, "Error: --work-title-similarity must be greater than 0 and at most 1")
+		os.Exit(1)
+	}
+
+	mode, err := vectorindex.ParseMode(*vectorIndex)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --vector-index: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.search.vectorIndex = mode
+	cfg.ingest.works.Matryoshka = mode == vectorindex.Matryoshka
+
+	if err := service.ValidateHighlight(cfg.search.highlight); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Admin commands do not call Gemini.
+	if cfg.apiKey == "" && flag.NArg() == 0 {
 		fmt.Fprintln(os.Stderr, "Error: --apikey is required")
 		os.Exit(1)
 	}
@@ -212,19 +540,176 @@ func runMigrations(cfg config, logger *slog.Logger) error {
 	return fmt.Errorf("failed to run migrations: %w", err)
 }
 
-func newEmbedder(ctx context.Context, cfg config, logger *slog.Logger) (embed.Embedder, error) {
-	base, err := embed.NewGeminiEmbedder(ctx, logger, cfg.apiKey)
+// newEmbeddingCache returns the shared embedding cache selected by
+// -cache-backend, or nil if caching is disabled. "auto" prefers Redis and
+// falls back to Postgres when no Redis address is configured.
+func newEmbeddingCache(cfg config, redisClient *redis.Client, repo *repository.Queries) (embed.Cache, error) {
+	backend := cfg.cache.backend
+	if backend == "auto" {
+		backend = "postgres"
+		if redisClient != nil {
+			backend = "redis"
+		}
+	}
+
+	switch backend {
+	case "redis":
+		if redisClient == nil {
+			return nil, errors.New("cache backend redis requires -redis")
+		}
+		return &embed.RedisCache{Client: redisClient}, nil
+	case "postgres":
+		return &embed.PostgresCache{Queries: repo}, nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.cache.backend)
+	}
+}
+
+func newEmbedder(cfg config, gemini embed.Embedder, cache embed.Cache, logger *slog.Logger) (embed.Embedder, error) {
+	// The cache sits outside the retry layer so cache hits never touch the breaker.
+	base := &embed.RetryEmbedder{
+		Base:           gemini,
+		MaxAttempts:    cfg.embed.maxAttempts,
+		BaseDelay:      cfg.embed.retryBaseDelay,
+		MaxDelay:       cfg.embed.retryMaxDelay,
+		AttemptTimeout: cfg.embed.attemptTimeout,
+		Breaker: &embed.Breaker{
+			Threshold:   cfg.embed.breakerThreshold,
+			Cooldown:    cfg.embed.breakerCooldown,
+			MaxCooldown: 10 * cfg.embed.breakerCooldown,
+		},
+		Logger: logger,
+	}
+
+	encoding, err := embed.ParseEncoding(cfg.cache.encoding)
 	if err != nil {
 		return nil, err
 	}
 
-	if cfg.db.redis != "" {
-		redisClient := db.NewRedisClient(cfg.db.redis, logger)
-		return &embed.CachedEmbedder{
-			Base:   base,
-			Redis:  redisClient,
-			Logger: logger,
-		}, nil
-	}
-	return base, nil
+	var local *embed.LRU
+	if cfg.cache.lruSize > 0 {
+		local = embed.NewLRU(cfg.cache.lruSize)
+	}
+	var embedder embed.Embedder = base
+	if cache != nil || local != nil {
+		embedder = &embed.CachedEmbedder{
+			Base:    base,
+			Local:   local,
+			Cache:   cache,
+			Breaker: &embed.Breaker{Threshold: 3, Cooldown: 5 * time.Second, MaxCooldown: time.Minute},
+			Logger:  logger,
+
+			Model:     embed.GeminiModel,
+			Dimension: embed.GeminiDimension,
+			TTL:       cfg.cache.ttl,
+			Encoding:  encoding,
+		}
+	}
+
+	// Coalesce outermost, so a burst of identical queries costs one cache
+	// lookup and at most one Gemini call. The shared call may spend every
+	// retry.
+	return &embed.CoalescingEmbedder{
+		Base:    embedder,
+		Timeout: time.Duration(cfg.embed.maxAttempts) * (cfg.embed.attemptTimeout + cfg.embed.retryMaxDelay),
+	}, nil
+}
+
+func newReadinessChecker(cfg config, pool *pgxpool.Pool, redisClient *redis.Client, embedder embed.Embedder, logger *slog.Logger) *health.Checker {
+	checker := &health.Checker{Timeout: 2 * time.Second}
+
+	checker.Register("postgres", pool.Ping)
+
+	// Redis is only a cache; the service keeps working without it.
+	if redisClient != nil {
+		checker.RegisterOptional("redis", func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		})
+	}
+
+	// Opening a migrate instance costs a fresh connection, so don't do it on every poll.
+	checker.Register("migrations", health.Cached(func(ctx context.Context) error {
+		status, err := db.GetMigrationStatus(cfg.db.dsn, logger)
+		if err != nil {
+			return err
+		}
+		if status.Dirty {
+			return fmt.Errorf("schema version %d is dirty", status.Current)
+		}
+		if status.Pending() {
+			return fmt.Errorf("schema at version %d, latest is %d", status.Current, status.Latest)
+		}
+		return nil
+	}, 30*time.Second, 5*time.Second))
+
+	// The probe calls Gemini directly: through the caches it would be a hit
+	// after the first success and never notice an outage or a revoked key.
+	// Failures are kept briefly so one transient error does not hold the pod
+	// out of rotation.
+	if cfg.ready.probeEmbed {
+		probe := health.Cached(func(ctx context.Context) error {
+			_, err := embedder.Embed(ctx, "readiness probe")
+			return err
+		}, 5*time.Minute, 15*time.Second)
+		// With the full-text fallback enabled an embedder outage only degrades search.
+		if cfg.search.fallback {
+			checker.RegisterOptional("embedder", probe)
+		} else {
+			checker.Register("embedder", probe)
+		}
+	}
+
+	return checker
+}
+
+// adminAuth protects /admin with a bearer token. Without a token /admin is
+// not mounted at all.
+func adminAuth(token string) echo.MiddlewareFunc {
+	return middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+		return subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1, nil
+	})
+}
+
+// warnMissingVectorIndex logs if the index for mode is missing or still
+// being built; semantic search then falls back to scanning every book.
+func warnMissingVectorIndex(ctx context.Context, pool *pgxpool.Pool, mode vectorindex.Mode, logger *slog.Logger) {
+	statuses, err := vectorindex.Stat(ctx, pool)
+	if err != nil {
+		logger.Warn("Failed to check vector index", "error", err)
+		return
+	}
+	for _, s := range statuses {
+		if s.Mode == mode && (!s.Exists || !s.Valid) {
+			logger.Warn("Vector index is not ready, semantic search will scan all books", "mode", mode, "index", mode.IndexName())
+		}
+	}
+}
+
+// runPurge periodically deletes expired rows using purge.
+func runPurge(ctx context.Context, what string, every time.Duration, purge func(context.Context) (int64, error), logger *slog.Logger) {
+	runEvery(ctx, every, func(ctx context.Context) {
+		n, err := purge(ctx)
+		if err != nil {
+			logger.Warn("Failed to purge "+what, "error", err)
+			return
+		}
+		logger.Debug("Purged "+what, "count", n)
+	})
+}
+
+// runEvery calls fn every interval until ctx is cancelled.
+func runEvery(ctx context.Context, every time.Duration, fn func(context.Context)) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
 }
@@ -1,21 +1,263 @@
--- name: InsertBook :exec
-INSERT INTO books (isbn, title, description, embedding)
-VALUES ($1, $2, $3, $4);
+-- name: InsertBook :one
+INSERT INTO books (isbn, title, description, embedding, content_hash, language, author, genres, published_year, work_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id;
 
 -- name: SearchBooks :many
-SELECT id, isbn, title, description, embedding
+-- Nearest books to embedding, narrowed by the optional drill-down filters.
+SELECT id, isbn, title, description, embedding, work_id
 FROM books
-ORDER BY embedding <=> $1
-LIMIT 5;
+WHERE embedding IS NOT NULL
+  AND (sqlc.narg(genre)::text IS NULL OR sqlc.narg(genre)::text = ANY (genres))
+  AND (sqlc.narg(author)::text IS NULL OR lower(author) = lower(sqlc.narg(author)::text))
+  AND (sqlc.narg(language)::text IS NULL OR language = sqlc.narg(language)::text)
+  AND (sqlc.narg(decade)::int IS NULL OR published_year / 10 * 10 = sqlc.narg(decade)::int)
+ORDER BY embedding <=> @embedding
+LIMIT @max_results;
+
+-- name: SearchBooksMatryoshka :many
+-- Like SearchBooks, but takes the candidates nearest by the truncated
+-- embeddings, which the matryoshka HNSW index (vectorindex.Matryoshka)
+-- serves, and re-ranks them by exact cosine distance of the full vectors.
+WITH candidates AS (
+    SELECT id
+    FROM books
+    WHERE embedding_short IS NOT NULL
+      AND (sqlc.narg(genre)::text IS NULL OR sqlc.narg(genre)::text = ANY (genres))
+      AND (sqlc.narg(author)::text IS NULL OR lower(author) = lower(sqlc.narg(author)::text))
+      AND (sqlc.narg(language)::text IS NULL OR language = sqlc.narg(language)::text)
+      AND (sqlc.narg(decade)::int IS NULL OR published_year / 10 * 10 = sqlc.narg(decade)::int)
+    ORDER BY embedding_short <=> subvector(@embedding::vector, 1, 256)::vector(256)
+    LIMIT @candidates
+)
+SELECT b.id, b.isbn, b.title, b.description, b.embedding, b.work_id
+FROM books AS b
+JOIN candidates AS c ON c.id = b.id
+ORDER BY b.embedding <=> @embedding::vector
+LIMIT @max_results;
+
+-- name: SearchBooksHalfvec :many
+-- Like SearchBooks, but ordered by the half precision embeddings, which the
+-- halfvec HNSW index (vectorindex.HalfVec) serves.
+SELECT id, isbn, title, description, embedding, work_id
+FROM books
+WHERE embedding IS NOT NULL
+  AND (sqlc.narg(genre)::text IS NULL OR sqlc.narg(genre)::text = ANY (genres))
+  AND (sqlc.narg(author)::text IS NULL OR lower(author) = lower(sqlc.narg(author)::text))
+  AND (sqlc.narg(language)::text IS NULL OR language = sqlc.narg(language)::text)
+  AND (sqlc.narg(decade)::int IS NULL OR published_year / 10 * 10 = sqlc.narg(decade)::int)
+ORDER BY embedding::halfvec(768) <=> @embedding::halfvec(768)
+LIMIT @max_results;
+
+-- name: SearchBooksBinary :many
+-- Like SearchBooks, but takes the candidates nearest by Hamming distance
+-- between binary quantized embeddings, which the binary HNSW index
+-- (vectorindex.Binary) serves, and re-ranks them by exact cosine distance.
+WITH candidates AS (
+    SELECT id
+    FROM books
+    WHERE embedding IS NOT NULL
+      AND (sqlc.narg(genre)::text IS NULL OR sqlc.narg(genre)::text = ANY (genres))
+      AND (sqlc.narg(author)::text IS NULL OR lower(author) = lower(sqlc.narg(author)::text))
+      AND (sqlc.narg(language)::text IS NULL OR language = sqlc.narg(language)::text)
+      AND (sqlc.narg(decade)::int IS NULL OR published_year / 10 * 10 = sqlc.narg(decade)::int)
+    ORDER BY binary_quantize(embedding)::bit(768) <~> binary_quantize(@embedding::vector)
+    LIMIT @candidates
+)
+SELECT b.id, b.isbn, b.title, b.description, b.embedding, b.work_id
+FROM books AS b
+JOIN candidates AS c ON c.id = b.id
+ORDER BY b.embedding <=> @embedding::vector
+LIMIT @max_results;
 
 -- name: GetBookByISBN :one
-SELECT id, isbn
+SELECT id, isbn, title, content_hash, language, author, genres, published_year, work_id, embedding_status
 FROM books
 WHERE isbn = $1;
 
 -- name: SearchBooksByText :many
-SELECT id, isbn, title, description
+-- Matches query in websearch syntax (quotes, -term, OR) and every prefix
+-- term, given in to_tsquery syntax, both parsed with config. If language is
+-- set, only books in that language are searched; the other filters drill
+-- down the same way. Headlines are only computed for the returned rows.
+-- With escape_html the title and description are HTML-escaped before the
+-- markers go in, so HTML markers wrap text that is safe to render.
+WITH q AS (
+    SELECT websearch_to_tsquery(@config::regconfig, @query::text) && to_tsquery(@config::regconfig, @prefixes::text) AS query
+),
+ranked AS (
+    SELECT b.id, b.isbn, b.title, b.description, b.language, b.work_id, ts_rank(b.tsv, q.query) AS rank
+    FROM books AS b, q
+    WHERE b.tsv @@ q.query
+      AND (sqlc.narg(language)::text IS NULL OR b.language = sqlc.narg(language)::text)
+      AND (sqlc.narg(genre)::text IS NULL OR sqlc.narg(genre)::text = ANY (b.genres))
+      AND (sqlc.narg(author)::text IS NULL OR lower(b.author) = lower(sqlc.narg(author)::text))
+      AND (sqlc.narg(decade)::int IS NULL OR b.published_year / 10 * 10 = sqlc.narg(decade)::int)
+    ORDER BY rank DESC
+    LIMIT @max_results
+)
+SELECT r.id, r.isbn, r.title, r.description, r.work_id, r.rank::float4 AS rank,
+       ts_headline(r.language::regconfig, e.title, q.query, @title_headline_options::text)::text AS title_headline,
+       ts_headline(r.language::regconfig, e.description, q.query, @headline_options::text)::text AS headline
+FROM ranked AS r, q,
+LATERAL (
+    SELECT CASE WHEN @escape_html::boolean
+                THEN replace(replace(replace(replace(r.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')
+                ELSE r.title END AS title,
+           CASE WHEN @escape_html::boolean
+                THEN replace(replace(replace(replace(r.description, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')
+                ELSE r.description END AS description
+) AS e
+ORDER BY r.rank DESC;
+
+-- name: UpsertBook :one
+-- A book keeps its work on update; work_id only applies to new books and to
+-- books that were never matched. The new embedding makes the book ready, and
+-- any embedding job still queued for it is dropped, so a worker embedding an
+-- older description cannot overwrite this one.
+WITH book AS (
+    INSERT INTO books (isbn, title, description, embedding, content_hash, language, author, genres, published_year, work_id)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    ON CONFLICT (isbn) DO UPDATE
+    SET title = EXCLUDED.title,
+        description = EXCLUDED.description,
+        embedding = EXCLUDED.embedding,
+        content_hash = EXCLUDED.content_hash,
+        language = EXCLUDED.language,
+        author = EXCLUDED.author,
+        genres = EXCLUDED.genres,
+        published_year = EXCLUDED.published_year,
+        work_id = coalesce(books.work_id, EXCLUDED.work_id),
+        embedding_status = 'ready',
+        embedding_error = NULL
+    RETURNING id, (xmax = 0)::boolean AS inserted
+), stale AS (
+    DELETE FROM embedding_jobs
+    WHERE book_id IN (SELECT id FROM book)
+)
+SELECT id, inserted FROM book;
+
+-- name: UpdateBookDetails :exec
+UPDATE books
+SET title = $2, language = $3, author = $4, genres = $5, published_year = $6
+WHERE id = $1;
+
+-- name: InsertPendingBook :one
+-- Stores a book without its embedding and enqueues the embedding job in the
+-- same statement, so both happen or neither does.
+WITH book AS (
+    INSERT INTO books (isbn, title, description, content_hash, language, author, genres, published_year, embedding_status)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending')
+    RETURNING id
+)
+INSERT INTO embedding_jobs (book_id)
+SELECT id FROM book
+RETURNING book_id;
+
+-- name: UpsertPendingBook :one
+-- Stores a book without its embedding, like InsertPendingBook, or replaces
+-- the one with the same ISBN. A replaced book loses its old vector, so it
+-- stops matching searches for a description it no longer has, and its queued
+-- embedding jobs are replaced by a new one; a worker still embedding the old
+-- description finds its job gone and discards the result.
+WITH book AS (
+    INSERT INTO books (isbn, title, description, content_hash, language, author, genres, published_year, embedding_status)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending')
+    ON CONFLICT (isbn) DO UPDATE
+    SET title = EXCLUDED.title,
+        description = EXCLUDED.description,
+        embedding = NULL,
+        content_hash = EXCLUDED.content_hash,
+        language = EXCLUDED.language,
+        author = EXCLUDED.author,
+        genres = EXCLUDED.genres,
+        published_year = EXCLUDED.published_year,
+        embedding_status = 'pending',
+        embedding_error = NULL
+    RETURNING id, (xmax = 0)::boolean AS inserted
+), stale AS (
+    DELETE FROM embedding_jobs
+    WHERE book_id IN (SELECT id FROM book)
+), job AS (
+    INSERT INTO embedding_jobs (book_id)
+    SELECT id FROM book
+)
+SELECT id, inserted FROM book;
+
+-- name: GetBookStatus :one
+SELECT id, isbn, title, embedding_status, embedding_error
+FROM books
+WHERE isbn = $1;
+
+-- name: DeleteBookByISBN :one
+DELETE FROM books
+WHERE isbn = $1
+RETURNING id, isbn, title;
+
+-- name: SuggestTitles :many
+-- Titles matching pattern (a LIKE pattern on the lowercased title), those
+-- starting with prefix first, then by trigram similarity to query.
+SELECT isbn, title,
+       (lower(title) LIKE @prefix::text)::boolean AS prefix_match,
+       similarity(lower(title), @query::text)::float8 AS score
+FROM books
+WHERE lower(title) LIKE @pattern::text
+ORDER BY prefix_match DESC, score DESC, title
+LIMIT @max_results;
+
+-- name: SetHNSWEfSearch :exec
+-- Sets the candidate list size of HNSW scans for the rest of the
+-- transaction. A scan returns at most ef_search rows, 40 by default.
+SELECT set_config('hnsw.ef_search', @ef_search::int::text, true);
+
+-- name: SetHNSWIterativeScan :exec
+-- Makes HNSW scans for the rest of the transaction go on past ef_search
+-- while filters discard rows, until the LIMIT is filled. Iterative scans
+-- arrived in pgvector 0.8; older versions keep the single pass.
+SELECT set_config('hnsw.iterative_scan', 'strict_order', true)
+FROM pg_extension
+WHERE extname = 'vector'
+  AND string_to_array(extversion, '.')::int[] >= '{0,8}';
+
+-- name: SetWordSimilarityThreshold :exec
+-- Sets the threshold of the %> operator for the rest of the transaction.
+SELECT set_config('pg_trgm.word_similarity_threshold', @threshold::text, true);
+
+-- name: FuzzySearchTitles :many
+-- Titles containing a close match for query, which must be lowercase.
+-- Candidates come from the trigram index at the transaction's
+-- pg_trgm.word_similarity_threshold.
+SELECT id, isbn, title, description,
+       word_similarity(@query::text, lower(title))::float8 AS score
+FROM books
+WHERE lower(title) %> @query::text
+  AND word_similarity(@query::text, lower(title)) >= @threshold::float8
+ORDER BY score DESC, title
+LIMIT @max_results;
+
+-- name: BookFacetCounts :many
+-- Counts metadata values over a candidate set of books, for the requested
+-- facets only. Decades are reported by their first year, e.g. "1990".
+SELECT f.facet::text AS facet, f.value::text AS value, count(*) AS books
+FROM books AS b
+CROSS JOIN LATERAL (
+    SELECT 'genre' AS facet, g AS value FROM unnest(b.genres) AS g
+    UNION ALL
+    SELECT 'author', b.author WHERE b.author IS NOT NULL
+    UNION ALL
+    SELECT 'language', b.language::text
+    UNION ALL
+    SELECT 'decade', (b.published_year / 10 * 10)::text WHERE b.published_year IS NOT NULL
+) AS f
+WHERE b.id = ANY (@ids::int[]) AND f.facet = ANY (@facets::text[])
+GROUP BY f.facet, f.value
+ORDER BY f.facet, books DESC, f.value;
+
+-- name: ListEmbeddedBooks :many
+-- Embedded books with an id above after, in id order, for paging through
+-- the catalogue.
+SELECT id, isbn, title, description, embedding, work_id, language, author, genres, published_year
 FROM books
-WHERE tsv @@ plainto_tsquery('english', $1)
-ORDER BY ts_rank(tsv, plainto_tsquery('english', $1)) DESC
-LIMIT 10;
\ No newline at end of file
+WHERE embedding IS NOT NULL AND id > @after
+ORDER BY id
+LIMIT @max_results;
@@ -0,0 +1,28 @@
+-- name: GetCachedEmbedding :one
+SELECT value
+FROM embedding_cache
+WHERE key = $1 AND expires_at > now();
+
+-- name: SetCachedEmbedding :exec
+INSERT INTO embedding_cache (key, value, expires_at)
+VALUES (@key, @value, now() + make_interval(secs => @ttl_seconds::float8))
+ON CONFLICT (key) DO UPDATE
+SET value = EXCLUDED.value,
+    expires_at = EXCLUDED.expires_at;
+
+-- name: DeleteExpiredCachedEmbeddings :execrows
+DELETE FROM embedding_cache
+WHERE expires_at <= now();
+
+-- name: GetEmbeddingCacheStats :many
+-- Groups entries by key namespace, i.e. the key without its trailing hash.
+SELECT regexp_replace(key, ':[^:]*$', '')::text AS namespace,
+       count(*) AS entries,
+       coalesce(sum(octet_length(value)), 0)::bigint AS bytes,
+       count(*) FILTER (WHERE expires_at <= now()) AS expired
+FROM embedding_cache
+GROUP BY 1
+ORDER BY 1;
+
+-- name: DeleteAllCachedEmbeddings :execrows
+DELETE FROM embedding_cache;
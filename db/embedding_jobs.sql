@@ -0,0 +1,57 @@
+-- name: ClaimEmbeddingJobs :many
+-- Leases up to batch_size due jobs. A leased job becomes due again once the
+-- lease expires, so jobs held by a crashed worker are picked up later.
+UPDATE embedding_jobs AS j
+SET attempts = j.attempts + 1,
+    run_after = now() + make_interval(secs => @lease_seconds::float8)
+FROM books AS b
+WHERE b.id = j.book_id
+  AND j.id IN (
+    SELECT id
+    FROM embedding_jobs
+    WHERE run_after <= now()
+    ORDER BY run_after
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING j.id, j.book_id, j.attempts, b.description;
+
+-- name: CompleteEmbeddingJob :one
+WITH job AS (
+    DELETE FROM embedding_jobs
+    WHERE embedding_jobs.id = $1
+    RETURNING book_id
+)
+UPDATE books
+SET embedding = $2,
+    embedding_status = 'ready',
+    embedding_error = NULL
+FROM job
+WHERE books.id = job.book_id
+RETURNING books.id, books.isbn, books.title, books.work_id;
+
+-- name: ReleaseEmbeddingJob :exec
+-- Hands a claimed job back without counting the attempt, for failures that
+-- say nothing about the job itself, such as an open circuit breaker.
+UPDATE embedding_jobs
+SET attempts = attempts - 1,
+    run_after = now() + make_interval(secs => @delay_seconds::float8)
+WHERE id = @id;
+
+-- name: RetryEmbeddingJob :exec
+UPDATE embedding_jobs
+SET last_error = @last_error,
+    run_after = now() + make_interval(secs => @delay_seconds::float8)
+WHERE id = @id;
+
+-- name: FailEmbeddingJob :exec
+WITH job AS (
+    DELETE FROM embedding_jobs
+    WHERE embedding_jobs.id = $1
+    RETURNING book_id
+)
+UPDATE books
+SET embedding_status = 'failed',
+    embedding_error = $2
+FROM job
+WHERE books.id = job.book_id;
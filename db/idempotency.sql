@@ -0,0 +1,38 @@
+-- name: ClaimIdempotencyKey :one
+-- Inserts a pending record for key, leased until $4, or takes over one that
+-- expired before $3, or a pending one for the same request whose lease ran
+-- out. Returns no rows if a live record already exists.
+INSERT INTO idempotency_keys (key, request_hash, locked_until)
+VALUES ($1, $2, $4)
+ON CONFLICT (key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash,
+    status_code = NULL,
+    content_type = NULL,
+    response_body = NULL,
+    created_at = now(),
+    locked_until = EXCLUDED.locked_until
+WHERE idempotency_keys.created_at < $3
+   OR (idempotency_keys.status_code IS NULL
+       AND idempotency_keys.request_hash = EXCLUDED.request_hash
+       AND (idempotency_keys.locked_until IS NULL OR idempotency_keys.locked_until < now()))
+RETURNING key;
+
+-- name: GetIdempotencyKey :one
+SELECT key, request_hash, status_code, content_type, response_body, created_at
+FROM idempotency_keys
+WHERE key = $1;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET status_code = $2,
+    content_type = $3,
+    response_body = $4
+WHERE key = $1;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE key = $1;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < $1;
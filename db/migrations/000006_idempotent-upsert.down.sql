@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS idempotency_keys;
+
+ALTER TABLE books DROP COLUMN IF EXISTS content_hash;
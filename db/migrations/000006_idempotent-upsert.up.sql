@@ -0,0 +1,14 @@
+-- Hash of the embedded content, used to skip re-embedding unchanged books on upsert
+ALTER TABLE books ADD COLUMN IF NOT EXISTS content_hash TEXT;
+
+-- Stored responses for requests carrying an Idempotency-Key header
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  key TEXT PRIMARY KEY,
+  request_hash TEXT NOT NULL,
+  status_code INT,
+  content_type TEXT,
+  response_body BYTEA,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys (created_at);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS embedding_jobs;
+
+ALTER TABLE books DROP COLUMN IF EXISTS embedding_error;
+
+ALTER TABLE books DROP COLUMN IF EXISTS embedding_status;
@@ -0,0 +1,18 @@
+-- Books ingested asynchronously are stored before they are embedded
+ALTER TABLE books
+ADD COLUMN IF NOT EXISTS embedding_status TEXT NOT NULL DEFAULT 'ready'
+    CHECK (embedding_status IN ('pending', 'ready', 'failed'));
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS embedding_error TEXT;
+
+-- Postgres-backed work queue, consumed with SELECT ... FOR UPDATE SKIP LOCKED
+CREATE TABLE IF NOT EXISTS embedding_jobs (
+  id BIGSERIAL PRIMARY KEY,
+  book_id INT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+  attempts INT NOT NULL DEFAULT 0,
+  last_error TEXT,
+  run_after TIMESTAMPTZ NOT NULL DEFAULT now(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_embedding_jobs_run_after ON embedding_jobs (run_after);
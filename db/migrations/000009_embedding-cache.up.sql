@@ -0,0 +1,9 @@
+-- Query embedding cache for deployments without Redis. Unlogged: the cache is
+-- rebuilt on demand, so it is not worth WAL traffic or crash safety.
+CREATE UNLOGGED TABLE IF NOT EXISTS embedding_cache (
+  key TEXT PRIMARY KEY,
+  value BYTEA NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_embedding_cache_expires_at ON embedding_cache (expires_at);
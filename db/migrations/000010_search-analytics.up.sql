@@ -0,0 +1,17 @@
+-- One row per search request, written in batches off the request path
+CREATE TABLE IF NOT EXISTS search_events (
+  id UUID PRIMARY KEY,
+  query TEXT NOT NULL,
+  -- semantic or text; semantic searches answered by the full-text fallback are degraded
+  mode TEXT NOT NULL,
+  result_isbns TEXT[] NOT NULL DEFAULT '{}',
+  result_count INT NOT NULL,
+  -- Best cosine similarity; NULL when the results carry no comparable score
+  top_score DOUBLE PRECISION,
+  latency_ms DOUBLE PRECISION NOT NULL,
+  degraded BOOLEAN NOT NULL DEFAULT FALSE,
+  tenant TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_events_created_at ON search_events (created_at);
@@ -0,0 +1,13 @@
+-- Clicks and relevance votes against a search event. event_id is not a
+-- foreign key: events are written asynchronously and may land after their
+-- feedback.
+CREATE TABLE IF NOT EXISTS search_feedback (
+  id BIGSERIAL PRIMARY KEY,
+  event_id UUID NOT NULL,
+  isbn TEXT NOT NULL,
+  kind TEXT NOT NULL CHECK (kind IN ('click', 'relevant', 'irrelevant')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_feedback_isbn ON search_feedback (isbn, created_at);
+CREATE INDEX IF NOT EXISTS idx_search_feedback_event_id ON search_feedback (event_id);
@@ -0,0 +1,3 @@
+DROP MATERIALIZED VIEW IF EXISTS popular_queries;
+DROP INDEX IF EXISTS idx_books_title_prefix;
+DROP INDEX IF EXISTS idx_books_title_trgm;
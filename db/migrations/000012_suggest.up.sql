@@ -0,0 +1,20 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Type-ahead: infix title matches use the trigram index, prefixes shorter
+-- than a trigram use the pattern index.
+CREATE INDEX IF NOT EXISTS idx_books_title_trgm ON books USING GIN (lower(title) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_books_title_prefix ON books (lower(title) text_pattern_ops);
+
+-- Queries that found something and were searched more than once in the last
+-- 30 days. One-off queries are left out so suggestions never echo a single
+-- user's search. Refreshed periodically by the server.
+CREATE MATERIALIZED VIEW IF NOT EXISTS popular_queries AS
+SELECT lower(btrim(query)) AS query, count(*) AS searches
+FROM search_events
+WHERE created_at >= now() - interval '30 days' AND result_count > 0
+GROUP BY 1
+HAVING count(*) > 1;
+
+-- The unique index allows REFRESH ... CONCURRENTLY.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_popular_queries_query ON popular_queries (query);
+CREATE INDEX IF NOT EXISTS idx_popular_queries_prefix ON popular_queries (query text_pattern_ops);
@@ -0,0 +1,4 @@
+-- Weighting title matches (A) above description matches (B) for ts_rank is
+-- done by 000014, together with the per-book language, so that the stored
+-- tsv column, and with it the whole books table, is rebuilt once.
+SELECT 1;
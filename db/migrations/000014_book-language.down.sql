@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_books_tsv;
+ALTER TABLE books DROP COLUMN IF EXISTS tsv;
+
+ALTER TABLE books
+ADD COLUMN tsv tsvector GENERATED ALWAYS AS (
+    to_tsvector('english', coalesce(title, '') || ' ' || coalesce(description, ''))
+) STORED;
+
+CREATE INDEX idx_books_tsv ON books USING GIN (tsv);
+
+ALTER TABLE books DROP COLUMN IF EXISTS language;
+DROP FUNCTION IF EXISTS books_ts_config(TEXT);
@@ -0,0 +1,30 @@
+-- Each book is stemmed with the text search configuration of its own
+-- language, and title matches (A) are weighted above description matches (B)
+-- for ts_rank. Both change the stored tsv column, so it is rebuilt once here
+-- rather than also in 000013. Rebuilding it rewrites the books table under
+-- an ACCESS EXCLUSIVE lock: reads and writes of books wait until the
+-- migration finishes, which takes about as long as a full-table UPDATE, so
+-- run it in a maintenance window on a large catalogue.
+--
+-- The language is stored as text: pg_upgrade refuses clusters with reg*
+-- columns other than regclass, regrole and regtype. The cast to regconfig
+-- looks up the catalog and is only STABLE, so the generated column goes
+-- through an IMMUTABLE wrapper, which holds as long as the configurations
+-- in use are not dropped or redefined.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'english'
+    CONSTRAINT books_language_check CHECK (to_regconfig(language) IS NOT NULL);
+
+CREATE OR REPLACE FUNCTION books_ts_config(language TEXT) RETURNS regconfig AS $$
+    SELECT language::regconfig
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+
+DROP INDEX IF EXISTS idx_books_tsv;
+ALTER TABLE books DROP COLUMN IF EXISTS tsv;
+
+ALTER TABLE books
+ADD COLUMN tsv tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector(books_ts_config(language), coalesce(title, '')), 'A') ||
+    setweight(to_tsvector(books_ts_config(language), coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX idx_books_tsv ON books USING GIN (tsv);
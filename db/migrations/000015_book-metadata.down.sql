@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_books_genres;
+DROP INDEX IF EXISTS idx_books_author;
+
+ALTER TABLE books
+  DROP COLUMN IF EXISTS published_year,
+  DROP COLUMN IF EXISTS genres,
+  DROP COLUMN IF EXISTS author;
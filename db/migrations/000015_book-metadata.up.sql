@@ -0,0 +1,8 @@
+-- Metadata for faceted search and drill-down filters.
+ALTER TABLE books
+  ADD COLUMN IF NOT EXISTS author TEXT,
+  ADD COLUMN IF NOT EXISTS genres TEXT[] NOT NULL DEFAULT '{}',
+  ADD COLUMN IF NOT EXISTS published_year INT;
+
+CREATE INDEX IF NOT EXISTS idx_books_author ON books (lower(author));
+CREATE INDEX IF NOT EXISTS idx_books_genres ON books USING GIN (genres);
@@ -0,0 +1,27 @@
+-- Works group the editions of one book that were published under different
+-- ISBNs. title_key is the normalised title used to match new editions; it
+-- must be computed the same way as service.titleKey.
+CREATE TABLE IF NOT EXISTS works (
+  id SERIAL PRIMARY KEY,
+  title TEXT NOT NULL,
+  title_key TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- NULL until the book is embedded and matched.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS work_id INT REFERENCES works (id);
+
+CREATE INDEX IF NOT EXISTS idx_books_work_id ON books (work_id);
+
+-- Every existing book starts out as a work of its own; the duplicates report
+-- lists the ones worth merging.
+INSERT INTO works (id, title, title_key)
+SELECT id, title,
+       btrim(regexp_replace(
+         regexp_replace(lower(split_part(title, ':', 1)), '\([^)]*\)', ' ', 'g'),
+         '[^[:alnum:]]+', ' ', 'g'))
+FROM books;
+
+SELECT setval(pg_get_serial_sequence('works', 'id'), coalesce(max(id), 0) + 1, false) FROM works;
+
+UPDATE books SET work_id = id;
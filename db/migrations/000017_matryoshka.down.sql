@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_books_embedding_matryoshka;
+
+DROP TRIGGER IF EXISTS books_embedding_short ON books;
+
+DROP FUNCTION IF EXISTS books_embedding_short();
+
+ALTER TABLE books DROP COLUMN IF EXISTS embedding_short;
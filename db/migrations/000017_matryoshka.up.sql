@@ -0,0 +1,21 @@
+-- The first 256 dimensions of the embedding. Gemini embeddings are trained
+-- so that a prefix is itself a usable, lower resolution embedding, and cosine
+-- distance needs no re-normalisation.
+--
+-- A nullable column is added without rewriting the table, where a generated
+-- column would rewrite it under an ACCESS EXCLUSIVE lock. A trigger keeps it
+-- in step with embedding from now on; existing rows are backfilled in
+-- batches, and the HNSW index built concurrently, by the index command
+-- (index create matryoshka).
+ALTER TABLE books ADD COLUMN IF NOT EXISTS embedding_short VECTOR(256);
+
+CREATE OR REPLACE FUNCTION books_embedding_short() RETURNS trigger AS $$
+BEGIN
+    NEW.embedding_short := subvector(NEW.embedding, 1, 256)::vector(256);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER books_embedding_short
+    BEFORE INSERT OR UPDATE OF embedding ON books
+    FOR EACH ROW EXECUTE FUNCTION books_embedding_short();
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_search_feedback_event_id ON search_feedback (event_id);
+
+ALTER TABLE search_feedback DROP CONSTRAINT IF EXISTS search_feedback_event_isbn_kind_key;
@@ -0,0 +1,14 @@
+-- One vote of each kind per result of a search, so replaying feedback for
+-- an event cannot push a book up the ranking.
+DELETE FROM search_feedback AS a
+USING search_feedback AS b
+WHERE a.event_id = b.event_id
+  AND a.isbn = b.isbn
+  AND a.kind = b.kind
+  AND a.id > b.id;
+
+ALTER TABLE search_feedback
+  ADD CONSTRAINT search_feedback_event_isbn_kind_key UNIQUE (event_id, isbn, kind);
+
+-- The unique index leads with event_id and serves its lookups.
+DROP INDEX IF EXISTS idx_search_feedback_event_id;
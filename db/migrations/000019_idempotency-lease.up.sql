@@ -0,0 +1,4 @@
+-- How long the request that claimed a key may take to complete it. A claim
+-- left pending past its lease, e.g. by a crashed server, can be taken over
+-- by a retry instead of blocking the key until it expires.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
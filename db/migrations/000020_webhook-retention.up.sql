@@ -0,0 +1,4 @@
+-- Settled events are purged by age; deliveries are deleted with their event
+-- through the foreign key, which needs an index to avoid scanning them all.
+CREATE INDEX IF NOT EXISTS idx_webhook_events_created_at ON webhook_events (created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries (event_id);
@@ -0,0 +1,3 @@
+-- The original spellings are not kept, and normalised ISBNs are valid in
+-- the old schema, so there is nothing to undo.
+SELECT 1;
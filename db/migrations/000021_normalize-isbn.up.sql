@@ -0,0 +1,73 @@
+-- ISBNs are stored as bare ISBN-13s since the API normalises them (see
+-- internal/isbn). Rows written before then may hold hyphens, spaces or an
+-- ISBN-10, and can no longer be looked up or deleted; an upsert of the same
+-- book would add a second row. Convert them the same way. ISBNs that do not
+-- validate are left as they are.
+CREATE FUNCTION pg_temp.normalize_isbn(raw TEXT) RETURNS TEXT AS $$
+DECLARE
+    s TEXT := upper(translate(raw, '- ', ''));
+    body TEXT;
+    total INT := 0;
+    check_digit INT;
+BEGIN
+    IF s ~ '^[0-9]{9}[0-9X]$' THEN
+        FOR i IN 1..10 LOOP
+            total := total + (11 - i) * CASE WHEN substr(s, i, 1) = 'X' THEN 10 ELSE substr(s, i, 1)::INT END;
+        END LOOP;
+        IF total % 11 <> 0 THEN
+            RETURN raw;
+        END IF;
+        body := '978' || left(s, 9);
+    ELSIF s ~ '^97[89][0-9]{10}$' THEN
+        body := left(s, 12);
+    ELSE
+        RETURN raw;
+    END IF;
+
+    total := 0;
+    FOR i IN 1..12 LOOP
+        total := total + substr(body, i, 1)::INT * CASE WHEN i % 2 = 0 THEN 3 ELSE 1 END;
+    END LOOP;
+    check_digit := (10 - total % 10) % 10;
+    IF length(s) = 13 AND right(s, 1)::INT <> check_digit THEN
+        RETURN raw;
+    END IF;
+    RETURN body || check_digit;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Spellings of the same ISBN collide once normalised. Keep the row already
+-- stored normalised, which is the one the API reads and writes, else an
+-- embedded one, else the newest; its embedding jobs go with the others.
+DELETE FROM books
+WHERE id IN (
+    SELECT id
+    FROM (
+        SELECT id, row_number() OVER (
+            PARTITION BY pg_temp.normalize_isbn(isbn)
+            ORDER BY isbn = pg_temp.normalize_isbn(isbn) DESC,
+                     embedding_status = 'ready' DESC,
+                     id DESC
+        ) AS rank
+        FROM books
+        WHERE isbn IS NOT NULL
+    ) AS ranked
+    WHERE rank > 1
+);
+
+UPDATE books
+SET isbn = pg_temp.normalize_isbn(isbn)
+WHERE isbn <> pg_temp.normalize_isbn(isbn);
+
+-- Feedback is matched to books by ISBN. One vote of each kind per result of
+-- a search, as in 000018.
+DELETE FROM search_feedback AS a
+USING search_feedback AS b
+WHERE a.event_id = b.event_id
+  AND pg_temp.normalize_isbn(a.isbn) = pg_temp.normalize_isbn(b.isbn)
+  AND a.kind = b.kind
+  AND a.id > b.id;
+
+UPDATE search_feedback
+SET isbn = pg_temp.normalize_isbn(isbn)
+WHERE isbn <> pg_temp.normalize_isbn(isbn);
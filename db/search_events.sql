@@ -0,0 +1,65 @@
+-- name: InsertSearchEvents :copyfrom
+INSERT INTO search_events (
+    id, query, mode, result_isbns, result_count, top_score, latency_ms, degraded, tenant, created_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: TopSearchQueries :many
+SELECT lower(btrim(query))::text AS query,
+       count(*) AS searches,
+       avg(result_count)::float8 AS avg_results
+FROM search_events
+WHERE created_at >= @since
+GROUP BY 1
+ORDER BY searches DESC, query
+LIMIT @max_results;
+
+-- name: ZeroResultSearchQueries :many
+SELECT lower(btrim(query))::text AS query,
+       count(*) AS searches,
+       max(created_at)::timestamptz AS last_seen
+FROM search_events
+WHERE created_at >= @since AND result_count = 0
+GROUP BY 1
+ORDER BY searches DESC, query
+LIMIT @max_results;
+
+-- name: LowScoreSearchQueries :many
+-- Semantic queries whose best match stays below threshold, worst first.
+SELECT lower(btrim(query))::text AS query,
+       count(*) AS searches,
+       avg(top_score)::float8 AS avg_top_score
+FROM search_events
+WHERE created_at >= @since
+  AND mode = 'semantic'
+  AND NOT degraded
+  AND top_score < @threshold::float8
+GROUP BY 1
+ORDER BY avg_top_score, searches DESC
+LIMIT @max_results;
+
+-- name: SearchLatencyPercentiles :many
+SELECT mode,
+       count(*) AS searches,
+       percentile_cont(0.5) WITHIN GROUP (ORDER BY latency_ms)::float8 AS p50,
+       percentile_cont(0.9) WITHIN GROUP (ORDER BY latency_ms)::float8 AS p90,
+       percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms)::float8 AS p95,
+       percentile_cont(0.99) WITHIN GROUP (ORDER BY latency_ms)::float8 AS p99
+FROM search_events
+WHERE created_at >= @since
+GROUP BY mode
+ORDER BY mode;
+
+-- name: SuggestPopularQueries :many
+SELECT query::text AS query, searches
+FROM popular_queries
+WHERE query LIKE @prefix::text
+ORDER BY searches DESC, query
+LIMIT @max_results;
+
+-- name: RefreshPopularQueries :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY popular_queries;
+
+-- name: GetSearchEventResults :one
+SELECT result_isbns
+FROM search_events
+WHERE id = $1;
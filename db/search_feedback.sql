@@ -0,0 +1,33 @@
+-- name: InsertSearchFeedback :exec
+-- Repeated feedback of the same kind on the same result is ignored.
+INSERT INTO search_feedback (event_id, isbn, kind)
+VALUES ($1, $2, $3)
+ON CONFLICT (event_id, isbn, kind) DO NOTHING;
+
+-- name: GetFeedbackCounts :many
+SELECT isbn,
+       count(*) FILTER (WHERE kind = 'click') AS clicks,
+       count(*) FILTER (WHERE kind = 'relevant') AS relevant,
+       count(*) FILTER (WHERE kind = 'irrelevant') AS irrelevant
+FROM search_feedback
+WHERE isbn = ANY (@isbns::text[]) AND created_at >= @since
+GROUP BY isbn;
+
+-- name: WorstClickThroughQueries :many
+-- Queries with at least min_searches searches, lowest click-through first.
+WITH clicked AS (
+    SELECT DISTINCT event_id
+    FROM search_feedback
+    WHERE kind = 'click'
+)
+SELECT lower(btrim(e.query))::text AS query,
+       count(*) AS searches,
+       count(c.event_id) AS clicked,
+       (count(c.event_id)::float8 / count(*))::float8 AS ctr
+FROM search_events AS e
+LEFT JOIN clicked AS c ON c.event_id = e.id
+WHERE e.created_at >= @since
+GROUP BY 1
+HAVING count(*) >= @min_searches::bigint
+ORDER BY ctr, searches DESC
+LIMIT @max_results;
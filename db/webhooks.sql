@@ -0,0 +1,107 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (url, secret, event_types)
+VALUES ($1, $2, $3)
+RETURNING id, url, secret, event_types, active, created_at;
+
+-- name: ListWebhookSubscriptions :many
+SELECT id, url, secret, event_types, active, created_at
+FROM webhook_subscriptions
+ORDER BY id;
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: EnqueueWebhookEvent :execrows
+-- Records the event and fans it out to a pending delivery per matching
+-- active subscription. Nothing is recorded when no subscription matches.
+-- Returns the number of deliveries created.
+WITH targets AS (
+    SELECT s.id
+    FROM webhook_subscriptions AS s
+    WHERE s.active
+      AND (cardinality(s.event_types) = 0 OR @event_type::text = ANY (s.event_types))
+), event AS (
+    INSERT INTO webhook_events (event_type, payload)
+    SELECT @event_type, @payload::jsonb
+    WHERE EXISTS (SELECT 1 FROM targets)
+    RETURNING id
+)
+INSERT INTO webhook_deliveries (event_id, subscription_id)
+SELECT event.id, targets.id
+FROM event, targets;
+
+-- name: ClaimWebhookDeliveries :many
+-- Leases due deliveries; see ClaimEmbeddingJobs for the lease semantics.
+UPDATE webhook_deliveries AS d
+SET attempts = d.attempts + 1,
+    next_attempt_at = now() + make_interval(secs => @lease_seconds::float8)
+FROM webhook_events AS e, webhook_subscriptions AS s
+WHERE e.id = d.event_id
+  AND s.id = d.subscription_id
+  AND d.id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING d.id, d.attempts, e.id AS event_id, e.event_type, e.payload, e.created_at AS event_created_at, s.url, s.secret;
+
+-- name: MarkWebhookDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered',
+    delivered_at = now(),
+    last_status_code = $2,
+    last_error = NULL
+WHERE id = $1;
+
+-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET next_attempt_at = now() + make_interval(secs => @delay_seconds::float8),
+    last_status_code = @last_status_code,
+    last_error = @last_error
+WHERE id = @id;
+
+-- name: DeadLetterWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = 'dead',
+    last_status_code = $2,
+    last_error = $3
+WHERE id = $1;
+
+-- name: ListWebhookDeliveries :many
+SELECT d.id, d.event_id, e.event_type, d.subscription_id, d.status, d.attempts,
+       d.last_status_code, d.last_error, d.delivered_at, d.created_at
+FROM webhook_deliveries AS d
+JOIN webhook_events AS e ON e.id = d.event_id
+WHERE d.status = @status
+ORDER BY d.id DESC
+LIMIT @max_results;
+
+-- name: ReplayWebhookDelivery :execrows
+UPDATE webhook_deliveries
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = now(),
+    delivered_at = NULL
+WHERE id = $1;
+
+-- name: ReplayDeadWebhookDeliveries :execrows
+UPDATE webhook_deliveries
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = now()
+WHERE subscription_id = $1 AND status = 'dead';
+
+-- name: DeleteSettledWebhookEvents :execrows
+-- Deletes events created before $1 that have no pending delivery, together
+-- with their delivered and dead deliveries.
+DELETE FROM webhook_events AS e
+WHERE e.created_at < $1
+  AND NOT EXISTS (
+    SELECT 1
+    FROM webhook_deliveries AS d
+    WHERE d.event_id = e.id AND d.status = 'pending'
+  );
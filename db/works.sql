@@ -0,0 +1,139 @@
+-- name: CreateWork :one
+INSERT INTO works (title, title_key)
+VALUES ($1, $2)
+RETURNING id;
+
+-- name: FindWorkCandidates :many
+-- Works of the books within max_distance of embedding, nearest first, with
+-- the trigram similarity of each work's title key to title_key. The book
+-- with isbn itself is skipped so that re-embedding never matches itself.
+SELECT w.id AS work_id,
+       (b.embedding <=> @embedding)::float8 AS distance,
+       similarity(w.title_key, @title_key::text)::float8 AS title_similarity
+FROM books AS b
+JOIN works AS w ON w.id = b.work_id
+WHERE b.embedding IS NOT NULL
+  AND b.isbn IS DISTINCT FROM @isbn
+  AND b.embedding <=> @embedding <= @max_distance::float8
+ORDER BY b.embedding <=> @embedding
+LIMIT @max_results;
+
+-- name: FindWorkCandidatesMatryoshka :many
+-- Like FindWorkCandidates, but takes the candidates nearest by the truncated
+-- embeddings, which the matryoshka index serves, so that work matching does
+-- not need the full vector index.
+WITH candidates AS (
+    SELECT id
+    FROM books
+    WHERE embedding_short IS NOT NULL
+      AND work_id IS NOT NULL
+      AND isbn IS DISTINCT FROM @isbn
+    ORDER BY embedding_short <=> subvector(@embedding::vector, 1, 256)::vector(256)
+    LIMIT @candidates
+)
+SELECT w.id AS work_id,
+       (b.embedding <=> @embedding::vector)::float8 AS distance,
+       similarity(w.title_key, @title_key::text)::float8 AS title_similarity
+FROM candidates AS c
+JOIN books AS b ON b.id = c.id
+JOIN works AS w ON w.id = b.work_id
+WHERE b.embedding <=> @embedding::vector <= @max_distance::float8
+ORDER BY b.embedding <=> @embedding::vector
+LIMIT @max_results;
+
+-- name: SetBookWork :exec
+UPDATE books
+SET work_id = $2
+WHERE id = $1;
+
+-- name: ListWorkEditions :many
+-- Every book of the given works, oldest publication first.
+SELECT id, isbn, title, work_id, published_year
+FROM books
+WHERE work_id = ANY (@work_ids::int[])
+ORDER BY work_id, published_year NULLS LAST, id;
+
+-- name: SuspectedDuplicates :many
+-- Pairs each of up to max_books embedded books after the given ID, in ID
+-- order, with its nearest neighbour from another work. A page of books costs
+-- a bounded number of index scans however large the catalog, and the caller
+-- keeps the pairs within its distance. Every book of the page is returned,
+-- with NULL neighbour columns if the index found none, so the last row is
+-- where the next page starts. Mirrored marks the second row of a
+-- pair within max_distance whose books are each other's nearest neighbour,
+-- which is already listed from the book with the lower ID.
+SELECT a.id, a.isbn, a.title, a.work_id,
+       n.id AS duplicate_id, n.isbn AS duplicate_isbn, n.title AS duplicate_title, n.work_id AS duplicate_work_id,
+       n.distance::float8 AS distance,
+       similarity(lower(a.title), lower(n.title))::float8 AS title_similarity,
+       (CASE WHEN n.id < a.id AND n.distance <= @max_distance::float8 THEN a.id = (
+           SELECT m.id
+           FROM books AS m
+           WHERE m.embedding IS NOT NULL
+             AND m.id <> n.id
+             AND m.work_id IS DISTINCT FROM n.work_id
+           ORDER BY m.embedding <=> n.embedding
+           LIMIT 1
+       ) ELSE false END)::boolean AS mirrored
+FROM (
+    SELECT id, isbn, title, work_id, embedding
+    FROM books
+    WHERE embedding IS NOT NULL AND id > @after::int
+    ORDER BY id
+    LIMIT @max_books
+) AS a
+LEFT JOIN LATERAL (
+    SELECT b.id, b.isbn, b.title, b.work_id, b.embedding, b.embedding <=> a.embedding AS distance
+    FROM books AS b
+    WHERE b.embedding IS NOT NULL
+      AND b.id <> a.id
+      AND b.work_id IS DISTINCT FROM a.work_id
+    ORDER BY b.embedding <=> a.embedding
+    LIMIT 1
+) AS n ON true
+ORDER BY a.id;
+
+-- name: SuspectedDuplicatesMatryoshka :many
+-- Like SuspectedDuplicates, but takes four neighbours of each book by the
+-- truncated embeddings, which the matryoshka index serves, and keeps the
+-- nearest of them by the full vectors.
+SELECT a.id, a.isbn, a.title, a.work_id,
+       n.id AS duplicate_id, n.isbn AS duplicate_isbn, n.title AS duplicate_title, n.work_id AS duplicate_work_id,
+       n.distance::float8 AS distance,
+       similarity(lower(a.title), lower(n.title))::float8 AS title_similarity,
+       (CASE WHEN n.id < a.id AND n.distance <= @max_distance::float8 THEN a.id = (
+           SELECT c.id
+           FROM (
+               SELECT m.id, m.embedding
+               FROM books AS m
+               WHERE m.embedding_short IS NOT NULL
+                 AND m.id <> n.id
+                 AND m.work_id IS DISTINCT FROM n.work_id
+               ORDER BY m.embedding_short <=> n.embedding_short
+               LIMIT 4
+           ) AS c
+           ORDER BY c.embedding <=> n.embedding
+           LIMIT 1
+       ) ELSE false END)::boolean AS mirrored
+FROM (
+    SELECT id, isbn, title, work_id, embedding, embedding_short
+    FROM books
+    WHERE embedding_short IS NOT NULL AND id > @after::int
+    ORDER BY id
+    LIMIT @max_books
+) AS a
+LEFT JOIN LATERAL (
+    SELECT c.id, c.isbn, c.title, c.work_id, c.embedding, c.embedding_short, c.embedding <=> a.embedding AS distance
+    FROM (
+        SELECT b.id, b.isbn, b.title, b.work_id, b.embedding, b.embedding_short
+        FROM books AS b
+        WHERE b.embedding_short IS NOT NULL
+          AND b.id <> a.id
+          AND b.work_id IS DISTINCT FROM a.work_id
+        ORDER BY b.embedding_short <=> a.embedding_short
+        LIMIT 4
+    ) AS c
+    ORDER BY c.embedding <=> a.embedding
+    LIMIT 1
+) AS n ON true
+ORDER BY a.id;
@@ -0,0 +1,113 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/isbn"
+	"github.com/nmdra/Semantic-Search/internal/repository"
+	"github.com/nmdra/Semantic-Search/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Feedback kinds. A click is implicit interest; relevant and irrelevant are
+// explicit votes on a result.
+const (
+	FeedbackClick      = "click"
+	FeedbackRelevant   = "relevant"
+	FeedbackIrrelevant = "irrelevant"
+)
+
+var feedbackKinds = []string{FeedbackClick, FeedbackRelevant, FeedbackIrrelevant}
+
+type QueryCTR struct {
+	Query    string  `json:"query"`
+	Searches int64   `json:"searches"`
+	Clicked  int64   `json:"clicked"`
+	CTR      float64 `json:"ctr"`
+}
+
+// RecordFeedback stores feedback on a result of the search identified by
+// eventID, as returned in the X-Search-Event-Id header. The event must exist,
+// written or still buffered by the Recorder, and must have returned the
+// book. Repeated feedback of the same kind on a result counts once.
+func (s *Service) RecordFeedback(ctx context.Context, eventID, isbnValue, kind string) error {
+	var fields []service.FieldError
+
+	id, err := uuid.Parse(eventID)
+	if err != nil {
+		fields = append(fields, service.FieldError{Field: "event_id", Code: "invalid_event_id", Message: "event_id must be a search event UUID"})
+	}
+	normalized, err := isbn.Normalize(isbnValue)
+	if err != nil {
+		fields = append(fields, service.FieldError{Field: "isbn", Code: "invalid_isbn", Message: err.Error()})
+	}
+	if !slices.Contains(feedbackKinds, kind) {
+		fields = append(fields, service.FieldError{Field: "type", Code: "invalid_type", Message: "type must be one of click, relevant, irrelevant"})
+	}
+	if len(fields) > 0 {
+		verr := service.Validation("invalid_feedback", "feedback is invalid")
+		verr.Fields = fields
+		return verr
+	}
+
+	results, err := s.eventResults(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(results, normalized) {
+		verr := service.Validation("invalid_feedback", "feedback is invalid")
+		verr.Fields = []service.FieldError{{Field: "isbn", Code: "not_in_results", Message: "isbn was not among the results of the search"}}
+		return verr
+	}
+
+	err = s.Queries.InsertSearchFeedback(ctx, repository.InsertSearchFeedbackParams{
+		EventID: pgtype.UUID{Bytes: id, Valid: true},
+		Isbn:    normalized,
+		Kind:    kind,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record search feedback: %w", err)
+	}
+	return nil
+}
+
+// eventResults returns the ISBNs a search event returned.
+func (s *Service) eventResults(ctx context.Context, id uuid.UUID) ([]string, error) {
+	if isbns, ok := s.Recorder.Pending(id); ok {
+		return isbns, nil
+	}
+	isbns, err := s.Queries.GetSearchEventResults(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, service.NotFound("search_event_not_found", "search event not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up search event: %w", err)
+	}
+	return isbns, nil
+}
+
+// WorstClickThrough returns queries searched at least minSearches times,
+// lowest click-through rate first.
+func (s *Service) WorstClickThrough(ctx context.Context, window time.Duration, minSearches int64, limit int32) ([]QueryCTR, error) {
+	rows, err := s.Queries.WorstClickThroughQueries(ctx, repository.WorstClickThroughQueriesParams{
+		Since:       since(window),
+		MinSearches: minSearches,
+		MaxResults:  limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query click-through: %w", err)
+	}
+
+	out := make([]QueryCTR, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, QueryCTR{Query: row.Query, Searches: row.Searches, Clicked: row.Clicked, CTR: row.Ctr})
+	}
+	return out, nil
+}
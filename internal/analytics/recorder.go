@@ -0,0 +1,218 @@
+// Package analytics records search requests and reports on them.
+package analytics
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Search modes.
+const (
+	ModeSemantic = "semantic"
+	ModeText     = "text"
+	ModeFuzzy    = "fuzzy"
+)
+
+// Event is one search as answered to a client.
+type Event struct {
+	ID          uuid.UUID
+	Query       string
+	Mode        string
+	ResultISBNs []string
+	TopScore    *float64 // nil when the results carry no comparable score
+	Latency     time.Duration
+	Degraded    bool
+	Tenant      string
+	Time        time.Time
+}
+
+// NewEventID returns a time-ordered ID for an event, so clients can refer to
+// a search (e.g. in feedback) before it has been written.
+func NewEventID() uuid.UUID {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.New()
+	}
+	return id
+}
+
+// Recorder writes search events to Postgres in batches from a background
+// goroutine, keeping the request path free of database writes. Record never
+// blocks: when the buffer is full the event is dropped and counted.
+type Recorder struct {
+	Queries *repository.Queries
+	Logger  *slog.Logger
+
+	BufferSize    int           // queued events before dropping; zero means 4096
+	BatchSize     int           // events per COPY; zero means 256
+	FlushInterval time.Duration // max delay before a partial batch is written; zero means 1s
+
+	once    sync.Once
+	events  chan Event
+	dropped atomic.Int64
+
+	mu      sync.Mutex
+	pending map[uuid.UUID][]string // results of queued events, until written
+
+	insert func(ctx context.Context, rows []repository.InsertSearchEventsParams) (int64, error)
+}
+
+func (r *Recorder) init() {
+	r.once.Do(func() {
+		size := r.BufferSize
+		if size <= 0 {
+			size = 4096
+		}
+		r.events = make(chan Event, size)
+		r.pending = make(map[uuid.UUID][]string)
+		if r.insert == nil {
+			r.insert = r.Queries.InsertSearchEvents
+		}
+	})
+}
+
+// Record queues e for writing. It reports whether the event was accepted;
+// a nil Recorder accepts nothing.
+func (r *Recorder) Record(e Event) bool {
+	if r == nil {
+		return false
+	}
+	r.init()
+
+	r.mu.Lock()
+	r.pending[e.ID] = e.ResultISBNs
+	r.mu.Unlock()
+
+	select {
+	case r.events <- e:
+		return true
+	default:
+		r.forget(e.ID)
+		if r.dropped.Add(1)%1000 == 1 {
+			r.Logger.Warn("Search analytics buffer full, dropping events", "dropped", r.dropped.Load())
+		}
+		return false
+	}
+}
+
+// Pending returns the result ISBNs of an accepted event that has not been
+// written yet.
+func (r *Recorder) Pending(id uuid.UUID) ([]string, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.init()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	isbns, ok := r.pending[id]
+	return isbns, ok
+}
+
+func (r *Recorder) forget(ids ...uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		delete(r.pending, id)
+	}
+}
+
+// Dropped returns how many events were discarded because the buffer was full.
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Run writes queued events until ctx is cancelled, then flushes what is left.
+func (r *Recorder) Run(ctx context.Context) {
+	r.init()
+
+	interval := r.FlushInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	batchSize := r.BatchSize
+	if batchSize <= 0 {
+		batchSize = 256
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]repository.InsertSearchEventsParams, 0, batchSize)
+	for {
+		select {
+		case e := <-r.events:
+			batch = append(batch, toParams(e))
+			if len(batch) >= batchSize {
+				batch = r.flush(ctx, batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(ctx, batch)
+		case <-ctx.Done():
+			// The final write gets its own deadline.
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			r.flush(flushCtx, r.drain(batch))
+			cancel()
+			return
+		}
+	}
+}
+
+// drain appends every queued event to batch without blocking.
+func (r *Recorder) drain(batch []repository.InsertSearchEventsParams) []repository.InsertSearchEventsParams {
+	for {
+		select {
+		case e := <-r.events:
+			batch = append(batch, toParams(e))
+		default:
+			return batch
+		}
+	}
+}
+
+// flush writes batch and returns it emptied. Failed batches are dropped:
+// analytics must never back up into the request path.
+func (r *Recorder) flush(ctx context.Context, batch []repository.InsertSearchEventsParams) []repository.InsertSearchEventsParams {
+	if len(batch) == 0 {
+		return batch
+	}
+	if _, err := r.insert(ctx, batch); err != nil {
+		r.Logger.Warn("Failed to write search events", "count", len(batch), "error", err)
+	}
+	ids := make([]uuid.UUID, len(batch))
+	for i, p := range batch {
+		ids[i] = p.ID.Bytes
+	}
+	r.forget(ids...)
+	return batch[:0]
+}
+
+func toParams(e Event) repository.InsertSearchEventsParams {
+	isbns := e.ResultISBNs
+	if isbns == nil {
+		isbns = []string{}
+	}
+	p := repository.InsertSearchEventsParams{
+		ID:          pgtype.UUID{Bytes: e.ID, Valid: true},
+		Query:       e.Query,
+		Mode:        e.Mode,
+		ResultIsbns: isbns,
+		ResultCount: int32(len(isbns)),
+		LatencyMs:   float64(e.Latency.Microseconds()) / 1000,
+		Degraded:    e.Degraded,
+		Tenant:      pgtype.Text{String: e.Tenant, Valid: e.Tenant != ""},
+		CreatedAt:   pgtype.Timestamptz{Time: e.Time, Valid: true},
+	}
+	if e.TopScore != nil {
+		p.TopScore = pgtype.Float8{Float64: *e.TopScore, Valid: true}
+	}
+	return p
+}
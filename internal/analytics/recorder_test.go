@@ -0,0 +1,173 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchSink collects the batches a Recorder writes.
+type batchSink struct {
+	mu      sync.Mutex
+	batches [][]repository.InsertSearchEventsParams
+	err     error
+}
+
+func (s *batchSink) insert(_ context.Context, rows []repository.InsertSearchEventsParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]repository.InsertSearchEventsParams(nil), rows...))
+	return int64(len(rows)), s.err
+}
+
+func (s *batchSink) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sizes []int
+	for _, b := range s.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func newTestRecorder(sink *batchSink, buffer, batch int) *Recorder {
+	return &Recorder{
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		BufferSize:    buffer,
+		BatchSize:     batch,
+		FlushInterval: time.Second,
+		insert:        sink.insert,
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	t.Run("writes full batches, then partial ones on the interval", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			sink := &batchSink{}
+			r := newTestRecorder(sink, 100, 3)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() { r.Run(ctx); close(done) }()
+
+			for i := range 4 {
+				require.True(t, r.Record(Event{ID: NewEventID(), Query: "q", ResultISBNs: make([]string, i)}))
+			}
+			synctest.Wait()
+			assert.Equal(t, []int{3}, sink.sizes())
+
+			time.Sleep(time.Second)
+			synctest.Wait()
+			assert.Equal(t, []int{3, 1}, sink.sizes())
+
+			cancel()
+			<-done
+		})
+	})
+
+	t.Run("flushes queued events on shutdown", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			sink := &batchSink{}
+			r := newTestRecorder(sink, 100, 50)
+			for range 5 {
+				require.True(t, r.Record(Event{ID: NewEventID(), Query: "q"}))
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			r.Run(ctx)
+
+			assert.Equal(t, []int{5}, sink.sizes())
+		})
+	})
+
+	t.Run("drops events when the buffer is full", func(t *testing.T) {
+		sink := &batchSink{}
+		r := newTestRecorder(sink, 2, 10)
+
+		assert.True(t, r.Record(Event{}))
+		assert.True(t, r.Record(Event{}))
+		assert.False(t, r.Record(Event{}))
+		assert.Equal(t, int64(1), r.Dropped())
+	})
+
+	t.Run("failed writes are dropped", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			sink := &batchSink{err: errors.New("database down")}
+			r := newTestRecorder(sink, 100, 1)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() { r.Run(ctx); close(done) }()
+
+			r.Record(Event{})
+			r.Record(Event{})
+			synctest.Wait()
+			assert.Equal(t, []int{1, 1}, sink.sizes())
+
+			cancel()
+			<-done
+		})
+	})
+
+	t.Run("remembers results of events until they are written", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			sink := &batchSink{}
+			r := newTestRecorder(sink, 100, 50)
+			id := NewEventID()
+			require.True(t, r.Record(Event{ID: id, Query: "q", ResultISBNs: []string{"9780441172719"}}))
+
+			isbns, ok := r.Pending(id)
+			require.True(t, ok)
+			assert.Equal(t, []string{"9780441172719"}, isbns)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			r.Run(ctx)
+
+			_, ok = r.Pending(id)
+			assert.False(t, ok)
+		})
+	})
+
+	t.Run("nil recorder accepts nothing", func(t *testing.T) {
+		var r *Recorder
+		assert.False(t, r.Record(Event{}))
+		_, ok := r.Pending(NewEventID())
+		assert.False(t, ok)
+	})
+}
+
+func TestToParams(t *testing.T) {
+	score := 0.83
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := toParams(Event{
+		ID:          NewEventID(),
+		Query:       "dune",
+		Mode:        ModeSemantic,
+		ResultISBNs: []string{"9780441013593", "9780441172719"},
+		TopScore:    &score,
+		Latency:     1500 * time.Microsecond,
+		Time:        at,
+	})
+
+	assert.True(t, p.ID.Valid)
+	assert.Equal(t, int32(2), p.ResultCount)
+	assert.Equal(t, 0.83, p.TopScore.Float64)
+	assert.True(t, p.TopScore.Valid)
+	assert.Equal(t, 1.5, p.LatencyMs)
+	assert.False(t, p.Tenant.Valid)
+	assert.Equal(t, at, p.CreatedAt.Time)
+
+	empty := toParams(Event{Mode: ModeText})
+	assert.Equal(t, []string{}, empty.ResultIsbns)
+	assert.False(t, empty.TopScore.Valid)
+}
@@ -0,0 +1,124 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Service reports on recorded searches over a trailing time window.
+type Service struct {
+	Queries *repository.Queries
+	// Recorder holds the events not written yet, which feedback may
+	// already refer to. Nil when search analytics are disabled.
+	Recorder *Recorder
+}
+
+type QueryCount struct {
+	Query      string  `json:"query"`
+	Searches   int64   `json:"searches"`
+	AvgResults float64 `json:"avg_results"`
+}
+
+type ZeroResultQuery struct {
+	Query    string    `json:"query"`
+	Searches int64     `json:"searches"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+type LowScoreQuery struct {
+	Query       string  `json:"query"`
+	Searches    int64   `json:"searches"`
+	AvgTopScore float64 `json:"avg_top_score"`
+}
+
+type LatencyStats struct {
+	Mode     string  `json:"mode"`
+	Searches int64   `json:"searches"`
+	P50      float64 `json:"p50_ms"`
+	P90      float64 `json:"p90_ms"`
+	P95      float64 `json:"p95_ms"`
+	P99      float64 `json:"p99_ms"`
+}
+
+// TopQueries returns the most frequent queries, compared case-insensitively.
+func (s *Service) TopQueries(ctx context.Context, window time.Duration, limit int32) ([]QueryCount, error) {
+	rows, err := s.Queries.TopSearchQueries(ctx, repository.TopSearchQueriesParams{
+		Since:      since(window),
+		MaxResults: limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top searches: %w", err)
+	}
+
+	out := make([]QueryCount, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, QueryCount{Query: row.Query, Searches: row.Searches, AvgResults: row.AvgResults})
+	}
+	return out, nil
+}
+
+// ZeroResultQueries returns the most frequent queries that found nothing.
+func (s *Service) ZeroResultQueries(ctx context.Context, window time.Duration, limit int32) ([]ZeroResultQuery, error) {
+	rows, err := s.Queries.ZeroResultSearchQueries(ctx, repository.ZeroResultSearchQueriesParams{
+		Since:      since(window),
+		MaxResults: limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query zero-result searches: %w", err)
+	}
+
+	out := make([]ZeroResultQuery, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, ZeroResultQuery{Query: row.Query, Searches: row.Searches, LastSeen: row.LastSeen.Time})
+	}
+	return out, nil
+}
+
+// LowScoreQueries returns semantic queries whose best match scored below
+// threshold on average, worst first.
+func (s *Service) LowScoreQueries(ctx context.Context, window time.Duration, threshold float64, limit int32) ([]LowScoreQuery, error) {
+	rows, err := s.Queries.LowScoreSearchQueries(ctx, repository.LowScoreSearchQueriesParams{
+		Since:      since(window),
+		Threshold:  threshold,
+		MaxResults: limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query low-score searches: %w", err)
+	}
+
+	out := make([]LowScoreQuery, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, LowScoreQuery{Query: row.Query, Searches: row.Searches, AvgTopScore: row.AvgTopScore})
+	}
+	return out, nil
+}
+
+// Latency returns latency percentiles per search mode.
+func (s *Service) Latency(ctx context.Context, window time.Duration) ([]LatencyStats, error) {
+	rows, err := s.Queries.SearchLatencyPercentiles(ctx, since(window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query search latency: %w", err)
+	}
+
+	out := make([]LatencyStats, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, LatencyStats{
+			Mode:     row.Mode,
+			Searches: row.Searches,
+			P50:      row.P50,
+			P90:      row.P90,
+			P95:      row.P95,
+			P99:      row.P99,
+		})
+	}
+	return out, nil
+}
+
+func since(window time.Duration) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.Now().Add(-window), Valid: true}
+}
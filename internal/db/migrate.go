@@ -2,19 +2,67 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 
 	migrations "github.com/nmdra/Semantic-Search/db"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
+// MigrationStatus describes how far the database schema is behind the
+// migrations embedded in the binary.
+type MigrationStatus struct {
+	Current uint `json:"current"`
+	Latest  uint `json:"latest"`
+	Dirty   bool `json:"dirty"`
+}
+
+// Pending reports whether there are embedded migrations not yet applied.
+func (s MigrationStatus) Pending() bool {
+	return s.Current < s.Latest
+}
+
 func RunMigrations(dsn string, logger *slog.Logger) error {
+	return withMigrate(dsn, logger, func(m *migrate.Migrate, _ source.Driver) error {
+		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+			return fmt.Errorf("migrate up: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetMigrationStatus compares the applied schema version against the latest
+// embedded migration without changing anything.
+func GetMigrationStatus(dsn string, logger *slog.Logger) (MigrationStatus, error) {
+	var status MigrationStatus
+
+	err := withMigrate(dsn, logger, func(m *migrate.Migrate, src source.Driver) error {
+		version, dirty, err := m.Version()
+		if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+			return fmt.Errorf("migrate version: %w", err)
+		}
+		status.Current, status.Dirty = version, dirty
+
+		latest, err := latestVersion(src)
+		if err != nil {
+			return err
+		}
+		status.Latest = latest
+		return nil
+	})
+
+	return status, err
+}
+
+func withMigrate(dsn string, logger *slog.Logger, fn func(*migrate.Migrate, source.Driver) error) error {
 	db, err := sql.Open("pgx", dsn) // use pgx with database/sql
 	if err != nil {
 		return fmt.Errorf("open db: %w", err)
@@ -40,10 +88,29 @@ func RunMigrations(dsn string, logger *slog.Logger) error {
 	if err != nil {
 		return fmt.Errorf("migrate instance: %w", err)
 	}
+	defer func() {
+		if srcErr, dbErr := m.Close(); srcErr != nil || dbErr != nil {
+			logger.Warn("error closing migrate instance", "source", srcErr, "database", dbErr)
+		}
+	}()
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("migrate up: %w", err)
+	return fn(m, src)
+}
+
+func latestVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("first migration: %w", err)
 	}
 
-	return nil
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("next migration: %w", err)
+		}
+		version = next
+	}
 }
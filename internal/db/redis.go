@@ -9,20 +9,26 @@ import (
 )
 
 // NewRedisClient creates a Redis client and logs the connection status.
+// Redis is only used as a cache, so an unreachable server is not fatal: the
+// client keeps redialling with backoff on subsequent commands.
 func NewRedisClient(addr string, logger *slog.Logger) *redis.Client {
 	client := redis.NewClient(&redis.Options{
-		Addr:         addr,
-		ReadTimeout:  1 * time.Second,
-		WriteTimeout: 1 * time.Second,
-		PoolSize:     10,
+		Addr:            addr,
+		DialTimeout:     1 * time.Second,
+		ReadTimeout:     1 * time.Second,
+		WriteTimeout:    1 * time.Second,
+		PoolSize:        10,
+		MaxRetries:      1,
+		MinRetryBackoff: 50 * time.Millisecond,
+		MaxRetryBackoff: 500 * time.Millisecond,
 	})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
-		logger.Error("Failed to connect to Redis", "addr", addr, "error", err)
-		panic("Failed to connect to Redis: " + err.Error())
+		logger.Warn("Redis unavailable, continuing without cache until it recovers", "addr", addr, "error", err)
+		return client
 	}
 
 	logger.Info("Connected to Redis", "addr", addr)
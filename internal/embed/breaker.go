@@ -0,0 +1,173 @@
+package embed
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a call is rejected because its breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker is a consecutive-failure circuit breaker. After Threshold failures
+// in a row it opens and rejects calls for Cooldown; it then lets a single
+// probe through (half-open). A successful probe closes it, a failed one
+// reopens it with the cooldown doubled, up to MaxCooldown.
+//
+// The zero value is usable and opens after 5 failures for 5s..1m. A nil
+// *Breaker never opens.
+type Breaker struct {
+	Threshold   int
+	Cooldown    time.Duration
+	MaxCooldown time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	backoff  time.Duration
+	openedAt time.Time
+	now      func() time.Time
+}
+
+// Allow reports whether a call may proceed. In the half-open state only the
+// first caller is let through until Success or Failure is recorded.
+func (b *Breaker) Allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.clock().Sub(b.openedAt) < b.currentBackoff() {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		return true
+	case BreakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// Success records a successful call and closes the breaker.
+func (b *Breaker) Success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = 0
+	b.backoff = 0
+}
+
+// Failure records a failed call, opening the breaker once the threshold is hit.
+func (b *Breaker) Failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.backoff = min(b.currentBackoff()*2, b.maxCooldown())
+		b.open()
+	case BreakerClosed:
+		b.failures++
+		if b.failures >= b.threshold() {
+			b.open()
+		}
+	}
+}
+
+// Abort gives up a call admitted by Allow without recording an outcome, e.g.
+// because the caller's context was cancelled. A half-open breaker becomes
+// ready for another probe.
+func (b *Breaker) Abort() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		b.state = BreakerOpen
+	}
+}
+
+// State returns the current breaker state.
+func (b *Breaker) State() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.clock().Sub(b.openedAt) >= b.currentBackoff() {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+func (b *Breaker) open() {
+	b.state = BreakerOpen
+	b.failures = 0
+	b.openedAt = b.clock()
+}
+
+func (b *Breaker) currentBackoff() time.Duration {
+	if b.backoff > 0 {
+		return b.backoff
+	}
+	if b.Cooldown > 0 {
+		return b.Cooldown
+	}
+	return 5 * time.Second
+}
+
+func (b *Breaker) maxCooldown() time.Duration {
+	if b.MaxCooldown > 0 {
+		return b.MaxCooldown
+	}
+	return time.Minute
+}
+
+func (b *Breaker) threshold() int {
+	if b.Threshold > 0 {
+		return b.Threshold
+	}
+	return 5
+}
+
+func (b *Breaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,195 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrCacheMiss is returned by Cache.Get when the key is absent or expired.
+var ErrCacheMiss = errors.New("embedding cache miss")
+
+// Cache is a key-value store for encoded embeddings used by CachedEmbedder.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CacheStats summarises the entries of one key namespace, i.e. one
+// combination of key version, model, dimension and task type.
+type CacheStats struct {
+	Namespace string
+	Entries   int64
+	Bytes     int64
+	Expired   int64 // expired entries awaiting purge; always zero for Redis
+}
+
+// AdminCache is a shared cache that can report on and drop its entries.
+type AdminCache interface {
+	Cache
+	Stats(ctx context.Context) ([]CacheStats, error)
+	Flush(ctx context.Context) (int64, error)
+}
+
+// RedisCache stores embeddings in Redis with a per-key expiry.
+type RedisCache struct {
+	Client *redis.Client
+}
+
+func (r *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.Client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return value, err
+}
+
+func (r *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.Client.Set(ctx, key, value, ttl).Err()
+}
+
+// Stats scans all embedding keys. It is meant for the admin command, not for
+// request paths.
+func (r *RedisCache) Stats(ctx context.Context) ([]CacheStats, error) {
+	byNamespace := make(map[string]*CacheStats)
+	err := r.scan(ctx, func(keys []string) error {
+		pipe := r.Client.Pipeline()
+		lens := make([]*redis.IntCmd, len(keys))
+		for i, key := range keys {
+			lens[i] = pipe.StrLen(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		for i, key := range keys {
+			ns := namespaceOf(key)
+			s, ok := byNamespace[ns]
+			if !ok {
+				s = &CacheStats{Namespace: ns}
+				byNamespace[ns] = s
+			}
+			s.Entries++
+			s.Bytes += lens[i].Val()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan redis embedding cache: %w", err)
+	}
+
+	stats := make([]CacheStats, 0, len(byNamespace))
+	for _, s := range byNamespace {
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b CacheStats) int { return strings.Compare(a.Namespace, b.Namespace) })
+	return stats, nil
+}
+
+// Flush deletes every embedding key and returns how many were removed.
+func (r *RedisCache) Flush(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.scan(ctx, func(keys []string) error {
+		deleted, err := r.Client.Unlink(ctx, keys...).Result()
+		n += deleted
+		return err
+	})
+	if err != nil {
+		return n, fmt.Errorf("flush redis embedding cache: %w", err)
+	}
+	return n, nil
+}
+
+// scan calls fn with batches of keys under CacheKeyPrefix.
+func (r *RedisCache) scan(ctx context.Context, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := r.Client.Scan(ctx, cursor, CacheKeyPrefix+"*", 500).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// namespaceOf strips the trailing hash from a cache key.
+func namespaceOf(key string) string {
+	if i := strings.LastIndexByte(key, ':'); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// PostgresCache stores embeddings in the unlogged embedding_cache table, for
+// deployments without Redis. Expired rows are ignored on read and removed by
+// Purge.
+type PostgresCache struct {
+	Queries *repository.Queries
+}
+
+func (p *PostgresCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := p.Queries.GetCachedEmbedding(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCacheMiss
+	}
+	return value, err
+}
+
+func (p *PostgresCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return p.Queries.SetCachedEmbedding(ctx, repository.SetCachedEmbeddingParams{
+		Key:        key,
+		Value:      value,
+		TtlSeconds: ttl.Seconds(),
+	})
+}
+
+// Purge deletes expired entries and returns how many were removed.
+func (p *PostgresCache) Purge(ctx context.Context) (int64, error) {
+	n, err := p.Queries.DeleteExpiredCachedEmbeddings(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("purge embedding cache: %w", err)
+	}
+	return n, nil
+}
+
+func (p *PostgresCache) Stats(ctx context.Context) ([]CacheStats, error) {
+	rows, err := p.Queries.GetEmbeddingCacheStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("embedding cache stats: %w", err)
+	}
+
+	stats := make([]CacheStats, 0, len(rows))
+	for _, row := range rows {
+		stats = append(stats, CacheStats{
+			Namespace: row.Namespace,
+			Entries:   row.Entries,
+			Bytes:     row.Bytes,
+			Expired:   row.Expired,
+		})
+	}
+	return stats, nil
+}
+
+// Flush deletes every entry and returns how many were removed.
+func (p *PostgresCache) Flush(ctx context.Context) (int64, error) {
+	n, err := p.Queries.DeleteAllCachedEmbeddings(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("flush embedding cache: %w", err)
+	}
+	return n, nil
+}
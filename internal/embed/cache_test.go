@@ -0,0 +1,202 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ Cache      = (*LRU)(nil)
+	_ AdminCache = (*RedisCache)(nil)
+	_ AdminCache = (*PostgresCache)(nil)
+)
+
+// mapCache is an in-memory shared cache that can be made to fail.
+type mapCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	err  error
+	gets int
+}
+
+func (m *mapCache) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gets++
+	if m.err != nil {
+		return nil, m.err
+	}
+	v, ok := m.data[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return v, nil
+}
+
+func (m *mapCache) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	if m.data == nil {
+		m.data = make(map[string][]byte)
+	}
+	m.data[key] = value
+	return nil
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		l := NewLRU(2)
+		require.NoError(t, l.Set(ctx, "a", []byte("1"), 0))
+		require.NoError(t, l.Set(ctx, "b", []byte("2"), 0))
+		_, err := l.Get(ctx, "a") // a is now more recent than b
+		require.NoError(t, err)
+		require.NoError(t, l.Set(ctx, "c", []byte("3"), 0))
+
+		_, err = l.Get(ctx, "b")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		v, err := l.Get(ctx, "a")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("1"), v)
+		assert.Equal(t, 2, l.Len())
+	})
+
+	t.Run("expires entries", func(t *testing.T) {
+		now := time.Unix(0, 0)
+		l := NewLRU(2)
+		l.now = func() time.Time { return now }
+
+		require.NoError(t, l.Set(ctx, "a", []byte("1"), time.Minute))
+		_, err := l.Get(ctx, "a")
+		require.NoError(t, err)
+
+		now = now.Add(time.Minute)
+		_, err = l.Get(ctx, "a")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.Equal(t, 0, l.Len())
+	})
+}
+
+func TestCachedEmbedder(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("local tier skips shared cache and base", func(t *testing.T) {
+		base := &faultyEmbedder{}
+		shared := &mapCache{}
+		c := &CachedEmbedder{Base: base, Local: NewLRU(8), Cache: shared, Logger: logger}
+
+		_, err := c.Embed(ctx, "Dune")
+		require.NoError(t, err)
+		_, err = c.Embed(ctx, "  dune ")
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, base.Calls())
+		assert.Equal(t, 1, shared.gets)
+	})
+
+	t.Run("shared hit fills local tier", func(t *testing.T) {
+		base := &faultyEmbedder{}
+		shared := &mapCache{}
+		warm := &CachedEmbedder{Base: base, Cache: shared, Logger: logger}
+		_, err := warm.Embed(ctx, "dune")
+		require.NoError(t, err)
+
+		c := &CachedEmbedder{Base: base, Local: NewLRU(8), Cache: shared, Logger: logger}
+		for range 3 {
+			_, err := c.Embed(ctx, "dune")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, base.Calls())
+		assert.Equal(t, 2, shared.gets)
+	})
+
+	t.Run("failing shared cache trips breaker", func(t *testing.T) {
+		base := &faultyEmbedder{}
+		shared := &mapCache{err: errors.New("connection refused")}
+		c := &CachedEmbedder{
+			Base:    base,
+			Cache:   shared,
+			Breaker: &Breaker{Threshold: 2, Cooldown: time.Hour},
+			Logger:  logger,
+		}
+
+		for range 5 {
+			_, err := c.Embed(ctx, "dune")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 5, base.Calls())
+		assert.Equal(t, 2, shared.gets)
+	})
+}
+
+func TestVectorCodec(t *testing.T) {
+	vec := []float32{0, 1, -1, 0.5, -0.333, 1e-6, 65504, 3.14159}
+
+	t.Run("float32 is lossless", func(t *testing.T) {
+		data := EncodeVector(vec, Float32)
+		assert.Len(t, data, headerSize+4*len(vec))
+
+		got, err := DecodeVector(data)
+		require.NoError(t, err)
+		assert.Equal(t, vec, got)
+	})
+
+	t.Run("float16 is close", func(t *testing.T) {
+		data := EncodeVector(vec, Float16)
+		assert.Len(t, data, headerSize+2*len(vec))
+
+		got, err := DecodeVector(data)
+		require.NoError(t, err)
+		require.Len(t, got, len(vec))
+		for i := range vec {
+			assert.InDelta(t, vec[i], got[i], 1e-3*max(1, math.Abs(float64(vec[i]))), "component %d", i)
+		}
+	})
+
+	t.Run("half precision edge cases", func(t *testing.T) {
+		assert.Equal(t, uint16(0x3c00), float32ToHalf(1))
+		assert.Equal(t, uint16(0x7bff), float32ToHalf(65504))
+		assert.Equal(t, uint16(0x7c00), float32ToHalf(1e6))
+		assert.Equal(t, uint16(0x0001), float32ToHalf(float32(math.Ldexp(1, -24))))
+		assert.Equal(t, float32(math.Ldexp(1, -24)), halfToFloat32(0x0001))
+		assert.True(t, math.IsNaN(float64(halfToFloat32(float32ToHalf(float32(math.NaN()))))))
+	})
+
+	t.Run("rejects malformed input", func(t *testing.T) {
+		_, err := DecodeVector([]byte(`[0.1,0.2]`))
+		assert.Error(t, err)
+
+		data := EncodeVector(vec, Float32)
+		_, err = DecodeVector(data[:len(data)-1])
+		assert.Error(t, err)
+
+		data[2] = 99
+		_, err = DecodeVector(data)
+		assert.Error(t, err)
+	})
+}
+
+func TestCachedEmbedderKey(t *testing.T) {
+	c := &CachedEmbedder{Model: "gemini-embedding-001", Dimension: 768}
+	assert.Regexp(t, `^embed:v2:gemini-embedding-001:768:default:[0-9a-f]+$`, c.Key("Dune"))
+	assert.Equal(t, c.Key("Dune"), c.Key("  dune "))
+
+	other := &CachedEmbedder{Model: "gemini-embedding-001", Dimension: 1536}
+	assert.NotEqual(t, c.Key("Dune"), other.Key("Dune"))
+	query := &CachedEmbedder{Model: "gemini-embedding-001", Dimension: 768, TaskType: "RETRIEVAL_QUERY"}
+	assert.NotEqual(t, c.Key("Dune"), query.Key("Dune"))
+}
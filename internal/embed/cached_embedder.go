@@ -2,53 +2,101 @@ package embed
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strings"
 	"time"
 
 	"github.com/cespare/xxhash/v2"
-	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+// CacheKeyPrefix starts every embedding cache key.
+const CacheKeyPrefix = "embed:"
+
+// cacheKeyVersion is bumped whenever the key or value format changes, so old
+// entries are ignored rather than misread.
+const cacheKeyVersion = 2
+
+// CachedEmbedder caches embeddings in two tiers: an optional in-process LRU
+// and an optional shared Cache (Redis or Postgres). The shared cache is
+// strictly optional: its errors trip Breaker so that an outage costs one
+// failed round-trip per cooldown rather than one per request.
 type CachedEmbedder struct {
-	Base   Embedder
-	Redis  *redis.Client
-	Logger *slog.Logger
+	Base    Embedder
+	Local   *LRU  // optional
+	Cache   Cache // optional
+	Breaker *Breaker
+	Logger  *slog.Logger
+
+	// Model, Dimension and TaskType describe the vectors Base produces. They
+	// are part of every key, so switching any of them never serves stale
+	// vectors.
+	Model     string
+	Dimension int
+	TaskType  string
+
+	TTL      time.Duration // entry lifetime; zero means 24h
+	Encoding Encoding      // value encoding; the zero value is lossless Float32
 }
 
 func (c *CachedEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
-	normalized := strings.TrimSpace(strings.ToLower(input))
-	hash := xxhash.Sum64String(normalized)
-	cacheKey := fmt.Sprintf("embed:%x", hash)
-
-	cached, err := c.Redis.Get(ctx, cacheKey).Bytes()
-	if err == nil {
-		var vec []float32
-		if err := json.Unmarshal(cached, &vec); err == nil {
-			c.Logger.Debug("Embedding cache hit", "query", input)
+	cacheKey := c.Key(input)
+
+	ctx, span := tracer.Start(ctx, "CachedEmbedder.Embed")
+	defer span.End()
+	span.SetAttributes(attribute.String("cache.key", cacheKey))
+
+	if c.Local != nil {
+		if vec, ok := c.loadLocal(ctx, cacheKey); ok {
+			c.Logger.Debug("Embedding cache hit", "query", input, "tier", "local")
+			span.SetAttributes(attribute.Bool("cache.hit", true), attribute.String("cache.tier", "local"))
 			return vec, nil
 		}
-		c.Logger.Warn("Failed to unmarshal cached embedding", "error", err)
-	} else if err != redis.Nil {
-		// Only log real Redis errors (not cache misses)
-		c.Logger.Warn("Redis GET failed", "key", cacheKey, "error", err)
+	}
+
+	useCache := c.cacheAvailable()
+	span.SetAttributes(attribute.Bool("cache.available", useCache))
+
+	if useCache {
+		cached, err := c.Cache.Get(ctx, cacheKey)
+		switch {
+		case err == nil:
+			c.Breaker.Success()
+			vec, err := c.decode(cached)
+			if err == nil {
+				c.Logger.Debug("Embedding cache hit", "query", input, "tier", "shared")
+				span.SetAttributes(attribute.Bool("cache.hit", true), attribute.String("cache.tier", "shared"))
+				c.storeLocal(ctx, cacheKey, cached)
+				return vec, nil
+			}
+			c.Logger.Warn("Failed to decode cached embedding", "key", cacheKey, "error", err)
+		case errors.Is(err, ErrCacheMiss):
+			c.Breaker.Success()
+		default:
+			c.Logger.Warn("Embedding cache GET failed", "key", cacheKey, "error", err)
+			c.cacheFailed(ctx)
+			useCache = false
+		}
 	}
 
 	// Cache Miss
+	span.SetAttributes(attribute.Bool("cache.hit", false))
 	vec, err := c.Base.Embed(ctx, input)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "embedding failed")
 		return nil, fmt.Errorf("embedding failed: %w", err)
 	}
 
-	data, err := json.Marshal(vec)
-	if err != nil {
-		c.Logger.Warn("Failed to marshal embedding for cache", "error", err)
-	} else {
-		err := c.Redis.Set(ctx, cacheKey, data, 24*time.Hour).Err()
-		if err != nil {
-			c.Logger.Warn("Failed to store embedding in Redis", "key", cacheKey, "error", err)
+	data := EncodeVector(vec, c.Encoding)
+	c.storeLocal(ctx, cacheKey, data)
+
+	if useCache {
+		if err := c.Cache.Set(ctx, cacheKey, data, c.ttl()); err != nil {
+			c.Logger.Warn("Failed to store embedding in cache", "key", cacheKey, "error", err)
+			c.cacheFailed(ctx)
 		} else {
 			c.Logger.Debug("Cached embedding", "query", input)
 		}
@@ -56,3 +104,65 @@ func (c *CachedEmbedder) Embed(ctx context.Context, input string) ([]float32, er
 
 	return vec, nil
 }
+
+// Key returns the cache key for input:
+// "embed:v2:<model>:<dimension>:<task type>:<xxhash of normalised input>".
+func (c *CachedEmbedder) Key(input string) string {
+	normalized := normalizeInput(input)
+	task := c.TaskType
+	if task == "" {
+		task = "default"
+	}
+	return fmt.Sprintf("%sv%d:%s:%d:%s:%x", CacheKeyPrefix, cacheKeyVersion,
+		c.Model, c.Dimension, task, xxhash.Sum64String(normalized))
+}
+
+func (c *CachedEmbedder) decode(data []byte) ([]float32, error) {
+	vec, err := DecodeVector(data)
+	if err != nil {
+		return nil, err
+	}
+	if c.Dimension > 0 && len(vec) != c.Dimension {
+		return nil, fmt.Errorf("cached vector has dimension %d, want %d", len(vec), c.Dimension)
+	}
+	return vec, nil
+}
+
+func (c *CachedEmbedder) loadLocal(ctx context.Context, key string) ([]float32, bool) {
+	data, err := c.Local.Get(ctx, key)
+	if err != nil {
+		return nil, false
+	}
+	vec, err := c.decode(data)
+	return vec, err == nil
+}
+
+func (c *CachedEmbedder) storeLocal(ctx context.Context, key string, data []byte) {
+	if c.Local != nil {
+		_ = c.Local.Set(ctx, key, data, c.ttl())
+	}
+}
+
+func (c *CachedEmbedder) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return 24 * time.Hour
+}
+
+func (c *CachedEmbedder) cacheAvailable() bool {
+	return c.Cache != nil && c.Breaker.Allow()
+}
+
+// cacheFailed records a shared cache failure unless it was caused by the
+// caller giving up, which says nothing about the cache's health.
+func (c *CachedEmbedder) cacheFailed(ctx context.Context) {
+	if ctx.Err() != nil {
+		c.Breaker.Abort()
+		return
+	}
+	c.Breaker.Failure()
+	if c.Breaker.State() == BreakerOpen {
+		c.Logger.Warn("Embedding cache circuit open; serving embeddings without shared cache")
+	}
+}
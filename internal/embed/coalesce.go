@@ -0,0 +1,66 @@
+package embed
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/singleflight"
+)
+
+// CoalescingEmbedder collapses concurrent calls for the same normalised
+// input into one call to Base. Placed in front of CachedEmbedder, a burst of
+// identical queries costs one cache lookup and at most one upstream call.
+//
+// The shared call is detached from the first caller's cancellation and
+// deadline, so one client giving up does not fail the others, and is bounded
+// by Timeout instead; each caller still stops waiting when its own context
+// is done.
+type CoalescingEmbedder struct {
+	Base    Embedder
+	Timeout time.Duration // bound on the shared call; zero means DefaultCoalesceTimeout
+
+	group singleflight.Group
+}
+
+// DefaultCoalesceTimeout bounds a shared call when Timeout is not set. It
+// leaves room for the retries of a RetryEmbedder underneath.
+const DefaultCoalesceTimeout = 30 * time.Second
+
+func (c *CoalescingEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
+	ctx, span := tracer.Start(ctx, "CoalescingEmbedder.Embed")
+	defer span.End()
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCoalesceTimeout
+	}
+	ch := c.group.DoChan(normalizeInput(input), func() (any, error) {
+		shared, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		return c.Base.Embed(shared, input)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		span.SetAttributes(attribute.Bool("embed.coalesced", res.Shared))
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		vec := res.Val.([]float32)
+		if res.Shared {
+			// Callers own their result; don't let them alias one slice.
+			vec = slices.Clone(vec)
+		}
+		return vec, nil
+	}
+}
+
+// normalizeInput maps inputs that should share an embedding to one key.
+func normalizeInput(input string) string {
+	return strings.TrimSpace(strings.ToLower(input))
+}
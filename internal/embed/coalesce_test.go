@@ -0,0 +1,160 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedEmbedder blocks every call until release is closed.
+type gatedEmbedder struct {
+	release chan struct{}
+	calls   atomic.Int32
+	err     error
+}
+
+func (g *gatedEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
+	g.calls.Add(1)
+	select {
+	case <-g.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+	return []float32{float32(len(input)), 1}, nil
+}
+
+func TestCoalescingEmbedder(t *testing.T) {
+	t.Run("concurrent identical inputs share one call", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			base := &gatedEmbedder{release: make(chan struct{})}
+			c := &CoalescingEmbedder{Base: base}
+
+			const n = 50
+			results := make([][]float32, n)
+			var wg sync.WaitGroup
+			for i := range n {
+				wg.Go(func() {
+					vec, err := c.Embed(context.Background(), []string{"Dune", " dune", "DUNE "}[i%3])
+					assert.NoError(t, err)
+					results[i] = vec
+				})
+			}
+
+			synctest.Wait() // every caller is now waiting on the shared call
+			assert.Equal(t, int32(1), base.calls.Load())
+
+			close(base.release)
+			wg.Wait()
+
+			for _, vec := range results {
+				require.Len(t, vec, 2)
+			}
+			// Results must not alias each other.
+			results[0][1] = 42
+			assert.Equal(t, float32(1), results[1][1])
+		})
+	})
+
+	t.Run("distinct inputs are not coalesced", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			base := &gatedEmbedder{release: make(chan struct{})}
+			c := &CoalescingEmbedder{Base: base}
+
+			var wg sync.WaitGroup
+			for _, q := range []string{"dune", "emma", "ulysses"} {
+				wg.Go(func() {
+					_, err := c.Embed(context.Background(), q)
+					assert.NoError(t, err)
+				})
+			}
+
+			synctest.Wait()
+			assert.Equal(t, int32(3), base.calls.Load())
+			close(base.release)
+			wg.Wait()
+		})
+	})
+
+	t.Run("errors are shared", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			upstream := errors.New("upstream down")
+			base := &gatedEmbedder{release: make(chan struct{}), err: upstream}
+			c := &CoalescingEmbedder{Base: base}
+
+			var wg sync.WaitGroup
+			for range 5 {
+				wg.Go(func() {
+					_, err := c.Embed(context.Background(), "dune")
+					assert.ErrorIs(t, err, upstream)
+				})
+			}
+
+			synctest.Wait()
+			close(base.release)
+			wg.Wait()
+			assert.Equal(t, int32(1), base.calls.Load())
+		})
+	})
+
+	t.Run("a cancelled caller does not fail the others", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			base := &gatedEmbedder{release: make(chan struct{})}
+			c := &CoalescingEmbedder{Base: base}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			var first error
+			var wg sync.WaitGroup
+			wg.Go(func() {
+				_, first = c.Embed(ctx, "dune")
+			})
+			synctest.Wait()
+
+			var second []float32
+			wg.Go(func() {
+				var err error
+				second, err = c.Embed(context.Background(), "dune")
+				assert.NoError(t, err)
+			})
+			synctest.Wait()
+
+			cancel()
+			synctest.Wait()
+			close(base.release)
+			wg.Wait()
+
+			assert.ErrorIs(t, first, context.Canceled)
+			assert.Len(t, second, 2)
+			assert.Equal(t, int32(1), base.calls.Load())
+		})
+	})
+	t.Run("the shared call is bounded by Timeout", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			base := &gatedEmbedder{release: make(chan struct{})}
+			c := &CoalescingEmbedder{Base: base, Timeout: 5 * time.Second}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			waiter, other := make(chan error, 1), make(chan error, 1)
+			go func() { _, err := c.Embed(ctx, "dune"); waiter <- err }()
+			go func() { _, err := c.Embed(context.Background(), "dune"); other <- err }()
+
+			time.Sleep(2 * time.Second)
+			synctest.Wait()
+			assert.ErrorIs(t, <-waiter, context.DeadlineExceeded, "a waiter returns at its own deadline")
+			assert.Empty(t, other, "the others keep waiting")
+
+			assert.ErrorIs(t, <-other, context.DeadlineExceeded)
+			assert.Equal(t, int32(1), base.calls.Load())
+		})
+	})
+}
@@ -0,0 +1,165 @@
+package embed
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Encoding selects how CachedEmbedder stores vector components.
+type Encoding byte
+
+const (
+	// Float32 stores components losslessly as little-endian IEEE 754 floats.
+	Float32 Encoding = iota
+	// Float16 halves the size again at about three significant digits, which
+	// changes cosine similarity by well under 1e-3 for normalised vectors.
+	Float16
+)
+
+// ParseEncoding parses "float32" or "float16".
+func ParseEncoding(s string) (Encoding, error) {
+	switch s {
+	case "float32", "":
+		return Float32, nil
+	case "float16":
+		return Float16, nil
+	}
+	return 0, fmt.Errorf("unknown vector encoding %q", s)
+}
+
+func (e Encoding) String() string {
+	if e == Float16 {
+		return "float16"
+	}
+	return "float32"
+}
+
+// Encoded vectors start with an 8-byte header: the magic "EV", a format
+// version, the Encoding, and the dimension as a little-endian uint32.
+const (
+	codecMagic0  = 'E'
+	codecMagic1  = 'V'
+	codecVersion = 1
+	headerSize   = 8
+)
+
+var errBadVector = errors.New("malformed encoded vector")
+
+// EncodeVector serialises vec in the versioned binary cache format.
+func EncodeVector(vec []float32, enc Encoding) []byte {
+	width := 4
+	if enc == Float16 {
+		width = 2
+	}
+
+	buf := make([]byte, headerSize+width*len(vec))
+	buf[0], buf[1], buf[2], buf[3] = codecMagic0, codecMagic1, codecVersion, byte(enc)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(vec)))
+
+	data := buf[headerSize:]
+	for i, v := range vec {
+		if enc == Float16 {
+			binary.LittleEndian.PutUint16(data[2*i:], float32ToHalf(v))
+		} else {
+			binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+		}
+	}
+	return buf
+}
+
+// DecodeVector parses a vector produced by EncodeVector.
+func DecodeVector(buf []byte) ([]float32, error) {
+	if len(buf) < headerSize || buf[0] != codecMagic0 || buf[1] != codecMagic1 {
+		return nil, errBadVector
+	}
+	if buf[2] != codecVersion {
+		return nil, fmt.Errorf("unsupported vector format version %d", buf[2])
+	}
+
+	enc := Encoding(buf[3])
+	width := 4
+	switch enc {
+	case Float32:
+	case Float16:
+		width = 2
+	default:
+		return nil, fmt.Errorf("unsupported vector encoding %d", buf[3])
+	}
+
+	dim := int(binary.LittleEndian.Uint32(buf[4:]))
+	data := buf[headerSize:]
+	if len(data) != dim*width {
+		return nil, errBadVector
+	}
+
+	vec := make([]float32, dim)
+	for i := range vec {
+		if enc == Float16 {
+			vec[i] = halfToFloat32(binary.LittleEndian.Uint16(data[2*i:]))
+		} else {
+			vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+		}
+	}
+	return vec, nil
+}
+
+// float32ToHalf converts to IEEE 754 binary16, rounding to nearest even.
+func float32ToHalf(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	rawExp := (bits >> 23) & 0xff
+	mant := bits & 0x7fffff
+
+	if rawExp == 0xff { // Inf or NaN
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	}
+
+	exp := int(rawExp) - 127 + 15
+	switch {
+	case exp >= 0x1f: // too large: infinity
+		return sign | 0x7c00
+	case exp <= 0: // subnormal or zero
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint32(14 - exp)
+		half := uint16(mant >> shift)
+		rem, mid := mant&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > mid || (rem == mid && half&1 == 1) {
+			half++
+		}
+		return sign | half
+	}
+
+	half := sign | uint16(exp)<<10 | uint16(mant>>13)
+	// A carry out of the mantissa correctly bumps the exponent.
+	if rem := mant & 0x1fff; rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return half
+}
+
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0:
+		// Subnormal: mant × 2^-24.
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
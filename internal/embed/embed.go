@@ -5,12 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"time"
 
 	_ "github.com/joho/godotenv/autoload"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"golang.org/x/time/rate"
 	"google.golang.org/genai"
 )
 
+var tracer = otel.Tracer("github.com/nmdra/Semantic-Search/internal/embed")
+
+// Model and output dimension requested from Gemini.
+const (
+	GeminiModel     = "gemini-embedding-001"
+	GeminiDimension = 768
+)
+
 type Embedder interface {
 	Embed(ctx context.Context, input string) ([]float32, error)
 }
@@ -28,8 +41,9 @@ func NewGeminiEmbedder(ctx context.Context, logger *slog.Logger, apiKey string)
 	}
 
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
+		APIKey:     apiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: geminiHTTPClient(),
 	})
 
 	if err != nil {
@@ -44,10 +58,19 @@ func NewGeminiEmbedder(ctx context.Context, logger *slog.Logger, apiKey string)
 }
 
 func (g *GeminiEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
-	dim := int32(768)
+	dim := int32(GeminiDimension)
+
+	ctx, span := tracer.Start(ctx, "GeminiEmbedder.Embed")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("embed.model", GeminiModel),
+		attribute.Int("embed.dimension", int(dim)),
+		attribute.Int("embed.input_length", len(input)),
+	)
 
 	if err := g.limiter.Wait(ctx); err != nil {
 		g.logger.Warn("rate limiter blocked request", "error", err)
+		span.SetStatus(codes.Error, "rate limited")
 		return nil, fmt.Errorf("rate limit exceeded: %w", err)
 	}
 
@@ -55,15 +78,18 @@ func (g *GeminiEmbedder) Embed(ctx context.Context, input string) ([]float32, er
 		genai.NewContentFromText(input, genai.RoleUser),
 	}
 
+	var retryAfter time.Duration
 	resp, err := g.client.Models.EmbedContent(
-		ctx,
-		"gemini-embedding-001",
+		context.WithValue(ctx, retryAfterKey{}, &retryAfter),
+		GeminiModel,
 		contents,
 		&genai.EmbedContentConfig{OutputDimensionality: &dim},
 	)
 	if err != nil {
 		g.logger.Error("embedding failed", "error", err)
-		return nil, err
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "embed request failed")
+		return nil, geminiError(err, retryAfter)
 	}
 
 	if len(resp.Embeddings) == 0 {
@@ -74,3 +100,43 @@ func (g *GeminiEmbedder) Embed(ctx context.Context, input string) ([]float32, er
 	g.logger.Debug("Embedding success", "length", len(embedding))
 	return embedding, nil
 }
+
+// geminiError wraps a Gemini API error in a StatusError with its HTTP status
+// and the delay the server asked for: the Retry-After header if it sent one,
+// else the RetryInfo detail. Other errors are returned as they are.
+func geminiError(err error, retryAfter time.Duration) error {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	if retryAfter <= 0 {
+		retryAfter = geminiRetryDelay(apiErr)
+	}
+	return &StatusError{StatusCode: apiErr.Code, RetryAfter: retryAfter, Err: err}
+}
+
+// retryAfterKey carries a *time.Duration through the request context to
+// retryAfterTransport. The genai client drops the headers of failed
+// responses, so this is the only way to see Retry-After.
+type retryAfterKey struct{}
+
+// retryAfterTransport stores the Retry-After header of each response in the
+// *time.Duration its request context carries, if any.
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if dst, ok := req.Context().Value(retryAfterKey{}).(*time.Duration); ok {
+		*dst = ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return resp, nil
+}
+
+func geminiHTTPClient() *http.Client {
+	return &http.Client{Transport: retryAfterTransport{base: http.DefaultTransport}}
+}
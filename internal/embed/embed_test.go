@@ -3,9 +3,17 @@ package embed
 import (
 	"context"
 	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/genai"
 )
 
 var _ Embedder = (*MockEmbedder)(nil)
@@ -73,3 +81,63 @@ func BenchmarkMockEmbedder(b *testing.B) {
 		_, _ = mock.Embed(ctx, "benchmark input")
 	}
 }
+
+func TestGeminiEmbedderErrors(t *testing.T) {
+	newEmbedder := func(t *testing.T, handler http.HandlerFunc) *GeminiEmbedder {
+		srv := httptest.NewServer(handler)
+		t.Cleanup(srv.Close)
+		client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+			APIKey:      "test",
+			Backend:     genai.BackendGeminiAPI,
+			HTTPClient:  geminiHTTPClient(),
+			HTTPOptions: genai.HTTPOptions{BaseURL: srv.URL},
+		})
+		require.NoError(t, err)
+		return &GeminiEmbedder{
+			client:  client,
+			logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+			limiter: rate.NewLimiter(rate.Inf, 1),
+		}
+	}
+
+	t.Run("Wraps API errors with the Retry-After header", func(t *testing.T) {
+		g := newEmbedder(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error": {"code": 429, "message": "quota exceeded", "status": "RESOURCE_EXHAUSTED"}}`))
+		})
+
+		_, err := g.Embed(context.Background(), "query")
+
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+		assert.Equal(t, 7*time.Second, statusErr.RetryAfter)
+		assert.True(t, IsRateLimited(err))
+		var apiErr genai.APIError
+		assert.ErrorAs(t, err, &apiErr, "the API error stays reachable")
+	})
+
+	t.Run("Falls back to RetryInfo", func(t *testing.T) {
+		g := newEmbedder(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error": {"code": 503, "message": "overloaded", "details": [
+				{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "2s"}]}}`))
+		})
+
+		_, err := g.Embed(context.Background(), "query")
+
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+		assert.Equal(t, 2*time.Second, statusErr.RetryAfter)
+		retryable, delay := classify(err)
+		assert.True(t, retryable)
+		assert.Equal(t, 2*time.Second, delay)
+	})
+
+	t.Run("Leaves other errors alone", func(t *testing.T) {
+		err := errors.New("connection refused")
+		assert.Same(t, err, geminiError(err, time.Second))
+	})
+}
@@ -0,0 +1,88 @@
+package embed
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in-process Cache holding at most Size entries. CachedEmbedder
+// consults it before the shared backend, so hot queries never leave the
+// process. The zero value is not usable; create one with NewLRU.
+type LRU struct {
+	size int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:  max(size, 1),
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+func (l *LRU) Get(_ context.Context, key string) ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	entry := el.Value.(*lruEntry)
+	if !entry.expires.IsZero() && !l.now().Before(entry.expires) {
+		l.remove(el)
+		return nil, ErrCacheMiss
+	}
+	l.ll.MoveToFront(el)
+	return entry.value, nil
+}
+
+// Set stores value under key; a non-positive ttl never expires.
+func (l *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = l.now().Add(ttl)
+	}
+
+	if el, ok := l.items[key]; ok {
+		entry := el.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		l.ll.MoveToFront(el)
+		return nil
+	}
+
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for l.ll.Len() > l.size {
+		l.remove(l.ll.Back())
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet evicted.
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
+
+func (l *LRU) remove(el *list.Element) {
+	l.ll.Remove(el)
+	delete(l.items, el.Value.(*lruEntry).key)
+}
@@ -0,0 +1,215 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// StatusError is an upstream HTTP failure. GeminiEmbedder wraps API errors
+// in it, and embedders that talk to other providers can return it to take
+// part in retry classification.
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the server-requested delay, typically parsed from the
+	// Retry-After header with ParseRetryAfter.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("upstream returned %d: %v", e.StatusCode, e.Err)
+}
+
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// ParseRetryAfter parses a Retry-After header value, given either as
+// delay-seconds or as an HTTP-date. It returns zero if the value is invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// RetryEmbedder decorates an Embedder with bounded retries using exponential
+// backoff with jitter, honouring server-requested retry delays, and guards the
+// upstream with a circuit breaker. Only transient failures (429, 5xx and
+// per-attempt timeouts) are retried.
+type RetryEmbedder struct {
+	Base Embedder
+
+	MaxAttempts    int           // total attempts including the first; zero means 3
+	BaseDelay      time.Duration // first backoff step; zero means 200ms
+	MaxDelay       time.Duration // cap for backoff and Retry-After; zero means 5s
+	AttemptTimeout time.Duration // deadline for each upstream call; zero means none
+
+	Breaker *Breaker // optional
+	Logger  *slog.Logger
+
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func (r *RetryEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
+	if !r.Breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	maxAttempts := r.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+
+	for attempt := 1; ; attempt++ {
+		vec, err := r.attempt(ctx, input)
+		if err == nil {
+			r.Breaker.Success()
+			return vec, nil
+		}
+
+		if ctx.Err() != nil {
+			r.Breaker.Abort()
+			return nil, ctx.Err()
+		}
+
+		retryable, retryAfter := classify(err)
+		if !retryable {
+			// The upstream answered; a bad request says nothing about its health.
+			r.Breaker.Success()
+			return nil, err
+		}
+
+		if attempt >= maxAttempts {
+			r.Breaker.Failure()
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		delay := max(r.backoff(attempt), min(retryAfter, r.maxDelay()))
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			r.Breaker.Failure()
+			return nil, fmt.Errorf("no time left to retry: %w", err)
+		}
+
+		r.Logger.Warn("Retrying embedding", "attempt", attempt, "delay", delay, "error", err)
+		if err := r.wait(ctx, delay); err != nil {
+			r.Breaker.Abort()
+			return nil, err
+		}
+	}
+}
+
+func (r *RetryEmbedder) attempt(ctx context.Context, input string) ([]float32, error) {
+	if r.AttemptTimeout <= 0 {
+		return r.Base.Embed(ctx, input)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.AttemptTimeout)
+	defer cancel()
+	return r.Base.Embed(ctx, input)
+}
+
+// backoff returns the delay before the given retry using "equal jitter": half
+// of the exponential step is fixed and the other half random.
+func (r *RetryEmbedder) backoff(attempt int) time.Duration {
+	base := r.BaseDelay
+	if base <= 0 {
+		base = 200 * time.Millisecond
+	}
+
+	step := min(base<<(attempt-1), r.maxDelay())
+	if step <= 0 { // shift overflow
+		step = r.maxDelay()
+	}
+	half := step / 2
+	return half + rand.N(half+1)
+}
+
+func (r *RetryEmbedder) maxDelay() time.Duration {
+	if r.MaxDelay > 0 {
+		return r.MaxDelay
+	}
+	return 5 * time.Second
+}
+
+func (r *RetryEmbedder) wait(ctx context.Context, d time.Duration) error {
+	if r.sleep != nil {
+		return r.sleep(ctx, d)
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// classify reports whether err is worth retrying and any delay the server
+// asked for. It is only called when the caller's own context is still live,
+// so a deadline error here means the per-attempt timeout fired.
+func classify(err error) (retryable bool, retryAfter time.Duration) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true, 0
+	}
+	if errors.Is(err, context.Canceled) {
+		return false, 0
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return retryableStatus(statusErr.StatusCode), statusErr.RetryAfter
+	}
+
+	return false, 0
+}
+
+// IsRateLimited reports whether err, possibly after retries, was caused by the
+// upstream rejecting the call with 429 Too Many Requests.
+func IsRateLimited(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// geminiRetryDelay extracts the google.rpc.RetryInfo delay that the Gemini API
+// attaches to 429 responses in place of a Retry-After header.
+func geminiRetryDelay(apiErr genai.APIError) time.Duration {
+	for _, detail := range apiErr.Details {
+		if detail["@type"] != "type.googleapis.com/google.rpc.RetryInfo" {
+			continue
+		}
+		if s, ok := detail["retryDelay"].(string); ok {
+			if d, err := time.ParseDuration(s); err == nil {
+				return d
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,222 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// faultyEmbedder returns the queued errors in order, then succeeds.
+type faultyEmbedder struct {
+	mu     sync.Mutex
+	faults []error
+	calls  int
+	delay  time.Duration
+}
+
+func (f *faultyEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
+	f.mu.Lock()
+	f.calls++
+	var err error
+	if len(f.faults) > 0 {
+		err, f.faults = f.faults[0], f.faults[1:]
+	}
+	f.mu.Unlock()
+
+	if f.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(f.delay):
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return make([]float32, 768), nil
+}
+
+func (f *faultyEmbedder) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func newTestRetryEmbedder(base Embedder) (*RetryEmbedder, *[]time.Duration) {
+	var sleeps []time.Duration
+	r := &RetryEmbedder{
+		Base:   base,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		sleep: func(ctx context.Context, d time.Duration) error {
+			sleeps = append(sleeps, d)
+			return ctx.Err()
+		},
+	}
+	return r, &sleeps
+}
+
+func TestRetryEmbedder(t *testing.T) {
+	unavailable := &StatusError{StatusCode: http.StatusServiceUnavailable, Err: errors.New("overloaded")}
+
+	t.Run("Retries transient errors", func(t *testing.T) {
+		base := &faultyEmbedder{faults: []error{unavailable, unavailable}}
+		r, sleeps := newTestRetryEmbedder(base)
+
+		vec, err := r.Embed(context.Background(), "query")
+
+		require.NoError(t, err)
+		assert.Len(t, vec, 768)
+		assert.Equal(t, 3, base.Calls())
+		assert.Len(t, *sleeps, 2)
+	})
+
+	t.Run("Gives up after max attempts", func(t *testing.T) {
+		base := &faultyEmbedder{faults: []error{unavailable, unavailable, unavailable, unavailable}}
+		r, _ := newTestRetryEmbedder(base)
+		r.MaxAttempts = 2
+
+		_, err := r.Embed(context.Background(), "query")
+
+		assert.ErrorIs(t, err, unavailable)
+		assert.Equal(t, 2, base.Calls())
+	})
+
+	t.Run("Does not retry client errors", func(t *testing.T) {
+		badRequest := geminiError(genai.APIError{Code: http.StatusBadRequest, Message: "invalid argument"}, 0)
+		base := &faultyEmbedder{faults: []error{badRequest}}
+		r, _ := newTestRetryEmbedder(base)
+
+		_, err := r.Embed(context.Background(), "query")
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, base.Calls())
+	})
+
+	t.Run("Honours Retry-After", func(t *testing.T) {
+		limited := &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 3 * time.Second}
+		base := &faultyEmbedder{faults: []error{limited}}
+		r, sleeps := newTestRetryEmbedder(base)
+
+		_, err := r.Embed(context.Background(), "query")
+
+		require.NoError(t, err)
+		assert.Equal(t, []time.Duration{3 * time.Second}, *sleeps)
+	})
+
+	t.Run("Honours Gemini RetryInfo", func(t *testing.T) {
+		limited := geminiError(genai.APIError{
+			Code: http.StatusTooManyRequests,
+			Details: []map[string]any{
+				{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "2s"},
+			},
+		}, 0)
+		base := &faultyEmbedder{faults: []error{limited}}
+		r, sleeps := newTestRetryEmbedder(base)
+
+		_, err := r.Embed(context.Background(), "query")
+
+		require.NoError(t, err)
+		assert.Equal(t, []time.Duration{2 * time.Second}, *sleeps)
+	})
+
+	t.Run("Backoff stays within bounds", func(t *testing.T) {
+		r := &RetryEmbedder{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+		for attempt := 1; attempt <= 10; attempt++ {
+			step := min(100*time.Millisecond<<(attempt-1), time.Second)
+			d := r.backoff(attempt)
+			assert.GreaterOrEqual(t, d, step/2)
+			assert.LessOrEqual(t, d, step)
+		}
+	})
+
+	t.Run("Per-attempt timeout is retried", func(t *testing.T) {
+		base := &faultyEmbedder{delay: 50 * time.Millisecond}
+		r, _ := newTestRetryEmbedder(base)
+		r.AttemptTimeout = 5 * time.Millisecond
+		r.MaxAttempts = 2
+
+		_, err := r.Embed(context.Background(), "query")
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 2, base.Calls())
+	})
+
+	t.Run("Caller cancellation stops retries", func(t *testing.T) {
+		base := &faultyEmbedder{faults: []error{unavailable, unavailable}}
+		r, _ := newTestRetryEmbedder(base)
+		ctx, cancel := context.WithCancel(context.Background())
+		r.sleep = func(context.Context, time.Duration) error {
+			cancel()
+			return context.Canceled
+		}
+
+		_, err := r.Embed(ctx, "query")
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, base.Calls())
+	})
+
+	t.Run("Breaker opens and rejects calls", func(t *testing.T) {
+		base := &faultyEmbedder{faults: []error{unavailable, unavailable}}
+		r, _ := newTestRetryEmbedder(base)
+		r.MaxAttempts = 1
+		r.Breaker = &Breaker{Threshold: 2, Cooldown: time.Hour}
+
+		_, _ = r.Embed(context.Background(), "query")
+		_, _ = r.Embed(context.Background(), "query")
+		_, err := r.Embed(context.Background(), "query")
+
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.Equal(t, 2, base.Calls(), "open breaker must not reach the upstream")
+	})
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := &Breaker{Threshold: 2, Cooldown: time.Second, MaxCooldown: 4 * time.Second}
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	assert.Equal(t, BreakerClosed, b.State())
+	b.Failure()
+	assert.Equal(t, BreakerOpen, b.State())
+	assert.False(t, b.Allow())
+
+	// After the cooldown a single probe is admitted.
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow(), "only one probe while half-open")
+
+	// A failed probe reopens with a doubled cooldown.
+	b.Failure()
+	now = now.Add(time.Second)
+	assert.False(t, b.Allow())
+	now = now.Add(time.Second)
+	assert.True(t, b.Allow())
+
+	// An aborted probe frees the slot for the next caller.
+	b.Abort()
+	assert.True(t, b.Allow())
+
+	b.Success()
+	assert.Equal(t, BreakerClosed, b.State())
+	assert.True(t, b.Allow())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 120*time.Second, ParseRetryAfter("120", now))
+	assert.Equal(t, 30*time.Second, ParseRetryAfter("Wed, 01 Jan 2025 00:00:30 GMT", now))
+	assert.Zero(t, ParseRetryAfter("", now))
+	assert.Zero(t, ParseRetryAfter("soon", now))
+}
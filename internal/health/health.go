@@ -0,0 +1,151 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check reports a component as healthy by returning nil.
+type Check func(ctx context.Context) error
+
+type ComponentReport struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	// Optional components are reported but do not affect overall readiness.
+	Optional bool `json:"optional,omitempty"`
+}
+
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentReport `json:"components"`
+}
+
+// Healthy reports whether every required component is up.
+func (r Report) Healthy() bool {
+	return r.Status == StatusUp
+}
+
+// Checker runs a set of named readiness checks concurrently.
+type Checker struct {
+	Timeout time.Duration // per-check deadline; zero means 2s
+
+	mu     sync.RWMutex
+	checks map[string]entry
+}
+
+type entry struct {
+	check    Check
+	optional bool
+}
+
+// Register adds a check under name, replacing any check with the same name.
+// A failing check marks the whole report down.
+func (c *Checker) Register(name string, check Check) {
+	c.register(name, entry{check: check})
+}
+
+// RegisterOptional adds a check for a component the service can run
+// without; its failure is reported but the service stays ready.
+func (c *Checker) RegisterOptional(name string, check Check) {
+	c.register(name, entry{check: check, optional: true})
+}
+
+func (c *Checker) register(name string, e entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.checks == nil {
+		c.checks = make(map[string]entry)
+	}
+	c.checks[name] = e
+}
+
+// Run executes all registered checks and aggregates their results.
+func (c *Checker) Run(ctx context.Context) Report {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	c.mu.RLock()
+	checks := make(map[string]entry, len(c.checks))
+	for name, e := range c.checks {
+		checks[name] = e
+	}
+	c.mu.RUnlock()
+
+	report := Report{
+		Status:     StatusUp,
+		Components: make(map[string]ComponentReport, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, e := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := e.check(checkCtx)
+			component := ComponentReport{
+				Status:    StatusUp,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+				Optional:  e.optional,
+			}
+			if err != nil {
+				component.Status = StatusDown
+				component.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[name] = component
+			if err != nil && !e.optional {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Cached wraps check so that a success is reused for ttl and a failure for
+// failureTTL. It is meant for expensive probes, such as a real embedding
+// call, that should not run on every readiness poll; a short failureTTL
+// lets a component come back as soon as it recovers.
+func Cached(check Check, ttl, failureTTL time.Duration) Check {
+	var (
+		mu      sync.Mutex
+		lastErr error
+		expires time.Time
+	)
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if time.Now().Before(expires) {
+			return lastErr
+		}
+
+		lastErr = check(ctx)
+		if lastErr != nil {
+			expires = time.Now().Add(failureTTL)
+		} else {
+			expires = time.Now().Add(ttl)
+		}
+		return lastErr
+	}
+}
@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	t.Run("All up", func(t *testing.T) {
+		c := &Checker{}
+		c.Register("postgres", func(ctx context.Context) error { return nil })
+		c.Register("redis", func(ctx context.Context) error { return nil })
+
+		report := c.Run(context.Background())
+
+		assert.True(t, report.Healthy())
+		assert.Len(t, report.Components, 2)
+		assert.Equal(t, StatusUp, report.Components["redis"].Status)
+	})
+
+	t.Run("One down", func(t *testing.T) {
+		c := &Checker{}
+		c.Register("postgres", func(ctx context.Context) error { return nil })
+		c.Register("redis", func(ctx context.Context) error { return errors.New("connection refused") })
+
+		report := c.Run(context.Background())
+
+		assert.False(t, report.Healthy())
+		assert.Equal(t, StatusUp, report.Components["postgres"].Status)
+		assert.Equal(t, StatusDown, report.Components["redis"].Status)
+		assert.Equal(t, "connection refused", report.Components["redis"].Error)
+	})
+
+	t.Run("Optional down", func(t *testing.T) {
+		c := &Checker{}
+		c.Register("postgres", func(ctx context.Context) error { return nil })
+		c.RegisterOptional("redis", func(ctx context.Context) error { return errors.New("connection refused") })
+
+		report := c.Run(context.Background())
+
+		assert.True(t, report.Healthy(), "optional components must not affect readiness")
+		assert.Equal(t, StatusDown, report.Components["redis"].Status)
+		assert.True(t, report.Components["redis"].Optional)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		c := &Checker{Timeout: 10 * time.Millisecond}
+		c.Register("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		report := c.Run(context.Background())
+
+		assert.False(t, report.Healthy())
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Components["slow"].Error)
+	})
+}
+
+func TestCached(t *testing.T) {
+	t.Run("Reuses a success for the TTL", func(t *testing.T) {
+		calls := 0
+		check := Cached(func(ctx context.Context) error {
+			calls++
+			return nil
+		}, time.Minute, 0)
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, check(context.Background()))
+		}
+		assert.Equal(t, 1, calls, "expected the probe to run once within the TTL")
+	})
+
+	t.Run("Retries a failure after the failure TTL", func(t *testing.T) {
+		calls := 0
+		check := Cached(func(ctx context.Context) error {
+			calls++
+			if calls == 1 {
+				return errors.New("unavailable")
+			}
+			return nil
+		}, time.Minute, 0)
+
+		assert.Error(t, check(context.Background()))
+		assert.NoError(t, check(context.Background()), "expected a failure not to be reused")
+		assert.NoError(t, check(context.Background()))
+		assert.Equal(t, 2, calls)
+	})
+}
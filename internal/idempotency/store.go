@@ -0,0 +1,124 @@
+// Package idempotency stores responses keyed by a client-supplied
+// Idempotency-Key so that retried requests replay the original result.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrInFlight means another request with the same key has not finished yet.
+	ErrInFlight = errors.New("a request with this idempotency key is still in progress")
+	// ErrMismatch means the key was already used for a different request.
+	ErrMismatch = errors.New("idempotency key was used with a different request")
+)
+
+// Response is a stored response to replay.
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type Store struct {
+	Queries *repository.Queries
+	TTL     time.Duration // how long keys are remembered; zero means 24h
+	// Lease is how long a claimed key blocks retries of its request before
+	// one may take it over, in case the claimant died; zero means a minute.
+	// It must outlast the slowest request.
+	Lease time.Duration
+}
+
+// Begin claims key for a request identified by requestHash. It returns
+// (nil, nil) when the caller now owns the key and must call Complete or
+// Release, or the stored response when the request was already served.
+// A key claimed for the same request but left pending past its lease is
+// taken over.
+func (s *Store) Begin(ctx context.Context, key, requestHash string) (*Response, error) {
+	lease := s.Lease
+	if lease <= 0 {
+		lease = time.Minute
+	}
+	_, err := s.Queries.ClaimIdempotencyKey(ctx, repository.ClaimIdempotencyKeyParams{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   s.cutoff(),
+		LockedUntil: pgtype.Timestamptz{Time: time.Now().Add(lease), Valid: true},
+	})
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("claim idempotency key: %w", err)
+	}
+
+	record, err := s.Queries.GetIdempotencyKey(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Purged between the two statements; let the caller retry.
+		return nil, ErrInFlight
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get idempotency key: %w", err)
+	}
+
+	if record.RequestHash != requestHash {
+		return nil, ErrMismatch
+	}
+	if !record.StatusCode.Valid {
+		return nil, ErrInFlight
+	}
+
+	return &Response{
+		StatusCode:  int(record.StatusCode.Int32),
+		ContentType: record.ContentType.String,
+		Body:        record.ResponseBody,
+	}, nil
+}
+
+// Complete stores the response for a key claimed with Begin.
+func (s *Store) Complete(ctx context.Context, key string, resp Response) error {
+	err := s.Queries.CompleteIdempotencyKey(ctx, repository.CompleteIdempotencyKeyParams{
+		Key:          key,
+		StatusCode:   pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
+		ContentType:  pgtype.Text{String: resp.ContentType, Valid: resp.ContentType != ""},
+		ResponseBody: resp.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release forgets a claimed key so the request can be retried, e.g. after a
+// transient server error.
+func (s *Store) Release(ctx context.Context, key string) error {
+	if err := s.Queries.DeleteIdempotencyKey(ctx, key); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Purge deletes expired keys and returns how many were removed.
+func (s *Store) Purge(ctx context.Context) (int64, error) {
+	n, err := s.Queries.DeleteExpiredIdempotencyKeys(ctx, s.cutoff())
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	return n, nil
+}
+
+func (s *Store) cutoff() pgtype.Timestamptz {
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return pgtype.Timestamptz{Time: time.Now().Add(-ttl), Valid: true}
+}
@@ -0,0 +1,85 @@
+// Package isbn validates and normalises ISBN-10 and ISBN-13 identifiers.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrLength   = errors.New("isbn must have 10 or 13 digits")
+	ErrChar     = errors.New("isbn contains invalid characters")
+	ErrChecksum = errors.New("isbn check digit is wrong")
+	ErrPrefix   = errors.New("isbn-13 must start with 978 or 979")
+)
+
+// Normalize validates s and returns it as a bare ISBN-13. Hyphens and spaces
+// are ignored and ISBN-10s are converted, so "0-14-143951-3",
+// "978-0-14-143951-8" and "9780141439518" all normalise to the same value.
+func Normalize(s string) (string, error) {
+	compact := strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, s))
+
+	switch len(compact) {
+	case 10:
+		return fromISBN10(compact)
+	case 13:
+		return validateISBN13(compact)
+	default:
+		return "", ErrLength
+	}
+}
+
+func fromISBN10(s string) (string, error) {
+	sum := 0
+	for i, r := range s {
+		var v int
+		switch {
+		case r >= '0' && r <= '9':
+			v = int(r - '0')
+		case r == 'X' && i == 9:
+			v = 10
+		default:
+			return "", ErrChar
+		}
+		sum += v * (10 - i)
+	}
+	if sum%11 != 0 {
+		return "", ErrChecksum
+	}
+
+	body := "978" + s[:9]
+	return body + string(rune('0'+checkDigit13(body))), nil
+}
+
+func validateISBN13(s string) (string, error) {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return "", ErrChar
+		}
+	}
+	if !strings.HasPrefix(s, "978") && !strings.HasPrefix(s, "979") {
+		return "", ErrPrefix
+	}
+	if int(s[12]-'0') != checkDigit13(s[:12]) {
+		return "", ErrChecksum
+	}
+	return s, nil
+}
+
+// checkDigit13 computes the ISBN-13 check digit for the first 12 digits.
+func checkDigit13(body string) int {
+	sum := 0
+	for i, r := range body {
+		w := 1
+		if i%2 == 1 {
+			w = 3
+		}
+		sum += int(r-'0') * w
+	}
+	return (10 - sum%10) % 10
+}
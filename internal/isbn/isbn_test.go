@@ -0,0 +1,41 @@
+package isbn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr error
+	}{
+		{in: "9780141439518", want: "9780141439518"},
+		{in: "978-0-14-143951-8", want: "9780141439518"},
+		{in: "978 0 14 143951 8", want: "9780141439518"},
+		{in: "0-14-143951-3", want: "9780141439518"},
+		{in: "080442957X", want: "9780804429573"},
+		{in: "080442957x", want: "9780804429573"},
+		{in: "9780141439519", wantErr: ErrChecksum},
+		{in: "0141439514", wantErr: ErrChecksum},
+		{in: "1234567890123", wantErr: ErrPrefix},
+		{in: "97801414395X8", wantErr: ErrChar},
+		{in: "X141439513", wantErr: ErrChar},
+		{in: "12345", wantErr: ErrLength},
+		{in: "", wantErr: ErrLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Normalize(tt.in)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Package lang maps languages to Postgres text search configurations and
+// guesses the language of a text.
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Default is the text search configuration used when a book's language is
+// neither given nor detected.
+const Default = "english"
+
+// configs maps ISO 639-1 codes to the built-in Postgres text search
+// configurations. "simple" does no stemming and suits any language.
+var configs = map[string]string{
+	"da": "danish",
+	"de": "german",
+	"en": "english",
+	"es": "spanish",
+	"fi": "finnish",
+	"fr": "french",
+	"hu": "hungarian",
+	"it": "italian",
+	"nl": "dutch",
+	"no": "norwegian",
+	"pt": "portuguese",
+	"ro": "romanian",
+	"ru": "russian",
+	"sv": "swedish",
+	"tr": "turkish",
+}
+
+// Config returns the text search configuration for a language given as an
+// ISO 639-1 code ("es") or a configuration name ("spanish"), ignoring case.
+func Config(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if config, ok := configs[s]; ok {
+		return config, true
+	}
+	if s == "simple" {
+		return s, true
+	}
+	for _, config := range configs {
+		if s == config {
+			return config, true
+		}
+	}
+	return "", false
+}
+
+// stopwords are frequent function words that rarely occur in the other
+// detectable languages.
+var stopwords = map[string][]string{
+	"english":    {"the", "and", "of", "to", "in", "is", "that", "with", "his", "her", "for", "was", "from", "this", "by"},
+	"spanish":    {"el", "la", "los", "las", "y", "de", "del", "que", "en", "un", "una", "por", "con", "su", "es"},
+	"german":     {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "mit", "von", "den", "dem", "zu", "sich", "auf"},
+	"french":     {"le", "la", "les", "et", "des", "du", "un", "une", "est", "dans", "qui", "pour", "sur", "au", "avec"},
+	"italian":    {"il", "lo", "gli", "e", "di", "che", "un", "una", "per", "con", "del", "della", "sono", "nel", "è"},
+	"portuguese": {"o", "os", "as", "e", "de", "do", "da", "que", "em", "um", "uma", "para", "com", "não", "no"},
+	"dutch":      {"de", "het", "een", "en", "van", "is", "niet", "op", "dat", "zijn", "met", "voor", "aan", "ook", "bij"},
+}
+
+// languagesByStopword inverts stopwords; some words belong to several
+// languages.
+var languagesByStopword = func() map[string][]string {
+	m := make(map[string][]string)
+	for config, words := range stopwords {
+		for _, w := range words {
+			m[w] = append(m[w], config)
+		}
+	}
+	return m
+}()
+
+// minHits is how many stopwords a text needs before Detect trusts a guess.
+const minHits = 3
+
+// Detect guesses the text search configuration for text by counting
+// stopwords. It reports false for short or ambiguous texts; callers should
+// fall back to a default rather than trust a weak guess.
+func Detect(text string) (string, bool) {
+	counts := make(map[string]int, len(stopwords))
+	for word := range strings.FieldsFuncSeq(strings.ToLower(text), notLetter) {
+		for _, config := range languagesByStopword[word] {
+			counts[config]++
+		}
+	}
+
+	var config string
+	var best, second int
+	for c, n := range counts {
+		if n > best {
+			config, best, second = c, n, best
+		} else if n > second {
+			second = n
+		}
+	}
+	// A tie leaves second equal to best.
+	if best < minHits || best == second {
+		return "", false
+	}
+	return config, true
+}
+
+func notLetter(r rune) bool {
+	return !unicode.IsLetter(r)
+}
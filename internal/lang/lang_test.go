@@ -0,0 +1,49 @@
+package lang
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{in: "es", want: "spanish", ok: true},
+		{in: "DE", want: "german", ok: true},
+		{in: "french", want: "french", ok: true},
+		{in: "simple", want: "simple", ok: true},
+		{in: "klingon"},
+		{in: ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := Config(tt.in)
+		assert.Equal(t, tt.ok, ok, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+		ok   bool
+	}{
+		{text: "The story of a young man and his journey to the sea", want: "english", ok: true},
+		{text: "La historia de un hombre que vive en el campo con su familia", want: "spanish", ok: true},
+		{text: "Die Geschichte eines Mannes, der mit seiner Familie auf dem Land lebt und nicht zurückkehrt", want: "german", ok: true},
+		{text: "L'histoire d'un homme qui vit dans la campagne avec les siens et pour les siens", want: "french", ok: true},
+		{text: "Dune"},
+		{text: ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := Detect(tt.text)
+		assert.Equal(t, tt.ok, ok, tt.text)
+		assert.Equal(t, tt.want, got, tt.text)
+	}
+}
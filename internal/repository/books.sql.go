@@ -12,63 +12,368 @@ import (
 	"github.com/pgvector/pgvector-go"
 )
 
+const bookFacetCounts = `-- name: BookFacetCounts :many
+SELECT f.facet::text AS facet, f.value::text AS value, count(*) AS books
+FROM books AS b
+CROSS JOIN LATERAL (
+    SELECT 'genre' AS facet, g AS value FROM unnest(b.genres) AS g
+    UNION ALL
+    SELECT 'author', b.author WHERE b.author IS NOT NULL
+    UNION ALL
+    SELECT 'language', b.language::text
+    UNION ALL
+    SELECT 'decade', (b.published_year / 10 * 10)::text WHERE b.published_year IS NOT NULL
+) AS f
+WHERE b.id = ANY ($1::int[]) AND f.facet = ANY ($2::text[])
+GROUP BY f.facet, f.value
+ORDER BY f.facet, books DESC, f.value
+`
+
+type BookFacetCountsParams struct {
+	Ids    []int32
+	Facets []string
+}
+
+type BookFacetCountsRow struct {
+	Facet string
+	Value string
+	Books int64
+}
+
+// Counts metadata values over a candidate set of books, for the requested
+// facets only. Decades are reported by their first year, e.g. "1990".
+func (q *Queries) BookFacetCounts(ctx context.Context, arg BookFacetCountsParams) ([]BookFacetCountsRow, error) {
+	rows, err := q.db.Query(ctx, bookFacetCounts, arg.Ids, arg.Facets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BookFacetCountsRow
+	for rows.Next() {
+		var i BookFacetCountsRow
+		if err := rows.Scan(&i.Facet, &i.Value, &i.Books); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteBookByISBN = `-- name: DeleteBookByISBN :one
+DELETE FROM books
+WHERE isbn = $1
+RETURNING id, isbn, title
+`
+
+type DeleteBookByISBNRow struct {
+	ID    int32
+	Isbn  pgtype.Text
+	Title string
+}
+
+func (q *Queries) DeleteBookByISBN(ctx context.Context, isbn pgtype.Text) (DeleteBookByISBNRow, error) {
+	row := q.db.QueryRow(ctx, deleteBookByISBN, isbn)
+	var i DeleteBookByISBNRow
+	err := row.Scan(&i.ID, &i.Isbn, &i.Title)
+	return i, err
+}
+
+const fuzzySearchTitles = `-- name: FuzzySearchTitles :many
+SELECT id, isbn, title, description,
+       word_similarity($1::text, lower(title))::float8 AS score
+FROM books
+WHERE lower(title) %> $1::text
+  AND word_similarity($1::text, lower(title)) >= $2::float8
+ORDER BY score DESC, title
+LIMIT $3
+`
+
+type FuzzySearchTitlesParams struct {
+	Query      string
+	Threshold  float64
+	MaxResults int32
+}
+
+type FuzzySearchTitlesRow struct {
+	ID          int32
+	Isbn        pgtype.Text
+	Title       string
+	Description string
+	Score       float64
+}
+
+// Titles containing a close match for query, which must be lowercase.
+// Candidates come from the trigram index at the transaction's
+// pg_trgm.word_similarity_threshold.
+func (q *Queries) FuzzySearchTitles(ctx context.Context, arg FuzzySearchTitlesParams) ([]FuzzySearchTitlesRow, error) {
+	rows, err := q.db.Query(ctx, fuzzySearchTitles, arg.Query, arg.Threshold, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FuzzySearchTitlesRow
+	for rows.Next() {
+		var i FuzzySearchTitlesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Isbn,
+			&i.Title,
+			&i.Description,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getBookByISBN = `-- name: GetBookByISBN :one
-SELECT id, isbn
+SELECT id, isbn, title, content_hash, language, author, genres, published_year, work_id, embedding_status
 FROM books
 WHERE isbn = $1
 `
 
 type GetBookByISBNRow struct {
-	ID   int32
-	Isbn pgtype.Text
+	ID              int32
+	Isbn            pgtype.Text
+	Title           string
+	ContentHash     pgtype.Text
+	Language        string
+	Author          pgtype.Text
+	Genres          []string
+	PublishedYear   pgtype.Int4
+	WorkID          pgtype.Int4
+	EmbeddingStatus string
 }
 
 func (q *Queries) GetBookByISBN(ctx context.Context, isbn pgtype.Text) (GetBookByISBNRow, error) {
 	row := q.db.QueryRow(ctx, getBookByISBN, isbn)
 	var i GetBookByISBNRow
-	err := row.Scan(&i.ID, &i.Isbn)
+	err := row.Scan(
+		&i.ID,
+		&i.Isbn,
+		&i.Title,
+		&i.ContentHash,
+		&i.Language,
+		&i.Author,
+		&i.Genres,
+		&i.PublishedYear,
+		&i.WorkID,
+		&i.EmbeddingStatus,
+	)
 	return i, err
 }
 
-const insertBook = `-- name: InsertBook :exec
-INSERT INTO books (isbn, title, description, embedding)
-VALUES ($1, $2, $3, $4)
+const getBookStatus = `-- name: GetBookStatus :one
+SELECT id, isbn, title, embedding_status, embedding_error
+FROM books
+WHERE isbn = $1
+`
+
+type GetBookStatusRow struct {
+	ID              int32
+	Isbn            pgtype.Text
+	Title           string
+	EmbeddingStatus string
+	EmbeddingError  pgtype.Text
+}
+
+func (q *Queries) GetBookStatus(ctx context.Context, isbn pgtype.Text) (GetBookStatusRow, error) {
+	row := q.db.QueryRow(ctx, getBookStatus, isbn)
+	var i GetBookStatusRow
+	err := row.Scan(
+		&i.ID,
+		&i.Isbn,
+		&i.Title,
+		&i.EmbeddingStatus,
+		&i.EmbeddingError,
+	)
+	return i, err
+}
+
+const insertBook = `-- name: InsertBook :one
+INSERT INTO books (isbn, title, description, embedding, content_hash, language, author, genres, published_year, work_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id
 `
 
 type InsertBookParams struct {
-	Isbn        pgtype.Text
-	Title       string
-	Description string
-	Embedding   pgvector.Vector
+	Isbn          pgtype.Text
+	Title         string
+	Description   string
+	Embedding     pgvector.Vector
+	ContentHash   pgtype.Text
+	Language      string
+	Author        pgtype.Text
+	Genres        []string
+	PublishedYear pgtype.Int4
+	WorkID        pgtype.Int4
 }
 
-func (q *Queries) InsertBook(ctx context.Context, arg InsertBookParams) error {
-	_, err := q.db.Exec(ctx, insertBook,
+func (q *Queries) InsertBook(ctx context.Context, arg InsertBookParams) (int32, error) {
+	row := q.db.QueryRow(ctx, insertBook,
 		arg.Isbn,
 		arg.Title,
 		arg.Description,
 		arg.Embedding,
+		arg.ContentHash,
+		arg.Language,
+		arg.Author,
+		arg.Genres,
+		arg.PublishedYear,
+		arg.WorkID,
 	)
-	return err
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertPendingBook = `-- name: InsertPendingBook :one
+WITH book AS (
+    INSERT INTO books (isbn, title, description, content_hash, language, author, genres, published_year, embedding_status)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending')
+    RETURNING id
+)
+INSERT INTO embedding_jobs (book_id)
+SELECT id FROM book
+RETURNING book_id
+`
+
+type InsertPendingBookParams struct {
+	Isbn          pgtype.Text
+	Title         string
+	Description   string
+	ContentHash   pgtype.Text
+	Language      string
+	Author        pgtype.Text
+	Genres        []string
+	PublishedYear pgtype.Int4
+}
+
+// Stores a book without its embedding and enqueues the embedding job in the
+// same statement, so both happen or neither does.
+func (q *Queries) InsertPendingBook(ctx context.Context, arg InsertPendingBookParams) (int32, error) {
+	row := q.db.QueryRow(ctx, insertPendingBook,
+		arg.Isbn,
+		arg.Title,
+		arg.Description,
+		arg.ContentHash,
+		arg.Language,
+		arg.Author,
+		arg.Genres,
+		arg.PublishedYear,
+	)
+	var book_id int32
+	err := row.Scan(&book_id)
+	return book_id, err
+}
+
+const listEmbeddedBooks = `-- name: ListEmbeddedBooks :many
+SELECT id, isbn, title, description, embedding, work_id, language, author, genres, published_year
+FROM books
+WHERE embedding IS NOT NULL AND id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListEmbeddedBooksParams struct {
+	After      int32
+	MaxResults int32
+}
+
+type ListEmbeddedBooksRow struct {
+	ID            int32
+	Isbn          pgtype.Text
+	Title         string
+	Description   string
+	Embedding     pgvector.Vector
+	WorkID        pgtype.Int4
+	Language      string
+	Author        pgtype.Text
+	Genres        []string
+	PublishedYear pgtype.Int4
+}
+
+// Embedded books with an id above after, in id order, for paging through
+// the catalogue.
+func (q *Queries) ListEmbeddedBooks(ctx context.Context, arg ListEmbeddedBooksParams) ([]ListEmbeddedBooksRow, error) {
+	rows, err := q.db.Query(ctx, listEmbeddedBooks, arg.After, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEmbeddedBooksRow
+	for rows.Next() {
+		var i ListEmbeddedBooksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Isbn,
+			&i.Title,
+			&i.Description,
+			&i.Embedding,
+			&i.WorkID,
+			&i.Language,
+			&i.Author,
+			&i.Genres,
+			&i.PublishedYear,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const searchBooks = `-- name: SearchBooks :many
-SELECT id, isbn, title, description, embedding
+SELECT id, isbn, title, description, embedding, work_id
 FROM books
-ORDER BY embedding <=> $1
-LIMIT 5
+WHERE embedding IS NOT NULL
+  AND ($1::text IS NULL OR $1::text = ANY (genres))
+  AND ($2::text IS NULL OR lower(author) = lower($2::text))
+  AND ($3::text IS NULL OR language = $3::text)
+  AND ($4::int IS NULL OR published_year / 10 * 10 = $4::int)
+ORDER BY embedding <=> $5
+LIMIT $6
 `
 
+type SearchBooksParams struct {
+	Genre      pgtype.Text
+	Author     pgtype.Text
+	Language   pgtype.Text
+	Decade     pgtype.Int4
+	Embedding  pgvector.Vector
+	MaxResults int32
+}
+
 type SearchBooksRow struct {
 	ID          int32
 	Isbn        pgtype.Text
 	Title       string
 	Description string
 	Embedding   pgvector.Vector
+	WorkID      pgtype.Int4
 }
 
-func (q *Queries) SearchBooks(ctx context.Context, embedding pgvector.Vector) ([]SearchBooksRow, error) {
-	rows, err := q.db.Query(ctx, searchBooks, embedding)
+// Nearest books to embedding, narrowed by the optional drill-down filters.
+func (q *Queries) SearchBooks(ctx context.Context, arg SearchBooksParams) ([]SearchBooksRow, error) {
+	rows, err := q.db.Query(ctx, searchBooks,
+		arg.Genre,
+		arg.Author,
+		arg.Language,
+		arg.Decade,
+		arg.Embedding,
+		arg.MaxResults,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -82,6 +387,7 @@ func (q *Queries) SearchBooks(ctx context.Context, embedding pgvector.Vector) ([
 			&i.Title,
 			&i.Description,
 			&i.Embedding,
+			&i.WorkID,
 		); err != nil {
 			return nil, err
 		}
@@ -93,23 +399,157 @@ func (q *Queries) SearchBooks(ctx context.Context, embedding pgvector.Vector) ([
 	return items, nil
 }
 
-const searchBooksByText = `-- name: SearchBooksByText :many
-SELECT id, isbn, title, description
-FROM books
-WHERE tsv @@ plainto_tsquery('english', $1)
-ORDER BY ts_rank(tsv, plainto_tsquery('english', $1)) DESC
-LIMIT 10
+const searchBooksBinary = `-- name: SearchBooksBinary :many
+WITH candidates AS (
+    SELECT id
+    FROM books
+    WHERE embedding IS NOT NULL
+      AND ($1::text IS NULL OR $1::text = ANY (genres))
+      AND ($2::text IS NULL OR lower(author) = lower($2::text))
+      AND ($3::text IS NULL OR language = $3::text)
+      AND ($4::int IS NULL OR published_year / 10 * 10 = $4::int)
+    ORDER BY binary_quantize(embedding)::bit(768) <~> binary_quantize($5::vector)
+    LIMIT $6
+)
+SELECT b.id, b.isbn, b.title, b.description, b.embedding, b.work_id
+FROM books AS b
+JOIN candidates AS c ON c.id = b.id
+ORDER BY b.embedding <=> $5::vector
+LIMIT $7
 `
 
-type SearchBooksByTextRow struct {
+type SearchBooksBinaryParams struct {
+	Genre      pgtype.Text
+	Author     pgtype.Text
+	Language   pgtype.Text
+	Decade     pgtype.Int4
+	Embedding  pgvector.Vector
+	Candidates int32
+	MaxResults int32
+}
+
+type SearchBooksBinaryRow struct {
 	ID          int32
 	Isbn        pgtype.Text
 	Title       string
 	Description string
+	Embedding   pgvector.Vector
+	WorkID      pgtype.Int4
+}
+
+// Like SearchBooks, but takes the candidates nearest by Hamming distance
+// between binary quantized embeddings, which the binary HNSW index
+// (vectorindex.Binary) serves, and re-ranks them by exact cosine distance.
+func (q *Queries) SearchBooksBinary(ctx context.Context, arg SearchBooksBinaryParams) ([]SearchBooksBinaryRow, error) {
+	rows, err := q.db.Query(ctx, searchBooksBinary,
+		arg.Genre,
+		arg.Author,
+		arg.Language,
+		arg.Decade,
+		arg.Embedding,
+		arg.Candidates,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchBooksBinaryRow
+	for rows.Next() {
+		var i SearchBooksBinaryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Isbn,
+			&i.Title,
+			&i.Description,
+			&i.Embedding,
+			&i.WorkID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchBooksByText = `-- name: SearchBooksByText :many
+WITH q AS (
+    SELECT websearch_to_tsquery($1::regconfig, $2::text) && to_tsquery($1::regconfig, $3::text) AS query
+),
+ranked AS (
+    SELECT b.id, b.isbn, b.title, b.description, b.language, b.work_id, ts_rank(b.tsv, q.query) AS rank
+    FROM books AS b, q
+    WHERE b.tsv @@ q.query
+      AND ($4::text IS NULL OR b.language = $4::text)
+      AND ($5::text IS NULL OR $5::text = ANY (b.genres))
+      AND ($6::text IS NULL OR lower(b.author) = lower($6::text))
+      AND ($7::int IS NULL OR b.published_year / 10 * 10 = $7::int)
+    ORDER BY rank DESC
+    LIMIT $8
+)
+SELECT r.id, r.isbn, r.title, r.description, r.work_id, r.rank::float4 AS rank,
+       ts_headline(r.language::regconfig, e.title, q.query, $9::text)::text AS title_headline,
+       ts_headline(r.language::regconfig, e.description, q.query, $10::text)::text AS headline
+FROM ranked AS r, q,
+LATERAL (
+    SELECT CASE WHEN $11::boolean
+                THEN replace(replace(replace(replace(r.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')
+                ELSE r.title END AS title,
+           CASE WHEN $11::boolean
+                THEN replace(replace(replace(replace(r.description, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')
+                ELSE r.description END AS description
+) AS e
+ORDER BY r.rank DESC
+`
+
+type SearchBooksByTextParams struct {
+	Config               string
+	Query                string
+	Prefixes             string
+	Language             pgtype.Text
+	Genre                pgtype.Text
+	Author               pgtype.Text
+	Decade               pgtype.Int4
+	MaxResults           int32
+	TitleHeadlineOptions string
+	HeadlineOptions      string
+	EscapeHtml           bool
 }
 
-func (q *Queries) SearchBooksByText(ctx context.Context, plaintoTsquery string) ([]SearchBooksByTextRow, error) {
-	rows, err := q.db.Query(ctx, searchBooksByText, plaintoTsquery)
+type SearchBooksByTextRow struct {
+	ID            int32
+	Isbn          pgtype.Text
+	Title         string
+	Description   string
+	WorkID        pgtype.Int4
+	Rank          float32
+	TitleHeadline string
+	Headline      string
+}
+
+// Matches query in websearch syntax (quotes, -term, OR) and every prefix
+// term, given in to_tsquery syntax, both parsed with config. If language is
+// set, only books in that language are searched; the other filters drill
+// down the same way. Headlines are only computed for the returned rows.
+// With escape_html the title and description are HTML-escaped before the
+// markers go in, so HTML markers wrap text that is safe to render.
+func (q *Queries) SearchBooksByText(ctx context.Context, arg SearchBooksByTextParams) ([]SearchBooksByTextRow, error) {
+	rows, err := q.db.Query(ctx, searchBooksByText,
+		arg.Config,
+		arg.Query,
+		arg.Prefixes,
+		arg.Language,
+		arg.Genre,
+		arg.Author,
+		arg.Decade,
+		arg.MaxResults,
+		arg.TitleHeadlineOptions,
+		arg.HeadlineOptions,
+		arg.EscapeHtml,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -122,6 +562,244 @@ func (q *Queries) SearchBooksByText(ctx context.Context, plaintoTsquery string)
 			&i.Isbn,
 			&i.Title,
 			&i.Description,
+			&i.WorkID,
+			&i.Rank,
+			&i.TitleHeadline,
+			&i.Headline,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchBooksHalfvec = `-- name: SearchBooksHalfvec :many
+SELECT id, isbn, title, description, embedding, work_id
+FROM books
+WHERE embedding IS NOT NULL
+  AND ($1::text IS NULL OR $1::text = ANY (genres))
+  AND ($2::text IS NULL OR lower(author) = lower($2::text))
+  AND ($3::text IS NULL OR language = $3::text)
+  AND ($4::int IS NULL OR published_year / 10 * 10 = $4::int)
+ORDER BY embedding::halfvec(768) <=> $5::halfvec(768)
+LIMIT $6
+`
+
+type SearchBooksHalfvecParams struct {
+	Genre      pgtype.Text
+	Author     pgtype.Text
+	Language   pgtype.Text
+	Decade     pgtype.Int4
+	Embedding  pgvector.HalfVector
+	MaxResults int32
+}
+
+type SearchBooksHalfvecRow struct {
+	ID          int32
+	Isbn        pgtype.Text
+	Title       string
+	Description string
+	Embedding   pgvector.Vector
+	WorkID      pgtype.Int4
+}
+
+// Like SearchBooks, but ordered by the half precision embeddings, which the
+// halfvec HNSW index (vectorindex.HalfVec) serves.
+func (q *Queries) SearchBooksHalfvec(ctx context.Context, arg SearchBooksHalfvecParams) ([]SearchBooksHalfvecRow, error) {
+	rows, err := q.db.Query(ctx, searchBooksHalfvec,
+		arg.Genre,
+		arg.Author,
+		arg.Language,
+		arg.Decade,
+		arg.Embedding,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchBooksHalfvecRow
+	for rows.Next() {
+		var i SearchBooksHalfvecRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Isbn,
+			&i.Title,
+			&i.Description,
+			&i.Embedding,
+			&i.WorkID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchBooksMatryoshka = `-- name: SearchBooksMatryoshka :many
+WITH candidates AS (
+    SELECT id
+    FROM books
+    WHERE embedding_short IS NOT NULL
+      AND ($1::text IS NULL OR $1::text = ANY (genres))
+      AND ($2::text IS NULL OR lower(author) = lower($2::text))
+      AND ($3::text IS NULL OR language = $3::text)
+      AND ($4::int IS NULL OR published_year / 10 * 10 = $4::int)
+    ORDER BY embedding_short <=> subvector($5::vector, 1, 256)::vector(256)
+    LIMIT $6
+)
+SELECT b.id, b.isbn, b.title, b.description, b.embedding, b.work_id
+FROM books AS b
+JOIN candidates AS c ON c.id = b.id
+ORDER BY b.embedding <=> $5::vector
+LIMIT $7
+`
+
+type SearchBooksMatryoshkaParams struct {
+	Genre      pgtype.Text
+	Author     pgtype.Text
+	Language   pgtype.Text
+	Decade     pgtype.Int4
+	Embedding  pgvector.Vector
+	Candidates int32
+	MaxResults int32
+}
+
+type SearchBooksMatryoshkaRow struct {
+	ID          int32
+	Isbn        pgtype.Text
+	Title       string
+	Description string
+	Embedding   pgvector.Vector
+	WorkID      pgtype.Int4
+}
+
+// Like SearchBooks, but takes the candidates nearest by the truncated
+// embeddings, which the matryoshka HNSW index (vectorindex.Matryoshka)
+// serves, and re-ranks them by exact cosine distance of the full vectors.
+func (q *Queries) SearchBooksMatryoshka(ctx context.Context, arg SearchBooksMatryoshkaParams) ([]SearchBooksMatryoshkaRow, error) {
+	rows, err := q.db.Query(ctx, searchBooksMatryoshka,
+		arg.Genre,
+		arg.Author,
+		arg.Language,
+		arg.Decade,
+		arg.Embedding,
+		arg.Candidates,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchBooksMatryoshkaRow
+	for rows.Next() {
+		var i SearchBooksMatryoshkaRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Isbn,
+			&i.Title,
+			&i.Description,
+			&i.Embedding,
+			&i.WorkID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setHNSWEfSearch = `-- name: SetHNSWEfSearch :exec
+SELECT set_config('hnsw.ef_search', $1::int::text, true)
+`
+
+// Sets the candidate list size of HNSW scans for the rest of the
+// transaction. A scan returns at most ef_search rows, 40 by default.
+func (q *Queries) SetHNSWEfSearch(ctx context.Context, efSearch int32) error {
+	_, err := q.db.Exec(ctx, setHNSWEfSearch, efSearch)
+	return err
+}
+
+const setHNSWIterativeScan = `-- name: SetHNSWIterativeScan :exec
+SELECT set_config('hnsw.iterative_scan', 'strict_order', true)
+FROM pg_extension
+WHERE extname = 'vector'
+  AND string_to_array(extversion, '.')::int[] >= '{0,8}'
+`
+
+// Makes HNSW scans for the rest of the transaction go on past ef_search
+// while filters discard rows, until the LIMIT is filled. Iterative scans
+// arrived in pgvector 0.8; older versions keep the single pass.
+func (q *Queries) SetHNSWIterativeScan(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, setHNSWIterativeScan)
+	return err
+}
+
+const setWordSimilarityThreshold = `-- name: SetWordSimilarityThreshold :exec
+SELECT set_config('pg_trgm.word_similarity_threshold', $1::text, true)
+`
+
+// Sets the threshold of the %> operator for the rest of the transaction.
+func (q *Queries) SetWordSimilarityThreshold(ctx context.Context, threshold string) error {
+	_, err := q.db.Exec(ctx, setWordSimilarityThreshold, threshold)
+	return err
+}
+
+const suggestTitles = `-- name: SuggestTitles :many
+SELECT isbn, title,
+       (lower(title) LIKE $1::text)::boolean AS prefix_match,
+       similarity(lower(title), $2::text)::float8 AS score
+FROM books
+WHERE lower(title) LIKE $3::text
+ORDER BY prefix_match DESC, score DESC, title
+LIMIT $4
+`
+
+type SuggestTitlesParams struct {
+	Prefix     string
+	Query      string
+	Pattern    string
+	MaxResults int32
+}
+
+type SuggestTitlesRow struct {
+	Isbn        pgtype.Text
+	Title       string
+	PrefixMatch bool
+	Score       float64
+}
+
+// Titles matching pattern (a LIKE pattern on the lowercased title), those
+// starting with prefix first, then by trigram similarity to query.
+func (q *Queries) SuggestTitles(ctx context.Context, arg SuggestTitlesParams) ([]SuggestTitlesRow, error) {
+	rows, err := q.db.Query(ctx, suggestTitles,
+		arg.Prefix,
+		arg.Query,
+		arg.Pattern,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SuggestTitlesRow
+	for rows.Next() {
+		var i SuggestTitlesRow
+		if err := rows.Scan(
+			&i.Isbn,
+			&i.Title,
+			&i.PrefixMatch,
+			&i.Score,
 		); err != nil {
 			return nil, err
 		}
@@ -132,3 +810,157 @@ func (q *Queries) SearchBooksByText(ctx context.Context, plaintoTsquery string)
 	}
 	return items, nil
 }
+
+const updateBookDetails = `-- name: UpdateBookDetails :exec
+UPDATE books
+SET title = $2, language = $3, author = $4, genres = $5, published_year = $6
+WHERE id = $1
+`
+
+type UpdateBookDetailsParams struct {
+	ID            int32
+	Title         string
+	Language      string
+	Author        pgtype.Text
+	Genres        []string
+	PublishedYear pgtype.Int4
+}
+
+func (q *Queries) UpdateBookDetails(ctx context.Context, arg UpdateBookDetailsParams) error {
+	_, err := q.db.Exec(ctx, updateBookDetails,
+		arg.ID,
+		arg.Title,
+		arg.Language,
+		arg.Author,
+		arg.Genres,
+		arg.PublishedYear,
+	)
+	return err
+}
+
+const upsertBook = `-- name: UpsertBook :one
+WITH book AS (
+    INSERT INTO books (isbn, title, description, embedding, content_hash, language, author, genres, published_year, work_id)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    ON CONFLICT (isbn) DO UPDATE
+    SET title = EXCLUDED.title,
+        description = EXCLUDED.description,
+        embedding = EXCLUDED.embedding,
+        content_hash = EXCLUDED.content_hash,
+        language = EXCLUDED.language,
+        author = EXCLUDED.author,
+        genres = EXCLUDED.genres,
+        published_year = EXCLUDED.published_year,
+        work_id = coalesce(books.work_id, EXCLUDED.work_id),
+        embedding_status = 'ready',
+        embedding_error = NULL
+    RETURNING id, (xmax = 0)::boolean AS inserted
+), stale AS (
+    DELETE FROM embedding_jobs
+    WHERE book_id IN (SELECT id FROM book)
+)
+SELECT id, inserted FROM book
+`
+
+type UpsertBookParams struct {
+	Isbn          pgtype.Text
+	Title         string
+	Description   string
+	Embedding     pgvector.Vector
+	ContentHash   pgtype.Text
+	Language      string
+	Author        pgtype.Text
+	Genres        []string
+	PublishedYear pgtype.Int4
+	WorkID        pgtype.Int4
+}
+
+type UpsertBookRow struct {
+	ID       int32
+	Inserted bool
+}
+
+// A book keeps its work on update; work_id only applies to new books and to
+// books that were never matched. The new embedding makes the book ready, and
+// any embedding job still queued for it is dropped, so a worker embedding an
+// older description cannot overwrite this one.
+func (q *Queries) UpsertBook(ctx context.Context, arg UpsertBookParams) (UpsertBookRow, error) {
+	row := q.db.QueryRow(ctx, upsertBook,
+		arg.Isbn,
+		arg.Title,
+		arg.Description,
+		arg.Embedding,
+		arg.ContentHash,
+		arg.Language,
+		arg.Author,
+		arg.Genres,
+		arg.PublishedYear,
+		arg.WorkID,
+	)
+	var i UpsertBookRow
+	err := row.Scan(&i.ID, &i.Inserted)
+	return i, err
+}
+
+const upsertPendingBook = `-- name: UpsertPendingBook :one
+WITH book AS (
+    INSERT INTO books (isbn, title, description, content_hash, language, author, genres, published_year, embedding_status)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'pending')
+    ON CONFLICT (isbn) DO UPDATE
+    SET title = EXCLUDED.title,
+        description = EXCLUDED.description,
+        embedding = NULL,
+        content_hash = EXCLUDED.content_hash,
+        language = EXCLUDED.language,
+        author = EXCLUDED.author,
+        genres = EXCLUDED.genres,
+        published_year = EXCLUDED.published_year,
+        embedding_status = 'pending',
+        embedding_error = NULL
+    RETURNING id, (xmax = 0)::boolean AS inserted
+), stale AS (
+    DELETE FROM embedding_jobs
+    WHERE book_id IN (SELECT id FROM book)
+), job AS (
+    INSERT INTO embedding_jobs (book_id)
+    SELECT id FROM book
+)
+SELECT id, inserted FROM book
+`
+
+type UpsertPendingBookParams struct {
+	Isbn          pgtype.Text
+	Title         string
+	Description   string
+	ContentHash   pgtype.Text
+	Language      string
+	Author        pgtype.Text
+	Genres        []string
+	PublishedYear pgtype.Int4
+}
+
+type UpsertPendingBookRow struct {
+	ID       int32
+	Inserted bool
+}
+
+// Stores a book without its embedding, like InsertPendingBook, or replaces
+// the one with the same ISBN. A replaced book loses its old vector, so it
+// stops matching searches for a description it no longer has, and its queued
+// embedding jobs are replaced by a new one; a worker still embedding the old
+// description finds its job gone and discards the result.
+func (q *Queries) UpsertPendingBook(ctx context.Context, arg UpsertPendingBookParams) (UpsertPendingBookRow, error) {
+	row := q.db.QueryRow(ctx, upsertPendingBook,
+		arg.Isbn,
+		arg.Title,
+		arg.Description,
+		arg.ContentHash,
+		arg.Language,
+		arg.Author,
+		arg.Genres,
+		arg.PublishedYear,
+	)
+	var i UpsertPendingBookRow
+	err := row.Scan(&i.ID, &i.Inserted)
+	return i, err
+}
@@ -10,10 +10,106 @@ import (
 )
 
 type Book struct {
-	ID          int32
-	Title       string
-	Description string
-	Embedding   pgvector.Vector
-	Isbn        pgtype.Text
-	Tsv         interface{}
+	ID              int32
+	Title           string
+	Description     string
+	Embedding       pgvector.Vector
+	Isbn            pgtype.Text
+	ContentHash     pgtype.Text
+	EmbeddingStatus string
+	EmbeddingError  pgtype.Text
+	Language        string
+	Tsv             interface{}
+	Author          pgtype.Text
+	Genres          []string
+	PublishedYear   pgtype.Int4
+	WorkID          pgtype.Int4
+	EmbeddingShort  pgvector.Vector
+}
+
+type EmbeddingCache struct {
+	Key       string
+	Value     []byte
+	ExpiresAt pgtype.Timestamptz
+}
+
+type EmbeddingJob struct {
+	ID        int64
+	BookID    int32
+	Attempts  int32
+	LastError pgtype.Text
+	RunAfter  pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+}
+
+type IdempotencyKey struct {
+	Key          string
+	RequestHash  string
+	StatusCode   pgtype.Int4
+	ContentType  pgtype.Text
+	ResponseBody []byte
+	CreatedAt    pgtype.Timestamptz
+	LockedUntil  pgtype.Timestamptz
+}
+
+type PopularQuery struct {
+	Query    pgtype.Text
+	Searches int64
+}
+
+type SearchEvent struct {
+	ID          pgtype.UUID
+	Query       string
+	Mode        string
+	ResultIsbns []string
+	ResultCount int32
+	TopScore    pgtype.Float8
+	LatencyMs   float64
+	Degraded    bool
+	Tenant      pgtype.Text
+	CreatedAt   pgtype.Timestamptz
+}
+
+type SearchFeedback struct {
+	ID        int64
+	EventID   pgtype.UUID
+	Isbn      string
+	Kind      string
+	CreatedAt pgtype.Timestamptz
+}
+
+type WebhookDelivery struct {
+	ID             int64
+	EventID        int64
+	SubscriptionID int32
+	Status         string
+	Attempts       int32
+	NextAttemptAt  pgtype.Timestamptz
+	LastStatusCode pgtype.Int4
+	LastError      pgtype.Text
+	DeliveredAt    pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+}
+
+type WebhookEvent struct {
+	ID        int64
+	EventType string
+	Payload   []byte
+	CreatedAt pgtype.Timestamptz
+}
+
+type WebhookSubscription struct {
+	ID         int32
+	Url        string
+	Secret     string
+	EventTypes []string
+	Active     bool
+	CreatedAt  pgtype.Timestamptz
+}
+
+type Work struct {
+	ID        int32
+	Title     string
+	TitleKey  string
+	CreatedAt pgtype.Timestamptz
 }
@@ -2,22 +2,78 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/nmdra/Semantic-Search/internal/embed"
 	"github.com/nmdra/Semantic-Search/internal/repository"
+	"github.com/nmdra/Semantic-Search/internal/vectorindex"
 	"log/slog"
 	"math"
+	"slices"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/pgvector/pgvector-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+var tracer = otel.Tracer("github.com/nmdra/Semantic-Search/internal/service")
+
+// uniqueViolation is the Postgres SQLSTATE for a unique constraint violation.
+const uniqueViolation = "23505"
+
 type BookService struct {
 	Embedder   embed.Embedder
-	Repository *repository.Queries
+	Repository Store
 	Logger     *slog.Logger
+
+	// DB makes each change and its webhook events commit together. If nil,
+	// events are recorded in a separate statement after the change.
+	DB TxBeginner
+
+	// FallbackToText makes SearchBooks answer with full-text results, flagged
+	// as degraded, when the query cannot be embedded.
+	FallbackToText bool
+
+	// FeedbackWeight blends aggregated click and relevance feedback into
+	// semantic ranking: score = (1-w)·similarity + w·feedback. Zero disables it.
+	FeedbackWeight float64
+
+	// Highlight sets the match markers in full-text headlines.
+	Highlight Highlight
+
+	// DefaultLanguage is the text search configuration for books without a
+	// language and for text searches without one; empty means lang.Default.
+	DefaultLanguage string
+	// DetectLanguage guesses the language of books ingested without one.
+	DetectLanguage bool
+
+	// Diversity is the default trade-off between relevance and novelty in
+	// semantic results, from 0 (off) to 1; see SearchOptions.Diversity.
+	Diversity float64
+
+	// FuzzyThreshold is the default minimum word similarity between a fuzzy
+	// query and a title; zero means DefaultFuzzyThreshold.
+	FuzzyThreshold float64
+
+	// Works groups new books with the other editions of the same work.
+	Works WorkMatcher
+
+	// VectorIndex selects the index that semantic search runs on; empty
+	// means vectorindex.Vector. The index must exist, see vectorindex.Create.
+	VectorIndex vectorindex.Mode
+
+	// Vectors, if set, serves semantic search in place of Postgres. With a
+	// Repository, books are still added to Postgres and reach Vectors with
+	// the next snapshot. Repository may be nil, and Vectors then stores new
+	// books too; only AddBook and SearchBooks work, facets and collapsing
+	// are rejected, and feedback ranking and the text fallback are skipped.
+	Vectors VectorStore
 }
 
 type BookWithSimilarity struct {
@@ -26,32 +82,94 @@ type BookWithSimilarity struct {
 	Title       string
 	Description string
 	Similarity  float64
+	// Score is the ranking score: Similarity blended with feedback.
+	Score float64
+	// WorkID is zero until the book has been matched to a work.
+	WorkID int32 `json:",omitempty"`
+	// Editions are the other books of the work, if results are collapsed.
+	Editions []Edition `json:",omitempty"`
+
+	embedding []float32 // for diversification
+}
+
+type SearchResult struct {
+	Results []BookWithSimilarity `json:"results"`
+	// Degraded is set when semantic search was unavailable and the results
+	// come from full-text search instead.
+	Degraded bool `json:"degraded"`
+	// Facets counts metadata over the candidates, if requested.
+	Facets Facets `json:"facets,omitempty"`
+	// EventID identifies the recorded search, for feedback. It is set by the
+	// API layer when search analytics are enabled.
+	EventID string `json:"event_id,omitempty"`
 }
 
-// AddBook embeds the book description and stores it in the database.
-func (s *BookService) AddBook(ctx context.Context, isbn, title, desc string) error {
+// AddBook validates the book, embeds its description and stores it in the database.
+func (s *BookService) AddBook(ctx context.Context, in BookInput) error {
+	ctx, span := tracer.Start(ctx, "BookService.AddBook")
+	defer span.End()
+
+	book, err := ValidateBook(in)
+	if err != nil {
+		return err
+	}
+	isbn, title, desc := book.ISBN, book.Title, book.Description
+	language := s.bookLanguage(book)
+	meta := newBookMetadata(book)
+	span.SetAttributes(attribute.String("book.isbn", isbn))
+
+	if s.Repository == nil {
+		return s.addToVectors(ctx, book)
+	}
 
 	// Check Book already exists
-	_, err := s.Repository.GetBookByISBN(ctx, pgtype.Text{String: isbn, Valid: true})
+	_, err = s.Repository.GetBookByISBN(ctx, pgtype.Text{String: isbn, Valid: true})
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		return fmt.Errorf("failed to check existing ISBN: %w", err)
 	}
 	if err == nil {
-		return fmt.Errorf("book with isbn %s already exists", isbn)
+		return Conflict("book_exists", fmt.Sprintf("book with isbn %s already exists", isbn))
 	}
 
 	vector, err := s.Embedder.Embed(ctx, desc)
 	if err != nil {
-		return fmt.Errorf("embedding failed: %w", err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return embeddingError(err)
 	}
 
-	err = s.Repository.InsertBook(ctx, repository.InsertBookParams{
-		Isbn:        pgtype.Text{String: isbn, Valid: true},
-		Title:       title,
-		Description: desc,
-		Embedding:   pgvector.NewVector(vector),
+	err = s.withTx(ctx, func(q Store) error {
+		workID, err := s.Works.Assign(ctx, q, isbn, title, vector)
+		if err != nil {
+			return err
+		}
+		id, err := q.InsertBook(ctx, repository.InsertBookParams{
+			Isbn:          pgtype.Text{String: isbn, Valid: true},
+			Title:         title,
+			Description:   desc,
+			Embedding:     pgvector.NewVector(vector),
+			ContentHash:   pgtype.Text{String: contentHash(desc), Valid: true},
+			Language:      language,
+			Author:        meta.author,
+			Genres:        meta.genres,
+			PublishedYear: meta.publishedYear,
+			WorkID:        pgtype.Int4{Int32: workID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		event := BookEvent{ID: id, ISBN: isbn, Title: title, EmbeddingStatus: EmbeddingReady}
+		if err := EmitEvent(ctx, q, EventBookCreated, event); err != nil {
+			return err
+		}
+		return EmitEvent(ctx, q, EventBookEmbedded, event)
 	})
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return Conflict("book_exists", fmt.Sprintf("book with isbn %s already exists", isbn))
+		}
 		s.Logger.Error("Failed to insert book", "isbn", isbn, "title", title, "error", err)
 		return fmt.Errorf("failed to insert book: %w", err)
 	}
@@ -59,23 +177,444 @@ func (s *BookService) AddBook(ctx context.Context, isbn, title, desc string) err
 	return nil
 }
 
+// Embedding states of a stored book.
+const (
+	EmbeddingPending = "pending"
+	EmbeddingReady   = "ready"
+	EmbeddingFailed  = "failed"
+)
+
+// AddBookAsync validates and stores the book with a pending embedding and
+// enqueues an embedding job in the same statement. The embedding worker
+// fills in the vector later; GetBookStatus reports progress.
+func (s *BookService) AddBookAsync(ctx context.Context, in BookInput) (string, error) {
+	ctx, span := tracer.Start(ctx, "BookService.AddBookAsync")
+	defer span.End()
+
+	book, err := ValidateBook(in)
+	if err != nil {
+		return "", err
+	}
+	span.SetAttributes(attribute.String("book.isbn", book.ISBN))
+	meta := newBookMetadata(book)
+
+	err = s.withTx(ctx, func(q Store) error {
+		id, err := q.InsertPendingBook(ctx, repository.InsertPendingBookParams{
+			Isbn:          pgtype.Text{String: book.ISBN, Valid: true},
+			Title:         book.Title,
+			Description:   book.Description,
+			ContentHash:   pgtype.Text{String: contentHash(book.Description), Valid: true},
+			Language:      s.bookLanguage(book),
+			Author:        meta.author,
+			Genres:        meta.genres,
+			PublishedYear: meta.publishedYear,
+		})
+		if err != nil {
+			return err
+		}
+		return EmitEvent(ctx, q, EventBookCreated, BookEvent{
+			ID:              id,
+			ISBN:            book.ISBN,
+			Title:           book.Title,
+			EmbeddingStatus: EmbeddingPending,
+		})
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return "", Conflict("book_exists", fmt.Sprintf("book with isbn %s already exists", book.ISBN))
+		}
+		s.Logger.Error("Failed to enqueue book", "isbn", book.ISBN, "error", err)
+		return "", fmt.Errorf("failed to enqueue book: %w", err)
+	}
+
+	return book.ISBN, nil
+}
+
+type BookStatus struct {
+	ID     int32  `json:"id"`
+	ISBN   string `json:"isbn"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	// Code and Error describe why a failed book could not be embedded.
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// embeddingFailures are the messages of the codes EmbeddingFailure returns.
+var embeddingFailures = map[string]string{
+	"embedding_rate_limited": "embedding provider is rate limiting requests, retry later",
+	"embedding_unavailable":  "embedding service unavailable",
+}
+
+// EmbeddingFailure returns the stable code of an embedding error, which the
+// embedding worker stores for a book in place of the error text, so that
+// upstream messages never reach clients.
+func EmbeddingFailure(err error) string {
+	domainErr, _ := AsError(embeddingError(err))
+	return domainErr.Code
+}
+
+// GetBookStatus reports the embedding state of the book with the given ISBN.
+func (s *BookService) GetBookStatus(ctx context.Context, isbn string) (BookStatus, error) {
+	normalized, err := normalizeLookupISBN(isbn)
+	if err != nil {
+		return BookStatus{}, err
+	}
+
+	row, err := s.Repository.GetBookStatus(ctx, pgtype.Text{String: normalized, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return BookStatus{}, NotFound("book_not_found", fmt.Sprintf("no book with isbn %s", normalized))
+	}
+	if err != nil {
+		return BookStatus{}, fmt.Errorf("failed to get book status: %w", err)
+	}
+
+	status := BookStatus{
+		ID:     row.ID,
+		ISBN:   row.Isbn.String,
+		Title:  row.Title,
+		Status: row.EmbeddingStatus,
+	}
+	if row.EmbeddingError.Valid {
+		status.Code = row.EmbeddingError.String
+		message, ok := embeddingFailures[status.Code]
+		if !ok {
+			// Stored before errors were recorded by code.
+			status.Code, message = "embedding_failed", "the book could not be embedded"
+		}
+		status.Error = message
+	}
+	return status, nil
+}
+
+type UpsertOutcome string
+
+const (
+	UpsertCreated   UpsertOutcome = "created"
+	UpsertUpdated   UpsertOutcome = "updated"
+	UpsertUnchanged UpsertOutcome = "unchanged"
+)
+
+type UpsertResult struct {
+	ID      int32         `json:"id"`
+	ISBN    string        `json:"isbn"`
+	Outcome UpsertOutcome `json:"status"`
+
+	// EmbeddingStatus is pending when UpsertBookAsync queued the book for
+	// embedding, and empty otherwise.
+	EmbeddingStatus string `json:"embedding_status,omitempty"`
+}
+
+// UpsertBook creates the book or replaces the one with the same ISBN. The
+// description is only re-embedded when its content hash has changed, so
+// repeating an upsert is cheap and never fails with a conflict.
+func (s *BookService) UpsertBook(ctx context.Context, in BookInput) (UpsertResult, error) {
+	ctx, span := tracer.Start(ctx, "BookService.UpsertBook")
+	defer span.End()
+
+	book, err := ValidateBook(in)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	isbn, title, desc := book.ISBN, book.Title, book.Description
+	language := s.bookLanguage(book)
+	meta := newBookMetadata(book)
+	hash := contentHash(desc)
+	span.SetAttributes(attribute.String("book.isbn", isbn))
+
+	existing, result, done, err := s.upsertUnchanged(ctx, book, hash)
+	if err != nil || done {
+		span.SetAttributes(attribute.Bool("book.reembedded", false))
+		return result, err
+	}
+
+	vector, err := s.Embedder.Embed(ctx, desc)
+	if err != nil {
+		if ctx.Err() != nil {
+			return UpsertResult{}, ctx.Err()
+		}
+		return UpsertResult{}, embeddingError(err)
+	}
+	span.SetAttributes(attribute.Bool("book.reembedded", true))
+
+	err = s.withTx(ctx, func(q Store) error {
+		workID := existing.WorkID
+		if !workID.Valid {
+			id, err := s.Works.Assign(ctx, q, isbn, title, vector)
+			if err != nil {
+				return err
+			}
+			workID = pgtype.Int4{Int32: id, Valid: true}
+		}
+		row, err := q.UpsertBook(ctx, repository.UpsertBookParams{
+			Isbn:          pgtype.Text{String: isbn, Valid: true},
+			Title:         title,
+			Description:   desc,
+			Embedding:     pgvector.NewVector(vector),
+			ContentHash:   pgtype.Text{String: hash, Valid: true},
+			Language:      language,
+			Author:        meta.author,
+			Genres:        meta.genres,
+			PublishedYear: meta.publishedYear,
+			WorkID:        workID,
+		})
+		if err != nil {
+			return err
+		}
+
+		result = UpsertResult{ID: row.ID, ISBN: isbn, Outcome: UpsertUpdated}
+		eventType := EventBookUpdated
+		if row.Inserted {
+			result.Outcome = UpsertCreated
+			eventType = EventBookCreated
+		}
+		event := BookEvent{ID: row.ID, ISBN: isbn, Title: title, EmbeddingStatus: EmbeddingReady}
+		if err := EmitEvent(ctx, q, eventType, event); err != nil {
+			return err
+		}
+		return EmitEvent(ctx, q, EventBookEmbedded, event)
+	})
+	if err != nil {
+		s.Logger.Error("Failed to upsert book", "isbn", isbn, "title", title, "error", err)
+		return UpsertResult{}, fmt.Errorf("failed to upsert book: %w", err)
+	}
+
+	return result, nil
+}
+
+// upsertUnchanged looks up the stored book with the ISBN of the validated
+// book. If its description has the given content hash and is embedded, the
+// embedding stays: only changed details are written, and done is true.
+// Otherwise it returns the stored book, or a zero row if there is none, for
+// the caller to replace.
+func (s *BookService) upsertUnchanged(ctx context.Context, book BookInput, hash string) (repository.GetBookByISBNRow, UpsertResult, bool, error) {
+	existing, err := s.Repository.GetBookByISBN(ctx, pgtype.Text{String: book.ISBN, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return existing, UpsertResult{}, false, nil
+	}
+	if err != nil {
+		return existing, UpsertResult{}, false, fmt.Errorf("failed to check existing ISBN: %w", err)
+	}
+	// A book still waiting for, or denied, its embedding is embedded again
+	// even if the description is the same.
+	if !existing.ContentHash.Valid || existing.ContentHash.String != hash || existing.EmbeddingStatus != EmbeddingReady {
+		return existing, UpsertResult{}, false, nil
+	}
+
+	language := s.bookLanguage(book)
+	meta := newBookMetadata(book)
+	result := UpsertResult{ID: existing.ID, ISBN: book.ISBN, Outcome: UpsertUnchanged}
+	if existing.Title == book.Title && existing.Language == language && meta.matches(existing) {
+		return existing, result, true, nil
+	}
+	err = s.withTx(ctx, func(q Store) error {
+		err := q.UpdateBookDetails(ctx, repository.UpdateBookDetailsParams{
+			ID:            existing.ID,
+			Title:         book.Title,
+			Language:      language,
+			Author:        meta.author,
+			Genres:        meta.genres,
+			PublishedYear: meta.publishedYear,
+		})
+		if err != nil {
+			return err
+		}
+		return EmitEvent(ctx, q, EventBookUpdated, BookEvent{
+			ID:              existing.ID,
+			ISBN:            book.ISBN,
+			Title:           book.Title,
+			EmbeddingStatus: EmbeddingReady,
+		})
+	})
+	if err != nil {
+		return existing, UpsertResult{}, false, fmt.Errorf("failed to update book details: %w", err)
+	}
+	result.Outcome = UpsertUpdated
+	return existing, result, true, nil
+}
+
+// UpsertBookAsync is UpsertBook for asynchronous ingest. A new book, or one
+// whose description has changed, is stored with a pending embedding and
+// queued for the embedding worker; a replaced book's old vector is cleared,
+// so it stops matching searches until the new one is ready.
+func (s *BookService) UpsertBookAsync(ctx context.Context, in BookInput) (UpsertResult, error) {
+	ctx, span := tracer.Start(ctx, "BookService.UpsertBookAsync")
+	defer span.End()
+
+	book, err := ValidateBook(in)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	span.SetAttributes(attribute.String("book.isbn", book.ISBN))
+	hash := contentHash(book.Description)
+
+	_, result, done, err := s.upsertUnchanged(ctx, book, hash)
+	if err != nil || done {
+		return result, err
+	}
+
+	meta := newBookMetadata(book)
+	err = s.withTx(ctx, func(q Store) error {
+		row, err := q.UpsertPendingBook(ctx, repository.UpsertPendingBookParams{
+			Isbn:          pgtype.Text{String: book.ISBN, Valid: true},
+			Title:         book.Title,
+			Description:   book.Description,
+			ContentHash:   pgtype.Text{String: hash, Valid: true},
+			Language:      s.bookLanguage(book),
+			Author:        meta.author,
+			Genres:        meta.genres,
+			PublishedYear: meta.publishedYear,
+		})
+		if err != nil {
+			return err
+		}
+
+		result = UpsertResult{ID: row.ID, ISBN: book.ISBN, Outcome: UpsertUpdated, EmbeddingStatus: EmbeddingPending}
+		eventType := EventBookUpdated
+		if row.Inserted {
+			result.Outcome = UpsertCreated
+			eventType = EventBookCreated
+		}
+		return EmitEvent(ctx, q, eventType, BookEvent{
+			ID:              row.ID,
+			ISBN:            book.ISBN,
+			Title:           book.Title,
+			EmbeddingStatus: EmbeddingPending,
+		})
+	})
+	if err != nil {
+		s.Logger.Error("Failed to enqueue book", "isbn", book.ISBN, "error", err)
+		return UpsertResult{}, fmt.Errorf("failed to enqueue book: %w", err)
+	}
+
+	return result, nil
+}
+
+// DeleteBook removes the book with the given ISBN.
+func (s *BookService) DeleteBook(ctx context.Context, isbn string) error {
+	ctx, span := tracer.Start(ctx, "BookService.DeleteBook")
+	defer span.End()
+
+	normalized, err := normalizeLookupISBN(isbn)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.String("book.isbn", normalized))
+
+	err = s.withTx(ctx, func(q Store) error {
+		row, err := q.DeleteBookByISBN(ctx, pgtype.Text{String: normalized, Valid: true})
+		if err != nil {
+			return err
+		}
+		return EmitEvent(ctx, q, EventBookDeleted, BookEvent{ID: row.ID, ISBN: row.Isbn.String, Title: row.Title})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NotFound("book_not_found", fmt.Sprintf("no book with isbn %s", normalized))
+	}
+	if err != nil {
+		s.Logger.Error("Failed to delete book", "isbn", normalized, "error", err)
+		return fmt.Errorf("failed to delete book: %w", err)
+	}
+	return nil
+}
+
+// bookMetadata holds a validated book's optional metadata as column values.
+type bookMetadata struct {
+	author        pgtype.Text
+	genres        []string
+	publishedYear pgtype.Int4
+}
+
+func newBookMetadata(book BookInput) bookMetadata {
+	meta := bookMetadata{genres: book.Genres}
+	if meta.genres == nil {
+		meta.genres = []string{} // the column is NOT NULL
+	}
+	if book.Author != "" {
+		meta.author = pgtype.Text{String: book.Author, Valid: true}
+	}
+	if book.PublishedYear != 0 {
+		meta.publishedYear = pgtype.Int4{Int32: int32(book.PublishedYear), Valid: true}
+	}
+	return meta
+}
+
+func (m bookMetadata) matches(row repository.GetBookByISBNRow) bool {
+	return m.author == row.Author && slices.Equal(m.genres, row.Genres) && m.publishedYear == row.PublishedYear
+}
+
+// contentHash identifies the text that gets embedded for a book.
+func contentHash(desc string) string {
+	sum := sha256.Sum256([]byte(desc))
+	return hex.EncodeToString(sum[:])
+}
+
 // SearchBooks embeds the query, performs vector search, and ranks by cosine similarity.
-func (s *BookService) SearchBooks(ctx context.Context, query string) ([]BookWithSimilarity, error) {
+// If embedding fails and FallbackToText is set, it degrades to full-text search.
+func (s *BookService) SearchBooks(ctx context.Context, query string, opts SearchOptions) (*SearchResult, error) {
+	ctx, span := tracer.Start(ctx, "BookService.SearchBooks")
+	defer span.End()
+
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
 
+	filters, err := opts.Filters.params()
+	if err != nil {
+		return nil, err
+	}
+	if s.Repository == nil && (len(opts.Facets) > 0 || opts.Collapse == CollapseWork) {
+		return nil, Validation("unsupported_option", "facets and collapse are not available without the database")
+	}
+
 	vector, err := s.Embedder.Embed(ctx, query)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if s.FallbackToText && s.Repository != nil {
+			s.Logger.Warn("Embedding failed, falling back to full-text search", "query", query, "error", err)
+			span.SetAttributes(attribute.Bool("search.degraded", true))
+			return s.fallbackSearch(ctx, query, opts)
+		}
 		s.Logger.Error("Embedding failed", "query", query, "error", err)
-		return nil, fmt.Errorf("embedding failed: %w", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "embedding failed")
+		return nil, embeddingError(err)
 	}
 
-	books, err := s.Repository.SearchBooks(ctx, pgvector.NewVector(vector))
+	diversity := s.Diversity
+	if opts.Diversity != nil {
+		diversity = *opts.Diversity
+	}
+
+	// Feedback lives in Postgres.
+	rerank := s.FeedbackWeight > 0 && s.Repository != nil
+
+	// Reranking and facets work on a wider candidate set.
+	candidates := searchLimit
+	if rerank || diversity > 0 || opts.Collapse == CollapseWork {
+		candidates *= rerankCandidateFactor
+	}
+	if len(opts.Facets) > 0 {
+		candidates = max(candidates, facetCandidates)
+	}
+
+	books, err := s.nearestBooks(ctx, repository.SearchBooksParams{
+		Genre:      filters.genre,
+		Author:     filters.author,
+		Language:   filters.language,
+		Decade:     filters.decade,
+		Embedding:  pgvector.NewVector(vector),
+		MaxResults: int32(candidates),
+	})
 	if err != nil {
 		s.Logger.Error("DB search failed", "query", query, "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "db search failed")
 		return nil, fmt.Errorf("db search failed: %w", err)
 	}
 
@@ -99,10 +638,182 @@ func (s *BookService) SearchBooks(ctx context.Context, query string) ([]BookWith
 			Title:       book.Title,
 			Description: book.Description,
 			Similarity:  sim,
+			Score:       sim,
+			WorkID:      book.WorkID.Int32,
+			embedding:   book.Embedding.Slice(),
+		})
+	}
+
+	var facets Facets
+	if len(opts.Facets) > 0 {
+		ids := make([]int32, 0, len(books))
+		for _, book := range books {
+			ids = append(ids, book.ID)
+		}
+		if facets, err = s.facetCounts(ctx, ids, opts.Facets); err != nil {
+			return nil, err
+		}
+	}
+
+	if rerank {
+		results = s.rerankByFeedback(ctx, results)
+	}
+	if opts.Collapse == CollapseWork {
+		results = collapseByWork(results, func(r BookWithSimilarity) int32 { return r.WorkID })
+	}
+	if diversity > 0 {
+		results = diversify(results, searchLimit, 1-diversity)
+	}
+	if len(results) > searchLimit {
+		results = results[:searchLimit]
+	}
+	if opts.Collapse == CollapseWork {
+		ids := make([]int32, 0, len(results))
+		for _, r := range results {
+			if r.WorkID != 0 {
+				ids = append(ids, r.WorkID)
+			}
+		}
+		editions, err := s.editions(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i, r := range results {
+			results[i].Editions = otherEditions(editions[r.WorkID], r.ID)
+		}
+	}
+
+	span.SetAttributes(attribute.Int("search.results", len(results)))
+	return &SearchResult{Results: results, Facets: facets}, nil
+}
+
+// coarseCandidateFactor is the number of candidates per result that binary
+// and matryoshka search re-rank with the full vectors.
+const coarseCandidateFactor = 4
+
+// An HNSW scan returns at most hnsw.ef_search rows; pgvector defaults it to
+// 40 and accepts up to 1000.
+const (
+	defaultEfSearch = 40
+	maxEfSearch     = 1000
+)
+
+// nearestBooks runs the vector search served by Vectors, or else by the
+// VectorIndex mode. Searches asking the index for more rows than the
+// default ef_search raise it for their transaction, and filtered searches
+// turn on iterative scans, or they would come back short.
+func (s *BookService) nearestBooks(ctx context.Context, arg repository.SearchBooksParams) ([]repository.SearchBooksRow, error) {
+	if s.Vectors != nil {
+		return s.Vectors.SearchBooks(ctx, arg)
+	}
+
+	candidates := arg.MaxResults
+	if s.VectorIndex == vectorindex.Binary || s.VectorIndex == vectorindex.Matryoshka {
+		candidates *= coarseCandidateFactor
+	}
+	filtered := arg.Genre.Valid || arg.Author.Valid || arg.Language.Valid || arg.Decade.Valid
+	if candidates <= defaultEfSearch && !filtered {
+		return s.searchIndex(ctx, s.Repository, arg, candidates)
+	}
+
+	var books []repository.SearchBooksRow
+	err := s.withTx(ctx, func(q Store) error {
+		if candidates > defaultEfSearch {
+			if err := q.SetHNSWEfSearch(ctx, min(candidates, maxEfSearch)); err != nil {
+				return err
+			}
+		}
+		if filtered {
+			if err := q.SetHNSWIterativeScan(ctx); err != nil {
+				return err
+			}
+		}
+		var err error
+		books, err = s.searchIndex(ctx, q, arg, candidates)
+		return err
+	})
+	return books, err
+}
+
+// searchIndex runs the search query of the VectorIndex mode on q; binary
+// and matryoshka take the given number of candidates from their index.
+func (s *BookService) searchIndex(ctx context.Context, q Store, arg repository.SearchBooksParams, candidates int32) ([]repository.SearchBooksRow, error) {
+	var books []repository.SearchBooksRow
+	switch s.VectorIndex {
+	case vectorindex.HalfVec:
+		rows, err := q.SearchBooksHalfvec(ctx, repository.SearchBooksHalfvecParams{
+			Genre:      arg.Genre,
+			Author:     arg.Author,
+			Language:   arg.Language,
+			Decade:     arg.Decade,
+			Embedding:  pgvector.NewHalfVector(arg.Embedding.Slice()),
+			MaxResults: arg.MaxResults,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			books = append(books, repository.SearchBooksRow(row))
+		}
+	case vectorindex.Binary:
+		rows, err := q.SearchBooksBinary(ctx, repository.SearchBooksBinaryParams{
+			Genre:      arg.Genre,
+			Author:     arg.Author,
+			Language:   arg.Language,
+			Decade:     arg.Decade,
+			Embedding:  arg.Embedding,
+			Candidates: candidates,
+			MaxResults: arg.MaxResults,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			books = append(books, repository.SearchBooksRow(row))
+		}
+	case vectorindex.Matryoshka:
+		rows, err := q.SearchBooksMatryoshka(ctx, repository.SearchBooksMatryoshkaParams{
+			Genre:      arg.Genre,
+			Author:     arg.Author,
+			Language:   arg.Language,
+			Decade:     arg.Decade,
+			Embedding:  arg.Embedding,
+			Candidates: candidates,
+			MaxResults: arg.MaxResults,
 		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			books = append(books, repository.SearchBooksRow(row))
+		}
+	default:
+		return q.SearchBooks(ctx, arg)
+	}
+	return books, nil
+}
+
+// fallbackSearch answers a semantic query with full-text matches. Full-text
+// rows carry no cosine score, so Similarity is left at zero.
+func (s *BookService) fallbackSearch(ctx context.Context, query string, opts SearchOptions) (*SearchResult, error) {
+	text, err := s.FullTextSearch(ctx, query, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return results, nil
+	results := make([]BookWithSimilarity, 0, len(text.Results))
+	for _, book := range text.Results {
+		results = append(results, BookWithSimilarity{
+			ID:          book.ID,
+			ISBN:        book.Isbn.String,
+			Title:       book.Title,
+			Description: book.Description,
+			WorkID:      book.WorkID.Int32,
+			Editions:    book.Editions,
+		})
+	}
+
+	return &SearchResult{Results: results, Facets: text.Facets, Degraded: true}, nil
 }
 
 // cosineSimilarity calculates cosine similarity between two float32 vectors.
@@ -113,9 +824,10 @@ func cosineSimilarity(a, b []float32) (float64, error) {
 
 	var dot, normA, normB float64
 	for i := range a {
-		dot += float64(a[i] * b[i])
-		normA += float64(a[i] * a[i])
-		normB += float64(b[i] * b[i])
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		normA += x * x
+		normB += y * y
 	}
 
 	if normA == 0 || normB == 0 {
@@ -125,22 +837,119 @@ func cosineSimilarity(a, b []float32) (float64, error) {
 	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), nil
 }
 
-func (s *BookService) FullTextSearch(ctx context.Context, query string) ([]repository.SearchBooksByTextRow, error) {
+type TextSearchResult struct {
+	Results []TextSearchHit `json:"results"`
+	Facets  Facets          `json:"facets,omitempty"`
+}
+
+// TextSearchHit is a full-text match, with the other editions of its work
+// if results are collapsed.
+type TextSearchHit struct {
+	repository.SearchBooksByTextRow
+	Editions []Edition `json:",omitempty"`
+}
+
+// textSearchLimit is the number of full-text results returned.
+const textSearchLimit = 10
+
+// FullTextSearch matches query against titles and descriptions. With a
+// language filter, the query is stemmed for that language and only books in
+// it are searched; otherwise the query uses DefaultLanguage and all books
+// are searched.
+func (s *BookService) FullTextSearch(ctx context.Context, query string, opts SearchOptions) (*TextSearchResult, error) {
+	ctx, span := tracer.Start(ctx, "BookService.FullTextSearch")
+	defer span.End()
+
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
 
-	if query == "" {
-		return nil, fmt.Errorf("search query cannot be empty")
+	websearch, prefixes := splitTextQuery(query)
+	if websearch == "" && prefixes == "" {
+		return nil, Validation("empty_query", "search query cannot be empty")
+	}
+
+	filters, err := opts.Filters.params()
+	if err != nil {
+		return nil, err
+	}
+	config := s.defaultLanguage()
+	if filters.language.Valid {
+		config = filters.language.String
+	}
+
+	candidates := textSearchLimit
+	if opts.Collapse == CollapseWork {
+		candidates *= rerankCandidateFactor
+	}
+	if len(opts.Facets) > 0 {
+		candidates = max(candidates, facetCandidates)
 	}
 
-	books, err := s.Repository.SearchBooksByText(ctx, query)
+	titleOptions, options := s.Highlight.headlineOptions()
+	books, err := s.Repository.SearchBooksByText(ctx, repository.SearchBooksByTextParams{
+		Config:               config,
+		Query:                websearch,
+		Prefixes:             prefixes,
+		Language:             filters.language,
+		Genre:                filters.genre,
+		Author:               filters.author,
+		Decade:               filters.decade,
+		MaxResults:           int32(candidates),
+		TitleHeadlineOptions: titleOptions,
+		HeadlineOptions:      options,
+		EscapeHtml:           s.Highlight.html(),
+	})
 	if err != nil {
 		s.Logger.Error("Full-text search failed", "query", query, "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "full-text search failed")
 		return nil, fmt.Errorf("full-text search failed: %w", err)
 	}
 
-	return books, nil
+	result := &TextSearchResult{Results: make([]TextSearchHit, 0, len(books))}
+	for _, book := range books {
+		result.Results = append(result.Results, TextSearchHit{SearchBooksByTextRow: book})
+	}
+	if len(opts.Facets) > 0 {
+		ids := make([]int32, 0, len(books))
+		for _, book := range books {
+			ids = append(ids, book.ID)
+		}
+		if result.Facets, err = s.facetCounts(ctx, ids, opts.Facets); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Collapse == CollapseWork {
+		result.Results = collapseByWork(result.Results, func(h TextSearchHit) int32 { return h.WorkID.Int32 })
+	}
+	if len(result.Results) > textSearchLimit {
+		result.Results = result.Results[:textSearchLimit]
+	}
+	if opts.Collapse == CollapseWork {
+		ids := make([]int32, 0, len(result.Results))
+		for _, hit := range result.Results {
+			if hit.WorkID.Valid {
+				ids = append(ids, hit.WorkID.Int32)
+			}
+		}
+		editions, err := s.editions(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i, hit := range result.Results {
+			result.Results[i].Editions = otherEditions(editions[hit.WorkID.Int32], hit.ID)
+		}
+	}
+	return result, nil
+}
+
+// embeddingError converts an embedder failure into a client-safe domain error.
+func embeddingError(err error) error {
+	if embed.IsRateLimited(err) {
+		return RateLimited("embedding_rate_limited", embeddingFailures["embedding_rate_limited"], err)
+	}
+	return Unavailable("embedding_unavailable", embeddingFailures["embedding_unavailable"], err)
 }
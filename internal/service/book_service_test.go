@@ -0,0 +1,595 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/nmdra/Semantic-Search/internal/embed"
+	"github.com/nmdra/Semantic-Search/internal/repository"
+	"github.com/nmdra/Semantic-Search/internal/vectorindex"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	dune      = BookInput{ISBN: "9780441172719", Title: "Dune", Description: "Politics and spice on a desert planet."}
+	hobbit    = BookInput{ISBN: "9780547928227", Title: "The Hobbit", Description: "A burglar, thirteen dwarves and a dragon."}
+	pride     = BookInput{ISBN: "9780141439518", Title: "Pride and Prejudice", Description: "A novel of manners in Regency society."}
+	errBroken = errors.New("connection refused")
+)
+
+func requireCode(t *testing.T, err error, code string) {
+	t.Helper()
+	domainErr, ok := AsError(err)
+	require.True(t, ok, "want a domain error, got %v", err)
+	assert.Equal(t, code, domainErr.Code)
+}
+
+func TestAddBook(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Stores the book with its embedding, work and events", func(t *testing.T) {
+		s, store, embedder := newTestService()
+
+		require.NoError(t, s.AddBook(ctx, BookInput{
+			ISBN:          "0-441-17271-7",
+			Title:         " Dune ",
+			Description:   dune.Description,
+			Genres:        []string{"Science Fiction"},
+			PublishedYear: 1965,
+		}))
+
+		require.Len(t, store.books, 1)
+		book := store.books[0]
+		assert.Equal(t, dune.ISBN, book.Isbn.String)
+		assert.Equal(t, "Dune", book.Title)
+		assert.Equal(t, testBook(dune.ISBN, dune.Title, dune.Description).Embedding, book.Embedding)
+		assert.Equal(t, "english", book.Language)
+		assert.Equal(t, []string{"science fiction"}, book.Genres)
+		assert.Equal(t, pgtype.Int4{Int32: 1965, Valid: true}, book.PublishedYear)
+		assert.True(t, book.ContentHash.Valid)
+		assert.Equal(t, pgtype.Int4{Int32: 1, Valid: true}, book.WorkID)
+		assert.Equal(t, []string{EventBookCreated, EventBookEmbedded}, store.events)
+		assert.Equal(t, 1, embedder.calls)
+	})
+
+	t.Run("Groups editions into one work", func(t *testing.T) {
+		s, store, _ := newTestService()
+
+		require.NoError(t, s.AddBook(ctx, dune))
+		require.NoError(t, s.AddBook(ctx, BookInput{ISBN: "9780340960196", Title: "Dune (Anniversary Edition)", Description: dune.Description}))
+		require.NoError(t, s.AddBook(ctx, hobbit))
+
+		require.Len(t, store.books, 3)
+		assert.Equal(t, store.books[0].WorkID, store.books[1].WorkID)
+		assert.NotEqual(t, store.books[0].WorkID, store.books[2].WorkID)
+		assert.Len(t, store.works, 2)
+		assert.Zero(t, store.shortSearches)
+	})
+
+	t.Run("Matches works on the matryoshka index", func(t *testing.T) {
+		s, store, _ := newTestService()
+		s.Works.Matryoshka = true
+
+		require.NoError(t, s.AddBook(ctx, dune))
+		require.NoError(t, s.AddBook(ctx, BookInput{ISBN: "9780340960196", Title: "Dune (Anniversary Edition)", Description: dune.Description}))
+
+		assert.Equal(t, store.books[0].WorkID, store.books[1].WorkID)
+		assert.Equal(t, 2, store.shortSearches)
+	})
+
+	t.Run("Rejects invalid input without embedding it", func(t *testing.T) {
+		s, store, embedder := newTestService()
+
+		err := s.AddBook(ctx, BookInput{ISBN: "123", Title: "Dune", Description: dune.Description})
+
+		require.ErrorIs(t, err, ErrValidation)
+		assert.Zero(t, embedder.calls)
+		assert.Empty(t, store.books)
+	})
+
+	t.Run("Rejects a duplicate ISBN without embedding it", func(t *testing.T) {
+		s, store, embedder := newTestService(testBook(dune.ISBN, dune.Title, dune.Description))
+
+		err := s.AddBook(ctx, dune)
+
+		require.ErrorIs(t, err, ErrConflict)
+		requireCode(t, err, "book_exists")
+		assert.Zero(t, embedder.calls)
+		assert.Len(t, store.books, 1)
+		assert.Empty(t, store.events)
+	})
+
+	t.Run("Rejects a duplicate stored while embedding", func(t *testing.T) {
+		s, store, embedder := newTestService()
+		embedder.hook = func() { store.add(testBook(dune.ISBN, dune.Title, dune.Description)) }
+
+		err := s.AddBook(ctx, dune)
+
+		require.ErrorIs(t, err, ErrConflict)
+		requireCode(t, err, "book_exists")
+		assert.Len(t, store.books, 1)
+	})
+
+	t.Run("Reports embedding failures as unavailable", func(t *testing.T) {
+		s, store, embedder := newTestService()
+		embedder.err = errors.New("model overloaded")
+
+		err := s.AddBook(ctx, dune)
+
+		require.ErrorIs(t, err, ErrUnavailable)
+		requireCode(t, err, "embedding_unavailable")
+		assert.Empty(t, store.books)
+	})
+
+	t.Run("Reports rate limiting", func(t *testing.T) {
+		s, _, embedder := newTestService()
+		embedder.err = &embed.StatusError{StatusCode: http.StatusTooManyRequests, Err: errors.New("quota exceeded")}
+
+		err := s.AddBook(ctx, dune)
+
+		require.ErrorIs(t, err, ErrRateLimited)
+		requireCode(t, err, "embedding_rate_limited")
+	})
+
+	t.Run("Stops when cancelled", func(t *testing.T) {
+		s, store, _ := newTestService()
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := s.AddBook(ctx, dune)
+
+		require.ErrorIs(t, err, context.Canceled)
+		_, isDomain := AsError(err)
+		assert.False(t, isDomain, "cancellation is not an upstream failure")
+		assert.Empty(t, store.books)
+	})
+
+	t.Run("Wraps store failures", func(t *testing.T) {
+		s, store, _ := newTestService()
+		store.err = errBroken
+
+		err := s.AddBook(ctx, dune)
+
+		require.ErrorIs(t, err, errBroken)
+		_, isDomain := AsError(err)
+		assert.False(t, isDomain)
+	})
+}
+
+func TestUpsertBookAsync(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Queues a new book", func(t *testing.T) {
+		s, store, embedder := newTestService()
+
+		result, err := s.UpsertBookAsync(ctx, dune)
+
+		require.NoError(t, err)
+		assert.Equal(t, UpsertResult{ID: 1, ISBN: dune.ISBN, Outcome: UpsertCreated, EmbeddingStatus: EmbeddingPending}, result)
+		assert.Equal(t, []int32{1}, store.jobs)
+		assert.Equal(t, []string{EventBookCreated}, store.events)
+		assert.Zero(t, embedder.calls, "the worker embeds the book")
+	})
+
+	t.Run("Clears the stale embedding of changed content and requeues it", func(t *testing.T) {
+		stored := testBook(dune.ISBN, dune.Title, dune.Description)
+		stored.WorkID = pgtype.Int4{Int32: 7, Valid: true}
+		s, store, _ := newTestService(stored)
+		store.jobs = []int32{1}
+
+		result, err := s.UpsertBookAsync(ctx, BookInput{ISBN: dune.ISBN, Title: dune.Title, Description: hobbit.Description})
+
+		require.NoError(t, err)
+		assert.Equal(t, UpsertUpdated, result.Outcome)
+		assert.Equal(t, EmbeddingPending, result.EmbeddingStatus)
+		require.Len(t, store.books, 1)
+		book := store.books[0]
+		assert.Equal(t, EmbeddingPending, book.EmbeddingStatus)
+		assert.Nil(t, book.Embedding.Slice(), "the old vector no longer matches the description")
+		assert.Equal(t, stored.WorkID, book.WorkID)
+		assert.Equal(t, []int32{1}, store.jobs, "one job, for the new description")
+		assert.Equal(t, []string{EventBookUpdated}, store.events)
+
+		res, err := s.SearchBooks(ctx, "desert", SearchOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, res.Results)
+	})
+
+	t.Run("Keeps the embedding of unchanged content", func(t *testing.T) {
+		stored := testBook(dune.ISBN, dune.Title, dune.Description)
+		stored.ContentHash = pgtype.Text{String: contentHash(dune.Description), Valid: true}
+		s, store, _ := newTestService(stored)
+
+		result, err := s.UpsertBookAsync(ctx, dune)
+
+		require.NoError(t, err)
+		assert.Equal(t, UpsertUnchanged, result.Outcome)
+		assert.Empty(t, result.EmbeddingStatus)
+		assert.NotNil(t, store.books[0].Embedding.Slice())
+		assert.Empty(t, store.jobs)
+	})
+
+	t.Run("Requeues a book whose embedding failed", func(t *testing.T) {
+		stored := testBook(dune.ISBN, dune.Title, dune.Description)
+		stored.ContentHash = pgtype.Text{String: contentHash(dune.Description), Valid: true}
+		stored.Embedding = pgvector.Vector{}
+		stored.EmbeddingStatus = EmbeddingFailed
+		s, store, _ := newTestService(stored)
+
+		result, err := s.UpsertBookAsync(ctx, dune)
+
+		require.NoError(t, err)
+		assert.Equal(t, UpsertUpdated, result.Outcome)
+		assert.Equal(t, EmbeddingPending, result.EmbeddingStatus)
+		assert.Equal(t, EmbeddingPending, store.books[0].EmbeddingStatus)
+		assert.Equal(t, []int32{1}, store.jobs)
+	})
+}
+
+func TestGetBookStatus(t *testing.T) {
+	failed := func(isbn, embeddingError string) repository.Book {
+		b := testBook(isbn, "Failed", "")
+		b.EmbeddingStatus = EmbeddingFailed
+		b.EmbeddingError = pgtype.Text{String: embeddingError, Valid: true}
+		return b
+	}
+	s, _, _ := newTestService(
+		testBook(dune.ISBN, dune.Title, dune.Description),
+		failed(hobbit.ISBN, "embedding_rate_limited"),
+		failed(pride.ISBN, "Error 403, Message: API key revoked"),
+	)
+
+	tests := []struct {
+		isbn string
+		want BookStatus
+	}{
+		{dune.ISBN, BookStatus{ID: 1, ISBN: dune.ISBN, Title: dune.Title, Status: EmbeddingReady}},
+		{hobbit.ISBN, BookStatus{ID: 2, ISBN: hobbit.ISBN, Title: "Failed", Status: EmbeddingFailed,
+			Code: "embedding_rate_limited", Error: "embedding provider is rate limiting requests, retry later"}},
+		{pride.ISBN, BookStatus{ID: 3, ISBN: pride.ISBN, Title: "Failed", Status: EmbeddingFailed,
+			Code: "embedding_failed", Error: "the book could not be embedded"}},
+	}
+	for _, tt := range tests {
+		got, err := s.GetBookStatus(context.Background(), tt.isbn)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestSearchBooks(t *testing.T) {
+	ctx := context.Background()
+	catalogue := func() []repository.Book {
+		return []repository.Book{
+			testBook(dune.ISBN, dune.Title, dune.Description),
+			testBook(hobbit.ISBN, hobbit.Title, hobbit.Description),
+			testBook(pride.ISBN, pride.Title, pride.Description),
+		}
+	}
+
+	t.Run("Ranks by cosine similarity", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+
+		res, err := s.SearchBooks(ctx, "a dragon's hoard, guarded by a dragon", SearchOptions{})
+
+		require.NoError(t, err)
+		require.Len(t, res.Results, 3)
+		assert.Equal(t, "The Hobbit", res.Results[0].Title)
+		assert.Equal(t, hobbit.ISBN, res.Results[0].ISBN)
+		assert.InDelta(t, 1, res.Results[0].Similarity, 0.01)
+		for i, r := range res.Results {
+			assert.Equal(t, r.Similarity, r.Score, "no feedback blended in")
+			if i > 0 {
+				assert.LessOrEqual(t, r.Similarity, res.Results[i-1].Similarity)
+			}
+		}
+		assert.False(t, res.Degraded)
+		assert.Equal(t, int32(searchLimit), store.lastSearch.MaxResults)
+	})
+
+	t.Run("Passes filters to the store", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+
+		_, err := s.SearchBooks(ctx, "dragon", SearchOptions{Filters: Filters{
+			Genre:    " Science  Fiction",
+			Author:   "Frank Herbert",
+			Language: "en",
+			Decade:   1960,
+		}})
+
+		require.NoError(t, err)
+		assert.Equal(t, pgtype.Text{String: "science fiction", Valid: true}, store.lastSearch.Genre)
+		assert.Equal(t, pgtype.Text{String: "Frank Herbert", Valid: true}, store.lastSearch.Author)
+		assert.Equal(t, pgtype.Text{String: "english", Valid: true}, store.lastSearch.Language)
+		assert.Equal(t, pgtype.Int4{Int32: 1960, Valid: true}, store.lastSearch.Decade)
+		assert.True(t, store.iterativeScan, "filters must not empty the index scan")
+	})
+
+	t.Run("Rejects an unsupported language before embedding", func(t *testing.T) {
+		s, _, embedder := newTestService(catalogue()...)
+
+		_, err := s.SearchBooks(ctx, "dragon", SearchOptions{Filters: Filters{Language: "xx"}})
+
+		require.ErrorIs(t, err, ErrValidation)
+		assert.Zero(t, embedder.calls)
+	})
+
+	t.Run("Skips books whose embedding cannot be compared", func(t *testing.T) {
+		odd := testBook("9780060850524", "Brave New World", "A dragon in a different space.")
+		odd.Embedding = pgvector.NewVector([]float32{1, 0})
+		s, _, _ := newTestService(append(catalogue(), odd)...)
+
+		res, err := s.SearchBooks(ctx, "dragon", SearchOptions{})
+
+		require.NoError(t, err)
+		require.Len(t, res.Results, 3)
+		for _, r := range res.Results {
+			assert.NotEqual(t, odd.Title, r.Title)
+		}
+	})
+
+	t.Run("Collapses editions of a work", func(t *testing.T) {
+		books := catalogue()
+		reissue := testBook("9780340960196", "Dune (Anniversary Edition)", dune.Description)
+		books = append(books, reissue)
+		books[0].WorkID = pgtype.Int4{Int32: 7, Valid: true}
+		books[3].WorkID = pgtype.Int4{Int32: 7, Valid: true}
+		s, _, _ := newTestService(books...)
+
+		res, err := s.SearchBooks(ctx, "desert", SearchOptions{Collapse: CollapseWork})
+
+		require.NoError(t, err)
+		require.Len(t, res.Results, 3)
+		assert.Equal(t, int32(7), res.Results[0].WorkID)
+		require.Len(t, res.Results[0].Editions, 1)
+		assert.NotEqual(t, res.Results[0].ISBN, res.Results[0].Editions[0].ISBN)
+	})
+
+	t.Run("Raises ef_search to the candidates asked of the index", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+		s.VectorIndex = vectorindex.Binary
+
+		_, err := s.SearchBooks(ctx, "dragon", SearchOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(searchLimit*coarseCandidateFactor), store.lastSearch.MaxResults)
+		assert.Zero(t, store.efSearch, "the default suffices")
+		assert.False(t, store.iterativeScan)
+
+		diversity := 0.5
+		_, err = s.SearchBooks(ctx, "dragon", SearchOptions{Diversity: &diversity})
+		require.NoError(t, err)
+		want := int32(searchLimit * rerankCandidateFactor * coarseCandidateFactor)
+		assert.Equal(t, want, store.lastSearch.MaxResults)
+		assert.Equal(t, want, store.efSearch)
+	})
+
+	t.Run("Lets a request turn off the default diversity", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+		s.Diversity = 0.5
+
+		_, err := s.SearchBooks(ctx, "dragon", SearchOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(searchLimit*rerankCandidateFactor), store.lastSearch.MaxResults, "the default applies")
+
+		off := 0.0
+		_, err = s.SearchBooks(ctx, "dragon", SearchOptions{Diversity: &off})
+		require.NoError(t, err)
+		assert.Equal(t, int32(searchLimit), store.lastSearch.MaxResults)
+	})
+
+	t.Run("Reports embedding failures as unavailable", func(t *testing.T) {
+		s, store, embedder := newTestService(catalogue()...)
+		embedder.err = errors.New("model overloaded")
+
+		_, err := s.SearchBooks(ctx, "dragon", SearchOptions{})
+
+		require.ErrorIs(t, err, ErrUnavailable)
+		assert.Zero(t, store.lastSearch.MaxResults, "store not queried")
+	})
+
+	t.Run("Reports rate limiting", func(t *testing.T) {
+		s, _, embedder := newTestService(catalogue()...)
+		embedder.err = &embed.StatusError{StatusCode: http.StatusTooManyRequests}
+
+		_, err := s.SearchBooks(ctx, "dragon", SearchOptions{})
+
+		require.ErrorIs(t, err, ErrRateLimited)
+	})
+
+	t.Run("Falls back to full-text search", func(t *testing.T) {
+		s, _, embedder := newTestService(catalogue()...)
+		s.FallbackToText = true
+		embedder.err = errors.New("model overloaded")
+
+		res, err := s.SearchBooks(ctx, "dragon", SearchOptions{})
+
+		require.NoError(t, err)
+		assert.True(t, res.Degraded)
+		require.Len(t, res.Results, 1)
+		assert.Equal(t, "The Hobbit", res.Results[0].Title)
+		assert.Zero(t, res.Results[0].Similarity, "text matches have no cosine score")
+	})
+
+	t.Run("Stops when cancelled", func(t *testing.T) {
+		s, _, embedder := newTestService(catalogue()...)
+		s.FallbackToText = true
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := s.SearchBooks(ctx, "dragon", SearchOptions{})
+
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, embedder.calls)
+	})
+
+	t.Run("Does not fall back when cancelled while embedding", func(t *testing.T) {
+		s, store, embedder := newTestService(catalogue()...)
+		s.FallbackToText = true
+		ctx, cancel := context.WithCancel(ctx)
+		embedder.hook = cancel
+
+		_, err := s.SearchBooks(ctx, "dragon", SearchOptions{})
+
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, store.lastTextSearch.Query)
+	})
+
+	t.Run("Wraps store failures", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+		store.err = errBroken
+
+		_, err := s.SearchBooks(ctx, "dragon", SearchOptions{})
+
+		require.ErrorIs(t, err, errBroken)
+		_, isDomain := AsError(err)
+		assert.False(t, isDomain)
+	})
+}
+
+func TestFullTextSearch(t *testing.T) {
+	ctx := context.Background()
+	catalogue := func() []repository.Book {
+		spanish := testBook("9780060883287", "Cien años de soledad", "La historia de la familia Buendía en Macondo.")
+		spanish.Language = "spanish"
+		return []repository.Book{
+			testBook(dune.ISBN, dune.Title, dune.Description),
+			testBook(hobbit.ISBN, hobbit.Title, hobbit.Description),
+			testBook(pride.ISBN, pride.Title, pride.Description),
+			spanish,
+		}
+	}
+
+	t.Run("Matches words and prefixes", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+
+		res, err := s.FullTextSearch(ctx, "novel soc*", SearchOptions{})
+
+		require.NoError(t, err)
+		require.Len(t, res.Results, 1)
+		assert.Equal(t, pride.ISBN, res.Results[0].Isbn.String)
+		assert.Equal(t, "novel", store.lastTextSearch.Query)
+		assert.Equal(t, "soc:*", store.lastTextSearch.Prefixes)
+		assert.Equal(t, "english", store.lastTextSearch.Config)
+		assert.False(t, store.lastTextSearch.Language.Valid, "all languages are searched")
+		assert.Contains(t, store.lastTextSearch.HeadlineOptions, `StartSel="<b>"`)
+		assert.True(t, store.lastTextSearch.EscapeHtml, "descriptions are escaped for the default <b> markers")
+	})
+
+	t.Run("Searches one language with its configuration", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+
+		res, err := s.FullTextSearch(ctx, "familia", SearchOptions{Filters: Filters{Language: "es"}})
+
+		require.NoError(t, err)
+		require.Len(t, res.Results, 1)
+		assert.Equal(t, "Cien años de soledad", res.Results[0].Title)
+		assert.Equal(t, "spanish", store.lastTextSearch.Config)
+		assert.Equal(t, pgtype.Text{String: "spanish", Valid: true}, store.lastTextSearch.Language)
+	})
+
+	t.Run("Uses the default language", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+		s.DefaultLanguage = "simple"
+
+		_, err := s.FullTextSearch(ctx, "dragon", SearchOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, "simple", store.lastTextSearch.Config)
+	})
+
+	t.Run("Returns at most the limit", func(t *testing.T) {
+		var books []repository.Book
+		for i := range 2 * textSearchLimit {
+			books = append(books, testBook(fmt.Sprintf("isbn-%d", i), fmt.Sprintf("Dragons %d", i), "Yet another dragon."))
+		}
+		s, _, _ := newTestService(books...)
+
+		res, err := s.FullTextSearch(ctx, "dragon", SearchOptions{})
+
+		require.NoError(t, err)
+		assert.Len(t, res.Results, textSearchLimit)
+	})
+
+	t.Run("Rejects an empty query", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+
+		for _, q := range []string{"", "   "} {
+			_, err := s.FullTextSearch(ctx, q, SearchOptions{})
+			require.ErrorIs(t, err, ErrValidation)
+			requireCode(t, err, "empty_query")
+		}
+		assert.Empty(t, store.lastTextSearch.Config, "store not queried")
+	})
+
+	t.Run("Stops when cancelled", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := s.FullTextSearch(ctx, "dragon", SearchOptions{})
+
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, store.lastTextSearch.Config)
+	})
+
+	t.Run("Wraps store failures", func(t *testing.T) {
+		s, store, _ := newTestService(catalogue()...)
+		store.err = errBroken
+
+		_, err := s.FullTextSearch(ctx, "dragon", SearchOptions{})
+
+		require.ErrorIs(t, err, errBroken)
+		_, isDomain := AsError(err)
+		assert.False(t, isDomain)
+	})
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{"Identical", []float32{1, 2, 3}, []float32{1, 2, 3}, 1},
+		{"Scaled", []float32{1, 2, 3}, []float32{2, 4, 6}, 1},
+		{"Opposite", []float32{1, -2}, []float32{-1, 2}, -1},
+		{"Orthogonal", []float32{1, 0}, []float32{0, 1}, 0},
+		{"Half", []float32{1, 0}, []float32{1, float32(math.Sqrt(3))}, 0.5},
+		// Products overflow float32 at this magnitude.
+		{"Large components", []float32{3e20, 4e20}, []float32{3e20, 4e20}, 1},
+		{"Tiny components", []float32{1e-30, 0}, []float32{1e-30, 0}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cosineSimilarity(tt.a, tt.b)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want, got, 1e-6)
+		})
+	}
+
+	errTests := []struct {
+		name string
+		a, b []float32
+	}{
+		{"Different lengths", []float32{1, 2}, []float32{1, 2, 3}},
+		{"Zero vector", []float32{0, 0}, []float32{1, 2}},
+		{"Both zero", []float32{0, 0}, []float32{0, 0}},
+		{"Empty", []float32{}, []float32{}},
+		{"Nil and empty", nil, []float32{}},
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := cosineSimilarity(tt.a, tt.b)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind classifies a domain error independently of transport. The API layer
+// maps each kind to an HTTP status.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindUnavailable
+	KindRateLimited
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindValidation:
+		return "validation failed"
+	case KindUnavailable:
+		return "upstream unavailable"
+	case KindRateLimited:
+		return "rate limited"
+	default:
+		return "internal error"
+	}
+}
+
+// Sentinels for errors.Is; any *Error of the same kind matches.
+var (
+	ErrNotFound    = &Error{Kind: KindNotFound}
+	ErrConflict    = &Error{Kind: KindConflict}
+	ErrValidation  = &Error{Kind: KindValidation}
+	ErrUnavailable = &Error{Kind: KindUnavailable}
+	ErrRateLimited = &Error{Kind: KindRateLimited}
+)
+
+// Error is a domain error that is safe to show to clients. Code is a stable,
+// machine-readable identifier; Message is human-readable and must not leak
+// internals. The underlying cause is kept in Err for logging only.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError // per-field details for validation errors
+	Err     error
+}
+
+// FieldError describes why a single input field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Kind.String()
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is matches sentinel errors by kind.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == "" && t.Kind == e.Kind
+}
+
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func Validation(code, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+func Unavailable(code, message string, cause error) *Error {
+	return &Error{Kind: KindUnavailable, Code: code, Message: message, Err: cause}
+}
+
+func RateLimited(code, message string, cause error) *Error {
+	return &Error{Kind: KindRateLimited, Code: code, Message: message, Err: cause}
+}
+
+// AsError returns the domain error in err's chain, if any.
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
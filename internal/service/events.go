@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+)
+
+// Book change events delivered to webhook subscribers.
+const (
+	EventBookCreated  = "book.created"
+	EventBookUpdated  = "book.updated"
+	EventBookDeleted  = "book.deleted"
+	EventBookEmbedded = "book.embedded"
+)
+
+// EventTypes lists every event a webhook can subscribe to.
+var EventTypes = []string{EventBookCreated, EventBookUpdated, EventBookDeleted, EventBookEmbedded}
+
+// BookEvent is the data carried by book change events.
+type BookEvent struct {
+	ID              int32  `json:"id"`
+	ISBN            string `json:"isbn"`
+	Title           string `json:"title"`
+	EmbeddingStatus string `json:"embedding_status,omitempty"`
+}
+
+// EmitEvent writes an event to the webhook outbox. Pass the queries of the
+// transaction that made the change, so the event is recorded if and only if
+// the change commits; the webhook dispatcher delivers it afterwards.
+func EmitEvent(ctx context.Context, q EventStore, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	_, err = q.EnqueueWebhookEvent(ctx, repository.EnqueueWebhookEventParams{
+		EventType: eventType,
+		Payload:   payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nmdra/Semantic-Search/internal/lang"
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Facets that can be counted over search candidates.
+const (
+	FacetGenre    = "genre"
+	FacetAuthor   = "author"
+	FacetLanguage = "language"
+	FacetDecade   = "decade"
+)
+
+var FacetNames = []string{FacetGenre, FacetAuthor, FacetLanguage, FacetDecade}
+
+const (
+	// facetCandidates is how many top matches facets are counted over.
+	facetCandidates = 50
+	// maxFacetValues caps the values returned per facet, most frequent first.
+	maxFacetValues = 10
+)
+
+// Filters narrow a search to books with the given metadata. Zero values
+// match everything.
+type Filters struct {
+	Genre    string
+	Author   string
+	Language string // ISO 639-1 code or text search configuration name
+	Decade   int    // first year of the decade, e.g. 1990
+}
+
+// SearchOptions are the drill-down filters and requested facets of a search.
+type SearchOptions struct {
+	Filters Filters
+	Facets  []string
+
+	// Diversity trades relevance for novelty in semantic results, from 0
+	// (rank by relevance only) to 1 (avoid redundancy at any cost). Nil
+	// uses BookService.Diversity, so a request can still turn it off with 0.
+	Diversity *float64
+
+	// Collapse set to CollapseWork returns one result per work, with the
+	// other editions nested in it.
+	Collapse string
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets maps each requested facet to its most frequent values.
+type Facets map[string][]FacetValue
+
+// ParseFacets parses a comma-separated list of facet names.
+func ParseFacets(s string) ([]string, error) {
+	var names []string
+	for name := range strings.SplitSeq(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || slices.Contains(names, name) {
+			continue
+		}
+		if !slices.Contains(FacetNames, name) {
+			return nil, Validation("invalid_facet", fmt.Sprintf("unknown facet %q; use %s", name, strings.Join(FacetNames, ", ")))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// ParseDecade parses a decade given as its first year, with or without a
+// trailing "s" ("1990", "1990s").
+func ParseDecade(s string) (int, error) {
+	year, err := strconv.Atoi(strings.TrimSuffix(s, "s"))
+	if err != nil || year%10 != 0 {
+		return 0, Validation("invalid_decade", "decade must be a year ending in 0, such as 1990")
+	}
+	return year, nil
+}
+
+// filterParams converts filters to nullable query parameters; a zero filter
+// becomes NULL and matches every book.
+type filterParams struct {
+	genre, author, language pgtype.Text
+	decade                  pgtype.Int4
+}
+
+func (f Filters) params() (filterParams, error) {
+	var p filterParams
+	if genre := normalizeGenre(f.Genre); genre != "" {
+		p.genre = pgtype.Text{String: genre, Valid: true}
+	}
+	if author := strings.TrimSpace(f.Author); author != "" {
+		p.author = pgtype.Text{String: author, Valid: true}
+	}
+	if f.Language != "" {
+		config, ok := lang.Config(f.Language)
+		if !ok {
+			err := Validation("unsupported_language", "search language is not supported")
+			err.Fields = []FieldError{languageError(f.Language)}
+			return filterParams{}, err
+		}
+		p.language = pgtype.Text{String: config, Valid: true}
+	}
+	if f.Decade != 0 {
+		p.decade = pgtype.Int4{Int32: int32(f.Decade), Valid: true}
+	}
+	return p, nil
+}
+
+// facetCounts counts the requested facets over the candidate books.
+func (s *BookService) facetCounts(ctx context.Context, ids []int32, names []string) (Facets, error) {
+	facets := make(Facets, len(names))
+	for _, name := range names {
+		facets[name] = []FacetValue{}
+	}
+	if len(ids) == 0 {
+		return facets, nil
+	}
+
+	rows, err := s.Repository.BookFacetCounts(ctx, repository.BookFacetCountsParams{Ids: ids, Facets: names})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count facets: %w", err)
+	}
+	// Rows are ordered by count within each facet.
+	for _, row := range rows {
+		if len(facets[row.Facet]) < maxFacetValues {
+			facets[row.Facet] = append(facets[row.Facet], FacetValue{Value: row.Value, Count: row.Books})
+		}
+	}
+	return facets, nil
+}
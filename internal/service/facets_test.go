@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFacets(t *testing.T) {
+	got, err := ParseFacets(" Genre,author,,genre ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{FacetGenre, FacetAuthor}, got)
+
+	_, err = ParseFacets("genre,publisher")
+	require.ErrorIs(t, err, ErrValidation)
+}
+
+func TestParseDecade(t *testing.T) {
+	for _, in := range []string{"1990", "1990s"} {
+		got, err := ParseDecade(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, 1990, got, in)
+	}
+
+	for _, in := range []string{"1994", "nineties", ""} {
+		_, err := ParseDecade(in)
+		assert.ErrorIs(t, err, ErrValidation, in)
+	}
+}
+
+func TestFilterParams(t *testing.T) {
+	t.Run("Zero filters match everything", func(t *testing.T) {
+		got, err := Filters{}.params()
+		require.NoError(t, err)
+		assert.Equal(t, filterParams{}, got)
+	})
+
+	t.Run("Normalises filters", func(t *testing.T) {
+		got, err := Filters{Genre: " Science  Fiction", Author: "Ursula K. Le Guin ", Language: "en", Decade: 1970}.params()
+		require.NoError(t, err)
+		assert.Equal(t, filterParams{
+			genre:    pgtype.Text{String: "science fiction", Valid: true},
+			author:   pgtype.Text{String: "Ursula K. Le Guin", Valid: true},
+			language: pgtype.Text{String: "english", Valid: true},
+			decade:   pgtype.Int4{Int32: 1970, Valid: true},
+		}, got)
+	})
+
+	t.Run("Rejects an unsupported language", func(t *testing.T) {
+		_, err := Filters{Language: "tlh"}.params()
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+}
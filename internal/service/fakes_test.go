@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pgvector/pgvector-go"
+)
+
+// fakeEmbedder embeds text as counts of a few topic words, so related
+// descriptions land close together without calling a model. If err is set
+// every call fails with it; hook, if set, runs before each call.
+type fakeEmbedder struct {
+	err   error
+	hook  func()
+	calls int
+}
+
+var keywords = []string{"desert", "dragon", "manners", "society"}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
+	e.calls++
+	if e.hook != nil {
+		e.hook()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	vec := make([]float32, len(keywords)+1)
+	vec[len(keywords)] = 0.1 // keeps unrelated text off the zero vector
+	for _, word := range strings.Fields(strings.ToLower(input)) {
+		for i, k := range keywords {
+			if strings.Trim(word, ".,") == k {
+				vec[i]++
+			}
+		}
+	}
+	return vec, nil
+}
+
+// fakeStore is an in-memory Store covering what AddBook, SearchBooks and
+// FullTextSearch use; the other methods panic through the nil embedded
+// Store. If err is set every implemented method fails with it.
+type fakeStore struct {
+	Store
+
+	err    error
+	books  []repository.Book
+	works  []repository.Work
+	events []string // event types, in order
+	jobs   []int32  // IDs of books queued for embedding
+
+	feedback   map[string]repository.GetFeedbackCountsRow // by ISBN
+	duplicates []repository.SuspectedDuplicatesRow        // in book ID order
+
+	lastSearch     repository.SearchBooksParams
+	lastTextSearch repository.SearchBooksByTextParams
+	efSearch       int32 // last hnsw.ef_search set
+	iterativeScan  bool
+	shortSearches  int // work candidate searches on the matryoshka index
+}
+
+func newTestService(books ...repository.Book) (*BookService, *fakeStore, *fakeEmbedder) {
+	store := &fakeStore{}
+	for _, b := range books {
+		store.add(b)
+	}
+	embedder := &fakeEmbedder{}
+	s := &BookService{
+		Embedder:   embedder,
+		Repository: store,
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	return s, store, embedder
+}
+
+// add stores b as is, numbering it if it has no ID.
+func (f *fakeStore) add(b repository.Book) {
+	if b.ID == 0 {
+		b.ID = int32(len(f.books) + 1)
+	}
+	if b.Genres == nil {
+		b.Genres = []string{}
+	}
+	f.books = append(f.books, b)
+}
+
+func (f *fakeStore) book(isbn string) (repository.Book, bool) {
+	for _, b := range f.books {
+		if b.Isbn.String == isbn {
+			return b, true
+		}
+	}
+	return repository.Book{}, false
+}
+
+func (f *fakeStore) GetBookByISBN(ctx context.Context, isbn pgtype.Text) (repository.GetBookByISBNRow, error) {
+	if f.err != nil {
+		return repository.GetBookByISBNRow{}, f.err
+	}
+	b, ok := f.book(isbn.String)
+	if !ok {
+		return repository.GetBookByISBNRow{}, pgx.ErrNoRows
+	}
+	return repository.GetBookByISBNRow{
+		ID:              b.ID,
+		Isbn:            b.Isbn,
+		Title:           b.Title,
+		ContentHash:     b.ContentHash,
+		Language:        b.Language,
+		Author:          b.Author,
+		Genres:          b.Genres,
+		PublishedYear:   b.PublishedYear,
+		WorkID:          b.WorkID,
+		EmbeddingStatus: b.EmbeddingStatus,
+	}, nil
+}
+
+func (f *fakeStore) GetBookStatus(ctx context.Context, isbn pgtype.Text) (repository.GetBookStatusRow, error) {
+	b, ok := f.book(isbn.String)
+	if !ok {
+		return repository.GetBookStatusRow{}, pgx.ErrNoRows
+	}
+	return repository.GetBookStatusRow{
+		ID:              b.ID,
+		Isbn:            b.Isbn,
+		Title:           b.Title,
+		EmbeddingStatus: b.EmbeddingStatus,
+		EmbeddingError:  b.EmbeddingError,
+	}, nil
+}
+
+func (f *fakeStore) InsertBook(ctx context.Context, arg repository.InsertBookParams) (int32, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if _, ok := f.book(arg.Isbn.String); ok {
+		return 0, &pgconn.PgError{Code: uniqueViolation, ConstraintName: "books_isbn_key"}
+	}
+	f.add(repository.Book{
+		Isbn:            arg.Isbn,
+		Title:           arg.Title,
+		Description:     arg.Description,
+		Embedding:       arg.Embedding,
+		ContentHash:     arg.ContentHash,
+		EmbeddingStatus: EmbeddingReady,
+		Language:        arg.Language,
+		Author:          arg.Author,
+		Genres:          arg.Genres,
+		PublishedYear:   arg.PublishedYear,
+		WorkID:          arg.WorkID,
+	})
+	return f.books[len(f.books)-1].ID, nil
+}
+
+func (f *fakeStore) UpsertPendingBook(ctx context.Context, arg repository.UpsertPendingBookParams) (repository.UpsertPendingBookRow, error) {
+	if f.err != nil {
+		return repository.UpsertPendingBookRow{}, f.err
+	}
+	book := repository.Book{
+		Isbn:            arg.Isbn,
+		Title:           arg.Title,
+		Description:     arg.Description,
+		ContentHash:     arg.ContentHash,
+		EmbeddingStatus: EmbeddingPending,
+		Language:        arg.Language,
+		Author:          arg.Author,
+		Genres:          arg.Genres,
+		PublishedYear:   arg.PublishedYear,
+	}
+	i := slices.IndexFunc(f.books, func(b repository.Book) bool { return b.Isbn == arg.Isbn })
+	inserted := i < 0
+	if inserted {
+		f.add(book)
+		i = len(f.books) - 1
+	} else {
+		book.ID, book.WorkID = f.books[i].ID, f.books[i].WorkID
+		f.books[i] = book
+		f.jobs = slices.DeleteFunc(f.jobs, func(id int32) bool { return id == book.ID })
+	}
+	f.jobs = append(f.jobs, f.books[i].ID)
+	return repository.UpsertPendingBookRow{ID: f.books[i].ID, Inserted: inserted}, nil
+}
+
+// matches applies the drill-down filters as the search queries do.
+func matches(b repository.Book, genre, author, language pgtype.Text, decade pgtype.Int4) bool {
+	return (!genre.Valid || slices.Contains(b.Genres, genre.String)) &&
+		(!author.Valid || strings.EqualFold(b.Author.String, author.String)) &&
+		(!language.Valid || b.Language == language.String) &&
+		(!decade.Valid || b.PublishedYear.Valid && b.PublishedYear.Int32/10*10 == decade.Int32)
+}
+
+func (f *fakeStore) SearchBooks(ctx context.Context, arg repository.SearchBooksParams) ([]repository.SearchBooksRow, error) {
+	f.lastSearch = arg
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	type hit struct {
+		book repository.Book
+		dist float64
+	}
+	var hits []hit
+	for _, b := range f.books {
+		if b.Embedding.Slice() == nil || !matches(b, arg.Genre, arg.Author, arg.Language, arg.Decade) {
+			continue
+		}
+		sim, err := cosineSimilarity(arg.Embedding.Slice(), b.Embedding.Slice())
+		if err != nil {
+			sim = 0 // Postgres would fail; the service tolerates odd rows
+		}
+		hits = append(hits, hit{b, 1 - sim})
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].dist < hits[j].dist })
+
+	var rows []repository.SearchBooksRow
+	for _, h := range hits[:min(len(hits), int(arg.MaxResults))] {
+		rows = append(rows, repository.SearchBooksRow{
+			ID:          h.book.ID,
+			Isbn:        h.book.Isbn,
+			Title:       h.book.Title,
+			Description: h.book.Description,
+			Embedding:   h.book.Embedding,
+			WorkID:      h.book.WorkID,
+		})
+	}
+	return rows, nil
+}
+
+// SearchBooksBinary searches exactly, like SearchBooks, and records the
+// candidate count as MaxResults of lastSearch.
+func (f *fakeStore) SearchBooksBinary(ctx context.Context, arg repository.SearchBooksBinaryParams) ([]repository.SearchBooksBinaryRow, error) {
+	rows, err := f.SearchBooks(ctx, repository.SearchBooksParams{
+		Genre:      arg.Genre,
+		Author:     arg.Author,
+		Language:   arg.Language,
+		Decade:     arg.Decade,
+		Embedding:  arg.Embedding,
+		MaxResults: arg.Candidates,
+	})
+	var out []repository.SearchBooksBinaryRow
+	for _, row := range rows[:min(len(rows), int(arg.MaxResults))] {
+		out = append(out, repository.SearchBooksBinaryRow(row))
+	}
+	return out, err
+}
+
+func (f *fakeStore) SetHNSWEfSearch(ctx context.Context, efSearch int32) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.efSearch = efSearch
+	return nil
+}
+
+func (f *fakeStore) SetHNSWIterativeScan(ctx context.Context) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.iterativeScan = true
+	return nil
+}
+
+// SearchBooksByText matches books containing every word of the query, and
+// words starting with every prefix, ranked by the number of occurrences.
+// Operators of the websearch syntax are not supported.
+func (f *fakeStore) SearchBooksByText(ctx context.Context, arg repository.SearchBooksByTextParams) ([]repository.SearchBooksByTextRow, error) {
+	f.lastTextSearch = arg
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var rows []repository.SearchBooksByTextRow
+	for _, b := range f.books {
+		if !matches(b, arg.Genre, arg.Author, arg.Language, arg.Decade) {
+			continue
+		}
+		words := strings.FieldsFunc(strings.ToLower(b.Title+" "+b.Description), func(r rune) bool {
+			return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+		})
+		rank, ok := 0, true
+		for _, term := range strings.Fields(strings.ToLower(arg.Query)) {
+			n := 0
+			for _, w := range words {
+				if w == term {
+					n++
+				}
+			}
+			rank, ok = rank+n, ok && n > 0
+		}
+		for prefix := range strings.SplitSeq(arg.Prefixes, " & ") {
+			if prefix = strings.TrimSuffix(prefix, ":*"); prefix == "" {
+				continue
+			}
+			n := 0
+			for _, w := range words {
+				if strings.HasPrefix(w, prefix) {
+					n++
+				}
+			}
+			rank, ok = rank+n, ok && n > 0
+		}
+		if ok {
+			rows = append(rows, repository.SearchBooksByTextRow{
+				ID:            b.ID,
+				Isbn:          b.Isbn,
+				Title:         b.Title,
+				Description:   b.Description,
+				WorkID:        b.WorkID,
+				Rank:          float32(rank),
+				TitleHeadline: b.Title,
+				Headline:      b.Description,
+			})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Rank > rows[j].Rank })
+	return rows[:min(len(rows), int(arg.MaxResults))], nil
+}
+
+// FindWorkCandidates returns the works of books within MaxDistance, with a
+// title similarity of 1 if the work's title key matches and 0 otherwise.
+func (f *fakeStore) FindWorkCandidates(ctx context.Context, arg repository.FindWorkCandidatesParams) ([]repository.FindWorkCandidatesRow, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var rows []repository.FindWorkCandidatesRow
+	for _, b := range f.books {
+		if !b.WorkID.Valid || b.Isbn == arg.Isbn {
+			continue
+		}
+		sim, err := cosineSimilarity(arg.Embedding.Slice(), b.Embedding.Slice())
+		if err != nil || 1-sim > arg.MaxDistance {
+			continue
+		}
+		row := repository.FindWorkCandidatesRow{WorkID: b.WorkID.Int32, Distance: 1 - sim}
+		if f.works[b.WorkID.Int32-1].TitleKey == arg.TitleKey {
+			row.TitleSimilarity = 1
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Distance < rows[j].Distance })
+	return rows[:min(len(rows), int(arg.MaxResults))], nil
+}
+
+// FindWorkCandidatesMatryoshka searches like FindWorkCandidates; the fake
+// has no truncated embeddings.
+func (f *fakeStore) FindWorkCandidatesMatryoshka(ctx context.Context, arg repository.FindWorkCandidatesMatryoshkaParams) ([]repository.FindWorkCandidatesMatryoshkaRow, error) {
+	f.shortSearches++
+	rows, err := f.FindWorkCandidates(ctx, repository.FindWorkCandidatesParams{
+		Embedding:   arg.Embedding,
+		TitleKey:    arg.TitleKey,
+		Isbn:        arg.Isbn,
+		MaxDistance: arg.MaxDistance,
+		MaxResults:  arg.MaxResults,
+	})
+	var out []repository.FindWorkCandidatesMatryoshkaRow
+	for _, row := range rows {
+		out = append(out, repository.FindWorkCandidatesMatryoshkaRow(row))
+	}
+	return out, err
+}
+
+func (f *fakeStore) CreateWork(ctx context.Context, arg repository.CreateWorkParams) (int32, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	id := int32(len(f.works) + 1)
+	f.works = append(f.works, repository.Work{ID: id, Title: arg.Title, TitleKey: arg.TitleKey})
+	return id, nil
+}
+
+func (f *fakeStore) ListWorkEditions(ctx context.Context, workIDs []int32) ([]repository.ListWorkEditionsRow, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var rows []repository.ListWorkEditionsRow
+	for _, b := range f.books {
+		if b.WorkID.Valid && slices.Contains(workIDs, b.WorkID.Int32) {
+			rows = append(rows, repository.ListWorkEditionsRow{
+				ID:            b.ID,
+				Isbn:          b.Isbn,
+				Title:         b.Title,
+				WorkID:        b.WorkID,
+				PublishedYear: b.PublishedYear,
+			})
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) GetFeedbackCounts(ctx context.Context, arg repository.GetFeedbackCountsParams) ([]repository.GetFeedbackCountsRow, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var rows []repository.GetFeedbackCountsRow
+	for _, isbn := range arg.Isbns {
+		if c, ok := f.feedback[isbn]; ok {
+			c.Isbn = isbn
+			rows = append(rows, c)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) SuspectedDuplicates(ctx context.Context, arg repository.SuspectedDuplicatesParams) ([]repository.SuspectedDuplicatesRow, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var rows []repository.SuspectedDuplicatesRow
+	for _, row := range f.duplicates {
+		if row.ID > arg.After && len(rows) < int(arg.MaxBooks) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) EnqueueWebhookEvent(ctx context.Context, arg repository.EnqueueWebhookEventParams) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.events = append(f.events, arg.EventType)
+	return int64(len(f.events)), nil
+}
+
+// testBook returns an embedded book as fakeEmbedder would store it.
+func testBook(isbn, title, description string) repository.Book {
+	vec, _ := (&fakeEmbedder{}).Embed(context.Background(), description)
+	return repository.Book{
+		Isbn:            pgtype.Text{String: isbn, Valid: true},
+		Title:           title,
+		Description:     description,
+		Embedding:       pgvector.NewVector(vec),
+		EmbeddingStatus: EmbeddingReady,
+		Language:        "english",
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// DefaultFuzzyThreshold is the minimum word similarity of a fuzzy match
+// when BookService.FuzzyThreshold is unset.
+const DefaultFuzzyThreshold = 0.4
+
+// FuzzySearch finds books whose title closely matches query, tolerating
+// typos such as "Hary Poter". A threshold of zero uses FuzzyThreshold.
+func (s *BookService) FuzzySearch(ctx context.Context, query string, threshold float64) ([]repository.FuzzySearchTitlesRow, error) {
+	ctx, span := tracer.Start(ctx, "BookService.FuzzySearch")
+	defer span.End()
+
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	if query == "" {
+		return nil, Validation("empty_query", "search query cannot be empty")
+	}
+	if utf8.RuneCountInString(query) > MaxTitleLength {
+		return nil, Validation("query_too_long", fmt.Sprintf("query must be at most %d characters", MaxTitleLength))
+	}
+	if threshold == 0 {
+		threshold = s.fuzzyThreshold()
+	}
+	if threshold <= 0 || threshold > 1 {
+		return nil, Validation("invalid_threshold", "threshold must be greater than 0 and at most 1")
+	}
+	span.SetAttributes(attribute.Float64("search.threshold", threshold))
+
+	var books []repository.FuzzySearchTitlesRow
+	err := s.withTx(ctx, func(q Store) error {
+		// The index only returns candidates above the session threshold.
+		err := q.SetWordSimilarityThreshold(ctx, strconv.FormatFloat(threshold, 'f', -1, 64))
+		if err != nil {
+			return err
+		}
+		books, err = q.FuzzySearchTitles(ctx, repository.FuzzySearchTitlesParams{
+			Query:      query,
+			Threshold:  threshold,
+			MaxResults: 10,
+		})
+		return err
+	})
+	if err != nil {
+		s.Logger.Error("Fuzzy search failed", "query", query, "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "fuzzy search failed")
+		return nil, fmt.Errorf("fuzzy search failed: %w", err)
+	}
+
+	return books, nil
+}
+
+func (s *BookService) fuzzyThreshold() float64 {
+	if s.FuzzyThreshold > 0 {
+		return s.FuzzyThreshold
+	}
+	return DefaultFuzzyThreshold
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzySearchValidation(t *testing.T) {
+	s := &BookService{}
+
+	tests := []struct {
+		name      string
+		query     string
+		threshold float64
+		code      string
+	}{
+		{"Blank query", "  ", 0, "empty_query"},
+		{"Negative threshold", "hary poter", -0.1, "invalid_threshold"},
+		{"Threshold above one", "hary poter", 1.5, "invalid_threshold"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.FuzzySearch(context.Background(), tt.query, tt.threshold)
+
+			require.ErrorIs(t, err, ErrValidation)
+			domainErr, _ := AsError(err)
+			assert.Equal(t, tt.code, domainErr.Code)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package service
+
+import "github.com/nmdra/Semantic-Search/internal/lang"
+
+// bookLanguage returns the text search configuration for a validated book:
+// its own language, a detected one, or the default.
+func (s *BookService) bookLanguage(book BookInput) string {
+	if book.Language != "" {
+		return book.Language
+	}
+	if s.DetectLanguage {
+		if config, ok := lang.Detect(book.Title + "\n" + book.Description); ok {
+			return config
+		}
+	}
+	return s.defaultLanguage()
+}
+
+func (s *BookService) defaultLanguage() string {
+	if s.DefaultLanguage != "" {
+		return s.DefaultLanguage
+	}
+	return lang.Default
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// searchLimit is the number of semantic results returned.
+	searchLimit = 5
+	// rerankCandidateFactor widens the vector search when reranking, so a
+	// well-liked or novel book just outside the top results can move up.
+	rerankCandidateFactor = 4
+	// feedbackWindow bounds the feedback used for ranking, so old opinions fade.
+	feedbackWindow = 90 * 24 * time.Hour
+)
+
+// Feedback weights: an explicit vote says more than a click.
+const (
+	clickWeight = 1
+	voteWeight  = 3
+)
+
+// feedbackScore maps feedback counts to [0, 1] as a smoothed share of
+// positive signal. A book without feedback scores 0.5, so it is neither
+// favoured nor penalised against other books without feedback.
+func feedbackScore(clicks, relevant, irrelevant int64) float64 {
+	positive := float64(clickWeight*clicks + voteWeight*relevant)
+	negative := float64(voteWeight * irrelevant)
+	return (positive + 1) / (positive + negative + 2)
+}
+
+// rerankByFeedback blends feedback into Score and sorts by it. If feedback
+// cannot be loaded the results keep their similarity order.
+func (s *BookService) rerankByFeedback(ctx context.Context, results []BookWithSimilarity) []BookWithSimilarity {
+	if len(results) == 0 {
+		return results
+	}
+
+	isbns := make([]string, 0, len(results))
+	for _, r := range results {
+		isbns = append(isbns, r.ISBN)
+	}
+
+	counts, err := s.Repository.GetFeedbackCounts(ctx, repository.GetFeedbackCountsParams{
+		Isbns: isbns,
+		Since: pgtype.Timestamptz{Time: time.Now().Add(-feedbackWindow), Valid: true},
+	})
+	if err != nil {
+		s.Logger.Warn("Failed to load search feedback, ranking by similarity only", "error", err)
+		return results
+	}
+
+	scores := make(map[string]float64, len(counts))
+	for _, c := range counts {
+		scores[c.Isbn] = feedbackScore(c.Clicks, c.Relevant, c.Irrelevant)
+	}
+
+	w := s.FeedbackWeight
+	for i := range results {
+		fb, ok := scores[results[i].ISBN]
+		if !ok {
+			fb = feedbackScore(0, 0, 0)
+		}
+		results[i].Score = (1-w)*results[i].Similarity + w*fb
+	}
+
+	slices.SortStableFunc(results, func(a, b BookWithSimilarity) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	return results
+}
+
+// diversify picks k results by maximal marginal relevance: each pick
+// maximises lambda·Score − (1−lambda)·(highest similarity to a book already
+// picked), so near-duplicates such as other editions of a picked book drop
+// down. lambda 1 keeps the Score order. Results without embeddings are
+// returned unchanged.
+func diversify(results []BookWithSimilarity, k int, lambda float64) []BookWithSimilarity {
+	if len(results) <= 1 {
+		return results
+	}
+	for _, r := range results {
+		if r.embedding == nil {
+			return results
+		}
+	}
+
+	remaining := slices.Clone(results)
+	picked := make([]BookWithSimilarity, 0, min(k, len(results)))
+	// redundancy[i] is the highest similarity of remaining[i] to any pick.
+	redundancy := make([]float64, len(remaining))
+
+	for len(picked) < k && len(remaining) > 0 {
+		best, bestScore := 0, math.Inf(-1)
+		for i, r := range remaining {
+			score := lambda*r.Score - (1-lambda)*redundancy[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		pick := remaining[best]
+		picked = append(picked, pick)
+		remaining = slices.Delete(remaining, best, best+1)
+		redundancy = slices.Delete(redundancy, best, best+1)
+
+		for i, r := range remaining {
+			if sim, err := cosineSimilarity(pick.embedding, r.embedding); err == nil {
+				redundancy[i] = max(redundancy[i], sim)
+			}
+		}
+	}
+	return picked
+}
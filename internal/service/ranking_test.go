@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedbackScore(t *testing.T) {
+	neutral := feedbackScore(0, 0, 0)
+	assert.InDelta(t, 0.5, neutral, 1e-9)
+
+	t.Run("Positive feedback raises the score", func(t *testing.T) {
+		assert.Greater(t, feedbackScore(1, 0, 0), neutral)
+		assert.Greater(t, feedbackScore(0, 1, 0), feedbackScore(1, 0, 0), "a vote outweighs a click")
+	})
+
+	t.Run("Negative feedback lowers the score", func(t *testing.T) {
+		assert.Less(t, feedbackScore(0, 0, 1), neutral)
+		assert.Less(t, feedbackScore(3, 0, 1), feedbackScore(3, 0, 0))
+	})
+
+	t.Run("Stays within bounds", func(t *testing.T) {
+		assert.Less(t, feedbackScore(1_000_000, 1_000_000, 0), 1.0)
+		assert.Greater(t, feedbackScore(0, 0, 1_000_000), 0.0)
+	})
+}
+
+func TestRerankByFeedback(t *testing.T) {
+	results := func() []BookWithSimilarity {
+		return []BookWithSimilarity{
+			{ISBN: "liked-less", Similarity: 0.80},
+			{ISBN: "disliked", Similarity: 0.78},
+			{ISBN: "liked", Similarity: 0.75},
+		}
+	}
+	s, store, _ := newTestService()
+	store.feedback = map[string]repository.GetFeedbackCountsRow{
+		"liked":    {Clicks: 10, Relevant: 5},
+		"disliked": {Irrelevant: 5},
+	}
+	isbns := func(rs []BookWithSimilarity) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.ISBN)
+		}
+		return out
+	}
+
+	t.Run("Feedback reorders results", func(t *testing.T) {
+		s.FeedbackWeight = 0.5
+		got := s.rerankByFeedback(context.Background(), results())
+		assert.Equal(t, []string{"liked", "liked-less", "disliked"}, isbns(got))
+		assert.InDelta(t, 0.5*0.80+0.5*feedbackScore(0, 0, 0), got[1].Score, 1e-9)
+	})
+
+	t.Run("A small weight does not override similarity", func(t *testing.T) {
+		s.FeedbackWeight = 0.01
+		got := s.rerankByFeedback(context.Background(), results())
+		assert.Equal(t, []string{"liked-less", "disliked", "liked"}, isbns(got))
+	})
+
+	t.Run("Weight zero keeps similarity order", func(t *testing.T) {
+		s.FeedbackWeight = 0
+		got := s.rerankByFeedback(context.Background(), results())
+		assert.Equal(t, []string{"liked-less", "disliked", "liked"}, isbns(got))
+		assert.InDelta(t, 0.80, got[0].Score, 1e-9)
+	})
+
+	t.Run("Failing to load feedback keeps the order", func(t *testing.T) {
+		s.FeedbackWeight = 0.5
+		store.err = errors.New("connection refused")
+		defer func() { store.err = nil }()
+		got := s.rerankByFeedback(context.Background(), results())
+		assert.Equal(t, []string{"liked-less", "disliked", "liked"}, isbns(got))
+	})
+}
+
+func TestDiversify(t *testing.T) {
+	// Two editions of the same book and one different book.
+	results := []BookWithSimilarity{
+		{ISBN: "edition-1", Score: 0.90, embedding: []float32{1, 0, 0}},
+		{ISBN: "edition-2", Score: 0.89, embedding: []float32{0.99, 0.01, 0}},
+		{ISBN: "other", Score: 0.80, embedding: []float32{0, 1, 0}},
+	}
+	isbns := func(rs []BookWithSimilarity) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.ISBN)
+		}
+		return out
+	}
+
+	t.Run("Moves near-duplicates down", func(t *testing.T) {
+		got := diversify(results, 3, 0.5)
+		assert.Equal(t, []string{"edition-1", "other", "edition-2"}, isbns(got))
+	})
+
+	t.Run("Lambda one keeps relevance order", func(t *testing.T) {
+		got := diversify(results, 3, 1)
+		assert.Equal(t, []string{"edition-1", "edition-2", "other"}, isbns(got))
+
+		// Unrelated and identical embeddings alike leave the order to the
+		// scores when redundancy has no weight.
+		mixed := []BookWithSimilarity{
+			{ISBN: "a", Score: 0.95, embedding: []float32{1, 0, 0}},
+			{ISBN: "b", Score: 0.94, embedding: []float32{1, 0, 0}},
+			{ISBN: "c", Score: 0.93, embedding: []float32{1, 0, 0}},
+			{ISBN: "d", Score: 0.70, embedding: []float32{0, 0, 1}},
+			{ISBN: "e", Score: 0.60, embedding: []float32{0, 1, 0}},
+		}
+		for k := 1; k <= len(mixed); k++ {
+			assert.Equal(t, isbns(mixed[:k]), isbns(diversify(mixed, k, 1)), "k=%d", k)
+		}
+	})
+
+	t.Run("Picks at most k", func(t *testing.T) {
+		assert.Len(t, diversify(results, 2, 0.5), 2)
+	})
+
+	t.Run("Leaves results without embeddings alone", func(t *testing.T) {
+		plain := []BookWithSimilarity{{ISBN: "a"}, {ISBN: "b"}}
+		assert.Equal(t, plain, diversify(plain, 1, 0.5))
+	})
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Store is the storage BookService runs on. *repository.Queries implements
+// it on Postgres; the narrow interfaces it is made of let helpers ask for
+// only what they use, and tests substitute in-memory fakes.
+type Store interface {
+	BookStore
+	SearchStore
+	WorkStore
+	EventStore
+}
+
+var _ Store = (*repository.Queries)(nil)
+
+// BookStore reads and writes books.
+type BookStore interface {
+	VectorStore
+	GetBookByISBN(ctx context.Context, isbn pgtype.Text) (repository.GetBookByISBNRow, error)
+	GetBookStatus(ctx context.Context, isbn pgtype.Text) (repository.GetBookStatusRow, error)
+	InsertPendingBook(ctx context.Context, arg repository.InsertPendingBookParams) (int32, error)
+	UpsertBook(ctx context.Context, arg repository.UpsertBookParams) (repository.UpsertBookRow, error)
+	UpsertPendingBook(ctx context.Context, arg repository.UpsertPendingBookParams) (repository.UpsertPendingBookRow, error)
+	UpdateBookDetails(ctx context.Context, arg repository.UpdateBookDetailsParams) error
+	DeleteBookByISBN(ctx context.Context, isbn pgtype.Text) (repository.DeleteBookByISBNRow, error)
+}
+
+// SearchStore runs the searches other than plain nearest neighbours, and
+// loads what ranking, facets and suggestions need.
+type SearchStore interface {
+	SearchBooksHalfvec(ctx context.Context, arg repository.SearchBooksHalfvecParams) ([]repository.SearchBooksHalfvecRow, error)
+	SearchBooksBinary(ctx context.Context, arg repository.SearchBooksBinaryParams) ([]repository.SearchBooksBinaryRow, error)
+	SearchBooksMatryoshka(ctx context.Context, arg repository.SearchBooksMatryoshkaParams) ([]repository.SearchBooksMatryoshkaRow, error)
+	SearchBooksByText(ctx context.Context, arg repository.SearchBooksByTextParams) ([]repository.SearchBooksByTextRow, error)
+	BookFacetCounts(ctx context.Context, arg repository.BookFacetCountsParams) ([]repository.BookFacetCountsRow, error)
+	FuzzySearchTitles(ctx context.Context, arg repository.FuzzySearchTitlesParams) ([]repository.FuzzySearchTitlesRow, error)
+	SetWordSimilarityThreshold(ctx context.Context, threshold string) error
+	SetHNSWEfSearch(ctx context.Context, efSearch int32) error
+	SetHNSWIterativeScan(ctx context.Context) error
+	SuggestTitles(ctx context.Context, arg repository.SuggestTitlesParams) ([]repository.SuggestTitlesRow, error)
+	SuggestPopularQueries(ctx context.Context, arg repository.SuggestPopularQueriesParams) ([]repository.SuggestPopularQueriesRow, error)
+	GetFeedbackCounts(ctx context.Context, arg repository.GetFeedbackCountsParams) ([]repository.GetFeedbackCountsRow, error)
+}
+
+// WorkMatchStore finds and creates the works that new books join.
+type WorkMatchStore interface {
+	FindWorkCandidates(ctx context.Context, arg repository.FindWorkCandidatesParams) ([]repository.FindWorkCandidatesRow, error)
+	FindWorkCandidatesMatryoshka(ctx context.Context, arg repository.FindWorkCandidatesMatryoshkaParams) ([]repository.FindWorkCandidatesMatryoshkaRow, error)
+	CreateWork(ctx context.Context, arg repository.CreateWorkParams) (int32, error)
+}
+
+// WorkStore groups books into works and reports on them.
+type WorkStore interface {
+	WorkMatchStore
+	ListWorkEditions(ctx context.Context, workIDs []int32) ([]repository.ListWorkEditionsRow, error)
+	SuspectedDuplicates(ctx context.Context, arg repository.SuspectedDuplicatesParams) ([]repository.SuspectedDuplicatesRow, error)
+	SuspectedDuplicatesMatryoshka(ctx context.Context, arg repository.SuspectedDuplicatesMatryoshkaParams) ([]repository.SuspectedDuplicatesMatryoshkaRow, error)
+}
+
+// EventStore records webhook events in the outbox.
+type EventStore interface {
+	EnqueueWebhookEvent(ctx context.Context, arg repository.EnqueueWebhookEventParams) (int64, error)
+}
+
+// withTx runs fn in a transaction on DB, like WithTx. Transactions are
+// only available on Postgres, so any other Store, or a nil DB, runs fn on
+// Repository directly.
+func (s *BookService) withTx(ctx context.Context, fn func(Store) error) error {
+	q, ok := s.Repository.(*repository.Queries)
+	if !ok || s.DB == nil {
+		return fn(s.Repository)
+	}
+	return WithTx(ctx, s.DB, q, func(q *repository.Queries) error { return fn(q) })
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+)
+
+const (
+	SuggestionTitle = "title"
+	SuggestionQuery = "query"
+
+	// MaxSuggestQueryLength bounds type-ahead input; longer text is a search,
+	// not a prefix.
+	MaxSuggestQueryLength = 100
+	// trigramLength is the shortest input the trigram index can help with.
+	trigramLength = 3
+)
+
+// Suggestion is a type-ahead completion: a book title or a popular query.
+type Suggestion struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+	ISBN string `json:"isbn,omitempty"`
+
+	score float64
+}
+
+// Suggest returns up to limit completions for the partial query q, merging
+// matching titles with popular past queries. It only reads indexed tables
+// and never calls the embedder, so it is cheap enough for every keystroke.
+func (s *BookService) Suggest(ctx context.Context, q string, limit int) ([]Suggestion, error) {
+	ctx, span := tracer.Start(ctx, "BookService.Suggest")
+	defer span.End()
+
+	q = strings.ToLower(strings.Join(strings.Fields(q), " "))
+	if q == "" {
+		return nil, Validation("missing_query", "query parameter q is required")
+	}
+	if utf8.RuneCountInString(q) > MaxSuggestQueryLength {
+		return nil, Validation("query_too_long", fmt.Sprintf("q must be at most %d characters", MaxSuggestQueryLength))
+	}
+
+	prefix := escapeLike(q) + "%"
+	pattern := prefix
+	if utf8.RuneCountInString(q) >= trigramLength {
+		pattern = "%" + prefix
+	}
+
+	titles, err := s.Repository.SuggestTitles(ctx, repository.SuggestTitlesParams{
+		Prefix:     prefix,
+		Query:      q,
+		Pattern:    pattern,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest titles: %w", err)
+	}
+
+	queries, err := s.Repository.SuggestPopularQueries(ctx, repository.SuggestPopularQueriesParams{
+		Prefix:     prefix,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest queries: %w", err)
+	}
+
+	return mergeSuggestions(titles, queries, limit), nil
+}
+
+// mergeSuggestions ranks titles and queries on one scale. A query that
+// repeats a title case-insensitively is folded into the title, which keeps
+// the better of the two scores and links to the book. Prefix
+// matches score in [1, 2] and come before infix matches in [0, 1]: a title by
+// its trigram similarity, a query by its popularity relative to the most
+// popular candidate.
+func mergeSuggestions(titles []repository.SuggestTitlesRow, queries []repository.SuggestPopularQueriesRow, limit int) []Suggestion {
+	out := make([]Suggestion, 0, len(titles)+len(queries))
+	index := make(map[string]int, cap(out))
+	add := func(sg Suggestion) {
+		key := strings.ToLower(sg.Text)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(out)
+			out = append(out, sg)
+			return
+		}
+		out[i].score = max(out[i].score, sg.score)
+	}
+
+	for _, t := range titles {
+		score := t.Score
+		if t.PrefixMatch {
+			score++
+		}
+		add(Suggestion{Text: t.Title, Type: SuggestionTitle, ISBN: t.Isbn.String, score: score})
+	}
+
+	var most int64
+	for _, q := range queries {
+		most = max(most, q.Searches)
+	}
+	for _, q := range queries {
+		add(Suggestion{Text: q.Query, Type: SuggestionQuery, score: 1 + float64(q.Searches)/float64(most)})
+	}
+
+	slices.SortStableFunc(out, func(a, b Suggestion) int {
+		switch {
+		case a.score > b.score:
+			return -1
+		case a.score < b.score:
+			return 1
+		}
+		return strings.Compare(a.Text, b.Text)
+	})
+
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
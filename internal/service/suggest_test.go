@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeSuggestions(t *testing.T) {
+	titles := []repository.SuggestTitlesRow{
+		{Isbn: pgtype.Text{String: "9780141439518", Valid: true}, Title: "Pride and Prejudice", PrefixMatch: true, Score: 0.3},
+		{Isbn: pgtype.Text{String: "9780140449136", Valid: true}, Title: "Crime and Punishment", Score: 0.2},
+	}
+	queries := []repository.SuggestPopularQueriesRow{
+		{Query: "pride and prejudice", Searches: 4},
+		{Query: "pride", Searches: 10},
+	}
+
+	t.Run("Ranks prefix matches first and folds duplicates into titles", func(t *testing.T) {
+		got := mergeSuggestions(titles, queries, 10)
+
+		assert.Equal(t, []Suggestion{
+			{Text: "pride", Type: SuggestionQuery, score: 2},
+			{Text: "Pride and Prejudice", Type: SuggestionTitle, ISBN: "9780141439518", score: 1.4},
+			{Text: "Crime and Punishment", Type: SuggestionTitle, ISBN: "9780140449136", score: 0.2},
+		}, got)
+	})
+
+	t.Run("Truncates to limit", func(t *testing.T) {
+		assert.Len(t, mergeSuggestions(titles, queries, 2), 2)
+	})
+
+	t.Run("Handles no candidates", func(t *testing.T) {
+		assert.Empty(t, mergeSuggestions(nil, nil, 5))
+	})
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\% pure\_go\\`, escapeLike(`100% pure_go\`))
+}
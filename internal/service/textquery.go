@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Highlight sets the markers ts_headline puts around matched terms. Empty
+// markers default to <b> and </b>. Headlines are HTML-escaped when the
+// markers are HTML tags, and left as plain text otherwise.
+type Highlight struct {
+	Start string
+	Stop  string
+}
+
+// ValidateHighlight rejects markers that cannot be passed to ts_headline.
+func ValidateHighlight(h Highlight) error {
+	if strings.Contains(h.Start, `"`) || strings.Contains(h.Stop, `"`) {
+		return fmt.Errorf("highlight markers must not contain double quotes")
+	}
+	return nil
+}
+
+// headlineOptions returns ts_headline options for the title, which is
+// highlighted in full, and the description, which is cut to fragments
+// around the matches.
+func (h Highlight) headlineOptions() (title, description string) {
+	start, stop := h.Start, h.Stop
+	if start == "" && stop == "" {
+		start, stop = "<b>", "</b>"
+	}
+	markers := fmt.Sprintf(`StartSel="%s", StopSel="%s"`, start, stop)
+	return markers + ", HighlightAll=true",
+		markers + `, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`
+}
+
+// html reports whether the markers are HTML tags, so that the text they
+// wrap must be escaped.
+func (h Highlight) html() bool {
+	return h.Start == "" && h.Stop == "" || strings.Contains(h.Start, "<") || strings.Contains(h.Stop, "<")
+}
+
+// splitTextQuery separates prefix terms such as "philos*" from the rest of a
+// full-text query. The rest is left in websearch syntax; prefix terms are
+// returned in to_tsquery syntax and are always required. Terms inside quotes
+// are never treated as prefixes.
+func splitTextQuery(q string) (websearch, prefixes string) {
+	var rest, prefix []string
+	quoted := false
+	for _, tok := range strings.Fields(q) {
+		if !quoted {
+			if word, ok := strings.CutSuffix(tok, "*"); ok && isPrefixWord(word) {
+				prefix = append(prefix, word+":*")
+				continue
+			}
+		}
+		if strings.Count(tok, `"`)%2 == 1 {
+			quoted = !quoted
+		}
+		rest = append(rest, tok)
+	}
+	return strings.Join(rest, " "), strings.Join(prefix, " & ")
+}
+
+// isPrefixWord reports whether w is safe to embed in a to_tsquery string.
+func isPrefixWord(w string) bool {
+	if w == "" {
+		return false
+	}
+	for _, r := range w {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
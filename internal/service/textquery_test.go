@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitTextQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		websearch string
+		prefixes  string
+	}{
+		{"Plain words", "pride prejudice", "pride prejudice", ""},
+		{"Websearch syntax is kept", `"pride and prejudice" OR emma -zombies`, `"pride and prejudice" OR emma -zombies`, ""},
+		{"Prefix terms", "austen philos* nov*", "austen", "philos:* & nov:*"},
+		{"Only prefixes", "philos*", "", "philos:*"},
+		{"Stars inside quotes stay literal", `"philos* stone" magic*`, `"philos* stone"`, "magic:*"},
+		{"Unsafe prefix terms stay literal", "a&b* *", "a&b* *", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			websearch, prefixes := splitTextQuery(tt.query)
+			assert.Equal(t, tt.websearch, websearch)
+			assert.Equal(t, tt.prefixes, prefixes)
+		})
+	}
+}
+
+func TestHeadlineOptions(t *testing.T) {
+	title, description := Highlight{}.headlineOptions()
+	assert.Equal(t, `StartSel="<b>", StopSel="</b>", HighlightAll=true`, title)
+	assert.Contains(t, description, `StartSel="<b>", StopSel="</b>", MaxWords=35`)
+
+	title, _ = Highlight{Start: "[", Stop: "]"}.headlineOptions()
+	assert.Equal(t, `StartSel="[", StopSel="]", HighlightAll=true`, title)
+
+	assert.Error(t, ValidateHighlight(Highlight{Start: `<em class="hit">`}))
+}
+
+func TestHighlightEscapesHTML(t *testing.T) {
+	assert.True(t, Highlight{}.html(), "the default markers are HTML")
+	assert.True(t, Highlight{Start: "<mark>", Stop: "</mark>"}.html())
+	assert.False(t, Highlight{Start: "[", Stop: "]"}.html(), "plain markers leave plain text")
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nmdra/Semantic-Search/internal/isbn"
+	"github.com/nmdra/Semantic-Search/internal/lang"
+)
+
+const (
+	MaxTitleLength = 500
+	// MaxDescriptionLength keeps descriptions well inside the embedding
+	// model's 2048-token input limit.
+	MaxDescriptionLength = 8000
+
+	MaxAuthorLength = 200
+	MaxGenres       = 10
+	MaxGenreLength  = 50
+	// MaxPublishedYear bounds publication years; zero means unknown.
+	MaxPublishedYear = 2100
+)
+
+// BookInput is the client-supplied part of a book. Every ingest path runs it
+// through ValidateBook before anything is embedded or stored.
+type BookInput struct {
+	ISBN        string
+	Title       string
+	Description string
+	// Language is an ISO 639-1 code or text search configuration name. It is
+	// optional; ValidateBook turns it into a configuration name.
+	Language string
+
+	// Optional metadata for facets and filters.
+	Author        string
+	Genres        []string
+	PublishedYear int // zero if unknown
+}
+
+// ValidateBook trims and normalises in, converting the ISBN to its bare
+// ISBN-13 form. On failure it returns a validation *Error listing every
+// offending field.
+func ValidateBook(in BookInput) (BookInput, error) {
+	out := BookInput{
+		Title:         strings.TrimSpace(in.Title),
+		Description:   strings.TrimSpace(in.Description),
+		Author:        strings.TrimSpace(in.Author),
+		PublishedYear: in.PublishedYear,
+	}
+	var fields []FieldError
+
+	if strings.TrimSpace(in.ISBN) == "" {
+		fields = append(fields, FieldError{Field: "isbn", Code: "required", Message: "isbn is required"})
+	} else if normalized, err := isbn.Normalize(in.ISBN); err != nil {
+		fields = append(fields, FieldError{Field: "isbn", Code: isbnErrorCode(err), Message: err.Error()})
+	} else {
+		out.ISBN = normalized
+	}
+
+	fields = appendTextErrors(fields, "title", out.Title, MaxTitleLength)
+	fields = appendTextErrors(fields, "description", out.Description, MaxDescriptionLength)
+
+	if strings.TrimSpace(in.Language) != "" {
+		if config, ok := lang.Config(in.Language); ok {
+			out.Language = config
+		} else {
+			fields = append(fields, languageError(in.Language))
+		}
+	}
+
+	if utf8.RuneCountInString(out.Author) > MaxAuthorLength {
+		fields = append(fields, FieldError{
+			Field:   "author",
+			Code:    "too_long",
+			Message: fmt.Sprintf("author must be at most %d characters", MaxAuthorLength),
+		})
+	}
+
+	for _, g := range in.Genres {
+		genre := normalizeGenre(g)
+		if genre != "" && !slices.Contains(out.Genres, genre) {
+			out.Genres = append(out.Genres, genre)
+		}
+		if utf8.RuneCountInString(genre) > MaxGenreLength {
+			fields = append(fields, FieldError{
+				Field:   "genres",
+				Code:    "too_long",
+				Message: fmt.Sprintf("each genre must be at most %d characters", MaxGenreLength),
+			})
+			break
+		}
+	}
+	if len(out.Genres) > MaxGenres {
+		fields = append(fields, FieldError{
+			Field:   "genres",
+			Code:    "too_many",
+			Message: fmt.Sprintf("a book can have at most %d genres", MaxGenres),
+		})
+	}
+
+	if out.PublishedYear < 0 || out.PublishedYear > MaxPublishedYear {
+		fields = append(fields, FieldError{
+			Field:   "published_year",
+			Code:    "out_of_range",
+			Message: fmt.Sprintf("published_year must be between 1 and %d", MaxPublishedYear),
+		})
+	}
+
+	if len(fields) > 0 {
+		return BookInput{}, &Error{
+			Kind:    KindValidation,
+			Code:    "invalid_book",
+			Message: "book failed validation",
+			Fields:  fields,
+		}
+	}
+	return out, nil
+}
+
+// normalizeLookupISBN normalises an ISBN used to look a book up.
+func normalizeLookupISBN(s string) (string, error) {
+	normalized, err := isbn.Normalize(s)
+	if err != nil {
+		return "", &Error{
+			Kind:    KindValidation,
+			Code:    "invalid_isbn",
+			Message: err.Error(),
+			Fields:  []FieldError{{Field: "isbn", Code: isbnErrorCode(err), Message: err.Error()}},
+		}
+	}
+	return normalized, nil
+}
+
+func appendTextErrors(fields []FieldError, name, value string, maxLen int) []FieldError {
+	switch {
+	case value == "":
+		return append(fields, FieldError{Field: name, Code: "required", Message: name + " is required"})
+	case utf8.RuneCountInString(value) > maxLen:
+		return append(fields, FieldError{
+			Field:   name,
+			Code:    "too_long",
+			Message: fmt.Sprintf("%s must be at most %d characters", name, maxLen),
+		})
+	}
+	return fields
+}
+
+func isbnErrorCode(err error) string {
+	switch {
+	case errors.Is(err, isbn.ErrChecksum):
+		return "invalid_checksum"
+	case errors.Is(err, isbn.ErrLength):
+		return "invalid_length"
+	default:
+		return "invalid_format"
+	}
+}
+
+func languageError(value string) FieldError {
+	return FieldError{Field: "language", Code: "unsupported_language", Message: fmt.Sprintf("language %q is not supported", value)}
+}
+
+// normalizeGenre lowercases a genre and collapses its whitespace, so
+// "Science  Fiction" and "science fiction" are the same facet value.
+func normalizeGenre(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBook(t *testing.T) {
+	t.Run("Normalises input", func(t *testing.T) {
+		got, err := ValidateBook(BookInput{
+			ISBN:        "0-14-143951-3",
+			Title:       "  Pride and Prejudice ",
+			Description: "A novel of manners.\n",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, BookInput{
+			ISBN:        "9780141439518",
+			Title:       "Pride and Prejudice",
+			Description: "A novel of manners.",
+		}, got)
+	})
+
+	t.Run("Reports every invalid field", func(t *testing.T) {
+		_, err := ValidateBook(BookInput{
+			ISBN:        "978-0-14-143951-9",
+			Title:       " ",
+			Description: strings.Repeat("a", MaxDescriptionLength+1),
+		})
+
+		require.ErrorIs(t, err, ErrValidation)
+		domainErr, ok := AsError(err)
+		require.True(t, ok)
+		assert.Equal(t, []FieldError{
+			{Field: "isbn", Code: "invalid_checksum", Message: "isbn check digit is wrong"},
+			{Field: "title", Code: "required", Message: "title is required"},
+			{Field: "description", Code: "too_long", Message: "description must be at most 8000 characters"},
+		}, domainErr.Fields)
+	})
+
+	t.Run("Normalises the language", func(t *testing.T) {
+		got, err := ValidateBook(BookInput{ISBN: "9780141439518", Title: "t", Description: "d", Language: "ES"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "spanish", got.Language)
+	})
+
+	t.Run("Rejects an unsupported language", func(t *testing.T) {
+		_, err := ValidateBook(BookInput{ISBN: "9780141439518", Title: "t", Description: "d", Language: "tlh"})
+
+		domainErr, ok := AsError(err)
+		require.True(t, ok)
+		assert.Equal(t, []FieldError{
+			{Field: "language", Code: "unsupported_language", Message: `language "tlh" is not supported`},
+		}, domainErr.Fields)
+	})
+
+	t.Run("Normalises metadata", func(t *testing.T) {
+		got, err := ValidateBook(BookInput{
+			ISBN:          "9780141439518",
+			Title:         "t",
+			Description:   "d",
+			Author:        " Jane Austen ",
+			Genres:        []string{"Romance", " romance", "", "Classic  Fiction"},
+			PublishedYear: 1813,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Jane Austen", got.Author)
+		assert.Equal(t, []string{"romance", "classic fiction"}, got.Genres)
+		assert.Equal(t, 1813, got.PublishedYear)
+	})
+
+	t.Run("Rejects invalid metadata", func(t *testing.T) {
+		_, err := ValidateBook(BookInput{
+			ISBN:          "9780141439518",
+			Title:         "t",
+			Description:   "d",
+			Genres:        []string{strings.Repeat("g", MaxGenreLength+1)},
+			PublishedYear: -1,
+		})
+
+		domainErr, ok := AsError(err)
+		require.True(t, ok)
+		require.Len(t, domainErr.Fields, 2)
+		assert.Equal(t, "genres", domainErr.Fields[0].Field)
+		assert.Equal(t, "published_year", domainErr.Fields[1].Field)
+	})
+
+	t.Run("Missing ISBN", func(t *testing.T) {
+		_, err := ValidateBook(BookInput{Title: "t", Description: "d"})
+
+		domainErr, ok := AsError(err)
+		require.True(t, ok)
+		assert.Equal(t, "isbn", domainErr.Fields[0].Field)
+		assert.Equal(t, "required", domainErr.Fields[0].Code)
+	})
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+	"github.com/nmdra/Semantic-Search/internal/vectorstore"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pgvector/pgvector-go"
+)
+
+// VectorStore stores books with their embeddings and finds the nearest ones.
+// *repository.Queries implements it on Postgres and vectorstore.Memory in
+// process. Postgres reports duplicate ISBNs as unique violations and
+// vectorstore.Memory as vectorstore.ErrDuplicateISBN.
+type VectorStore interface {
+	InsertBook(ctx context.Context, arg repository.InsertBookParams) (int32, error)
+	SearchBooks(ctx context.Context, arg repository.SearchBooksParams) ([]repository.SearchBooksRow, error)
+}
+
+var _ VectorStore = (*repository.Queries)(nil)
+
+// addToVectors embeds the book and stores it in Vectors. Works and webhook
+// events live in Postgres, so the book gets neither.
+func (s *BookService) addToVectors(ctx context.Context, book BookInput) error {
+	vector, err := s.Embedder.Embed(ctx, book.Description)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return embeddingError(err)
+	}
+
+	meta := newBookMetadata(book)
+	_, err = s.Vectors.InsertBook(ctx, repository.InsertBookParams{
+		Isbn:          pgtype.Text{String: book.ISBN, Valid: true},
+		Title:         book.Title,
+		Description:   book.Description,
+		Embedding:     pgvector.NewVector(vector),
+		ContentHash:   pgtype.Text{String: contentHash(book.Description), Valid: true},
+		Language:      s.bookLanguage(book),
+		Author:        meta.author,
+		Genres:        meta.genres,
+		PublishedYear: meta.publishedYear,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.Is(err, vectorstore.ErrDuplicateISBN) || errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return Conflict("book_exists", fmt.Sprintf("book with isbn %s already exists", book.ISBN))
+		}
+		s.Logger.Error("Failed to insert book", "isbn", book.ISBN, "title", book.Title, "error", err)
+		return fmt.Errorf("failed to insert book: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/nmdra/Semantic-Search/internal/vectorstore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOfflineService() *BookService {
+	return &BookService{
+		Embedder: &fakeEmbedder{},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Vectors:  vectorstore.NewMemory(vectorstore.Options{Seed: 1}),
+	}
+}
+
+func TestOfflineAddAndSearch(t *testing.T) {
+	ctx := context.Background()
+	s := newOfflineService()
+
+	books := []BookInput{
+		{ISBN: "9780441172719", Title: "Dune", Description: "Politics and spice on a desert planet.", Genres: []string{"science fiction"}},
+		{ISBN: "9780547928227", Title: "The Hobbit", Description: "A burglar, thirteen dwarves and a dragon."},
+		{ISBN: "9780141439518", Title: "Pride and Prejudice", Description: "A novel of manners in Regency society."},
+	}
+	for _, b := range books {
+		require.NoError(t, s.AddBook(ctx, b))
+	}
+
+	err := s.AddBook(ctx, books[0])
+	require.ErrorIs(t, err, ErrConflict)
+
+	res, err := s.SearchBooks(ctx, "a dragon hoard", SearchOptions{})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.Results)
+	assert.Equal(t, "The Hobbit", res.Results[0].Title)
+	assert.Greater(t, res.Results[0].Similarity, 0.9)
+
+	res, err = s.SearchBooks(ctx, "desert", SearchOptions{Filters: Filters{Genre: "science fiction"}})
+	require.NoError(t, err)
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "9780441172719", res.Results[0].ISBN)
+}
+
+func TestOfflineSearchNeedsDatabaseFor(t *testing.T) {
+	s := newOfflineService()
+
+	for name, opts := range map[string]SearchOptions{
+		"Facets":   {Facets: []string{"genre"}},
+		"Collapse": {Collapse: CollapseWork},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.SearchBooks(context.Background(), "dragon", opts)
+			require.ErrorIs(t, err, ErrValidation)
+			domainErr, _ := AsError(err)
+			assert.Equal(t, "unsupported_option", domainErr.Code)
+		})
+	}
+}
+
+func TestOfflineSearchSkipsFeedbackAndFallback(t *testing.T) {
+	ctx := context.Background()
+	s := newOfflineService()
+	s.FeedbackWeight = 0.5
+	s.FallbackToText = true
+	require.NoError(t, s.AddBook(ctx, BookInput{ISBN: "9780547928227", Title: "The Hobbit", Description: "A burglar, thirteen dwarves and a dragon."}))
+
+	res, err := s.SearchBooks(ctx, "dragon", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, res.Results[0].Similarity, res.Results[0].Score, "feedback needs the database")
+
+	s.Embedder = &fakeEmbedder{err: errors.New("quota exceeded")}
+	_, err = s.SearchBooks(ctx, "dragon", SearchOptions{})
+	require.ErrorIs(t, err, ErrUnavailable, "the text fallback needs the database")
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+	"github.com/nmdra/Semantic-Search/internal/vectorindex"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pgvector/pgvector-go"
+)
+
+// Defaults for matching a new edition to an existing work.
+const (
+	DefaultWorkDistance        = 0.1
+	DefaultWorkTitleSimilarity = 0.5
+)
+
+// workCandidates is the number of nearest books checked for a matching work.
+const workCandidates = 5
+
+// CollapseWork collapses search results to one per work.
+const CollapseWork = "work"
+
+// WorkMatcher groups the editions of a book, published under different
+// ISBNs, into one work. A new book joins the work of a nearby book whose
+// embedding is within MaxDistance (cosine distance) and whose work title is
+// at least MinTitleSimilarity alike once normalised; otherwise it starts a
+// work of its own. Matching is not serialised, so editions ingested at the
+// same moment can end up in separate works; SuspectedDuplicates lists them.
+type WorkMatcher struct {
+	MaxDistance        float64 // zero means DefaultWorkDistance
+	MinTitleSimilarity float64 // zero means DefaultWorkTitleSimilarity
+	// Matryoshka takes the nearby books from the matryoshka index instead of
+	// the full vector index, so that a catalog searching in that mode can
+	// drop the latter.
+	Matryoshka bool
+}
+
+// Assign returns the work of the book with the given ISBN, title and
+// embedding, creating a new work if none matches.
+func (m WorkMatcher) Assign(ctx context.Context, q WorkMatchStore, isbn, title string, vector []float32) (int32, error) {
+	key := titleKey(title)
+	candidates, err := m.candidates(ctx, q, isbn, key, vector)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find matching works: %w", err)
+	}
+	for _, c := range candidates {
+		if c.TitleSimilarity >= m.minTitleSimilarity() {
+			return c.WorkID, nil
+		}
+	}
+
+	id, err := q.CreateWork(ctx, repository.CreateWorkParams{Title: title, TitleKey: key})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create work: %w", err)
+	}
+	return id, nil
+}
+
+// candidates returns the works of the books nearest to vector.
+func (m WorkMatcher) candidates(ctx context.Context, q WorkMatchStore, isbn, key string, vector []float32) ([]repository.FindWorkCandidatesRow, error) {
+	if !m.Matryoshka {
+		return q.FindWorkCandidates(ctx, repository.FindWorkCandidatesParams{
+			Embedding:   pgvector.NewVector(vector),
+			TitleKey:    key,
+			Isbn:        pgtype.Text{String: isbn, Valid: isbn != ""},
+			MaxDistance: m.maxDistance(),
+			MaxResults:  workCandidates,
+		})
+	}
+	rows, err := q.FindWorkCandidatesMatryoshka(ctx, repository.FindWorkCandidatesMatryoshkaParams{
+		Isbn:        pgtype.Text{String: isbn, Valid: isbn != ""},
+		Embedding:   pgvector.NewVector(vector),
+		Candidates:  workCandidates * coarseCandidateFactor,
+		TitleKey:    key,
+		MaxDistance: m.maxDistance(),
+		MaxResults:  workCandidates,
+	})
+	candidates := make([]repository.FindWorkCandidatesRow, 0, len(rows))
+	for _, row := range rows {
+		candidates = append(candidates, repository.FindWorkCandidatesRow(row))
+	}
+	return candidates, err
+}
+
+func (m WorkMatcher) maxDistance() float64 {
+	if m.MaxDistance > 0 {
+		return m.MaxDistance
+	}
+	return DefaultWorkDistance
+}
+
+func (m WorkMatcher) minTitleSimilarity() float64 {
+	if m.MinTitleSimilarity > 0 {
+		return m.MinTitleSimilarity
+	}
+	return DefaultWorkTitleSimilarity
+}
+
+var parenthesised = regexp.MustCompile(`\([^)]*\)`)
+
+// titleKey normalises a title for matching editions: subtitles after a colon
+// and parenthesised notes such as "(2nd Edition)" are dropped, and case and
+// punctuation are ignored. Migration 000016 computes the same key in SQL.
+func titleKey(title string) string {
+	title, _, _ = strings.Cut(title, ":")
+	title = parenthesised.ReplaceAllString(strings.ToLower(title), " ")
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// Edition is another book of the same work as a collapsed search result.
+// It is keyed like the BookWithSimilarity and TextSearchHit it is nested in.
+type Edition struct {
+	ID            int32
+	ISBN          string
+	Title         string
+	PublishedYear int `json:",omitempty"`
+}
+
+// collapseByWork keeps the first, best ranked, item of each work. Items
+// without a work are books that are not matched yet and are always kept.
+func collapseByWork[T any](items []T, work func(T) int32) []T {
+	seen := make(map[int32]bool)
+	collapsed := items[:0:0]
+	for _, item := range items {
+		id := work(item)
+		if id != 0 {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		}
+		collapsed = append(collapsed, item)
+	}
+	return collapsed
+}
+
+// editions lists the books of the given works, keyed by work.
+func (s *BookService) editions(ctx context.Context, workIDs []int32) (map[int32][]Edition, error) {
+	if len(workIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := s.Repository.ListWorkEditions(ctx, workIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list editions: %w", err)
+	}
+
+	editions := make(map[int32][]Edition)
+	for _, row := range rows {
+		editions[row.WorkID.Int32] = append(editions[row.WorkID.Int32], Edition{
+			ID:            row.ID,
+			ISBN:          row.Isbn.String,
+			Title:         row.Title,
+			PublishedYear: int(row.PublishedYear.Int32),
+		})
+	}
+	return editions, nil
+}
+
+// otherEditions returns the editions of a work except the book itself.
+func otherEditions(editions []Edition, bookID int32) []Edition {
+	var others []Edition
+	for _, e := range editions {
+		if e.ID != bookID {
+			others = append(others, e)
+		}
+	}
+	return others
+}
+
+// WorkBook is one side of a suspected duplicate.
+type WorkBook struct {
+	ID     int32  `json:"id"`
+	ISBN   string `json:"isbn"`
+	Title  string `json:"title"`
+	WorkID int32  `json:"work_id,omitempty"`
+}
+
+// DuplicatePair is two books in different works that look like editions of
+// the same work.
+type DuplicatePair struct {
+	Book      WorkBook `json:"book"`
+	Duplicate WorkBook `json:"duplicate"`
+	// Distance is the cosine distance between the two embeddings.
+	Distance float64 `json:"distance"`
+	// TitleSimilarity is the trigram similarity of the two titles.
+	TitleSimilarity float64 `json:"title_similarity"`
+}
+
+// DuplicatesPage is one page of suspected duplicates.
+type DuplicatesPage struct {
+	Pairs []DuplicatePair `json:"pairs"`
+	// Next is the after cursor of the next page, zero on the last one.
+	Next int32 `json:"next,omitempty"`
+}
+
+// SuspectedDuplicates checks up to limit books, those with IDs after the
+// given one, for a book of another work whose embedding is within
+// maxDistance, and lists the pairs found, closest first. Pages bound the
+// index scans a call runs; pass Next as after to check the following books.
+// Zero maxDistance means twice the ingest threshold, to include the near
+// misses.
+func (s *BookService) SuspectedDuplicates(ctx context.Context, maxDistance float64, after int32, limit int) (DuplicatesPage, error) {
+	ctx, span := tracer.Start(ctx, "BookService.SuspectedDuplicates")
+	defer span.End()
+
+	if maxDistance < 0 || maxDistance > 2 {
+		return DuplicatesPage{}, Validation("invalid_max_distance", "max_distance must be between 0 and 2")
+	}
+	if maxDistance == 0 {
+		maxDistance = 2 * s.Works.maxDistance()
+	}
+
+	rows, err := s.duplicates(ctx, repository.SuspectedDuplicatesParams{
+		MaxDistance: maxDistance,
+		After:       after,
+		MaxBooks:    int32(limit),
+	})
+	if err != nil {
+		return DuplicatesPage{}, fmt.Errorf("failed to find duplicates: %w", err)
+	}
+
+	page := DuplicatesPage{Pairs: []DuplicatePair{}}
+	// Every book of the page comes back, matched or not, so a full page
+	// means there may be more.
+	if len(rows) == limit {
+		page.Next = rows[len(rows)-1].ID
+	}
+	for _, row := range rows {
+		if !row.Distance.Valid || row.Distance.Float64 > maxDistance || row.Mirrored {
+			continue
+		}
+		page.Pairs = append(page.Pairs, DuplicatePair{
+			Book:            WorkBook{ID: row.ID, ISBN: row.Isbn.String, Title: row.Title, WorkID: row.WorkID.Int32},
+			Duplicate:       WorkBook{ID: row.DuplicateID.Int32, ISBN: row.DuplicateIsbn.String, Title: row.DuplicateTitle.String, WorkID: row.DuplicateWorkID.Int32},
+			Distance:        row.Distance.Float64,
+			TitleSimilarity: row.TitleSimilarity.Float64,
+		})
+	}
+	slices.SortStableFunc(page.Pairs, func(a, b DuplicatePair) int {
+		return cmp.Compare(a.Distance, b.Distance)
+	})
+	return page, nil
+}
+
+// duplicates runs the SuspectedDuplicates query on the index that semantic
+// search uses, matryoshka or else the full vectors.
+func (s *BookService) duplicates(ctx context.Context, arg repository.SuspectedDuplicatesParams) ([]repository.SuspectedDuplicatesRow, error) {
+	if s.VectorIndex != vectorindex.Matryoshka {
+		return s.Repository.SuspectedDuplicates(ctx, arg)
+	}
+	rows, err := s.Repository.SuspectedDuplicatesMatryoshka(ctx, repository.SuspectedDuplicatesMatryoshkaParams(arg))
+	pairs := make([]repository.SuspectedDuplicatesRow, 0, len(rows))
+	for _, row := range rows {
+		pairs = append(pairs, repository.SuspectedDuplicatesRow(row))
+	}
+	return pairs, err
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTitleKey(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Dune", "dune"},
+		{"Dune (40th Anniversary Edition)", "dune"},
+		{"The Hobbit: or There and Back Again", "the hobbit"},
+		{"Harry Potter and the Philosopher's Stone", "harry potter and the philosopher s stone"},
+		{"  Cien años de soledad ", "cien años de soledad"},
+		{"1984", "1984"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			assert.Equal(t, tt.want, titleKey(tt.title))
+		})
+	}
+}
+
+func TestCollapseByWork(t *testing.T) {
+	results := []BookWithSimilarity{
+		{ID: 1, WorkID: 10},
+		{ID: 2, WorkID: 20},
+		{ID: 3, WorkID: 10},
+		{ID: 4}, // not matched yet
+		{ID: 5},
+		{ID: 6, WorkID: 20},
+	}
+
+	collapsed := collapseByWork(results, func(r BookWithSimilarity) int32 { return r.WorkID })
+
+	var ids []int32
+	for _, r := range collapsed {
+		ids = append(ids, r.ID)
+	}
+	assert.Equal(t, []int32{1, 2, 4, 5}, ids)
+	assert.Len(t, results, 6, "input must not be modified")
+	assert.Equal(t, int32(3), results[2].ID)
+}
+
+func TestOtherEditions(t *testing.T) {
+	editions := []Edition{{ID: 1, ISBN: "a"}, {ID: 2, ISBN: "b"}, {ID: 3, ISBN: "c"}}
+
+	assert.Equal(t, []Edition{{ID: 1, ISBN: "a"}, {ID: 3, ISBN: "c"}}, otherEditions(editions, 2))
+	assert.Nil(t, otherEditions(editions[:1], 1))
+}
+
+func TestSuspectedDuplicates(t *testing.T) {
+	ctx := context.Background()
+	s, store, _ := newTestService()
+	near := func(id int32, distance float64) repository.SuspectedDuplicatesRow {
+		return repository.SuspectedDuplicatesRow{
+			DuplicateID: pgtype.Int4{Int32: id, Valid: true},
+			Distance:    pgtype.Float8{Float64: distance, Valid: true},
+		}
+	}
+	store.duplicates = []repository.SuspectedDuplicatesRow{near(2, 0.08), near(1, 0.08), near(1, 0.5), {}, near(6, 0.01), near(3, 0.15)}
+	for i := range store.duplicates {
+		store.duplicates[i].ID = int32(i + 1)
+	}
+	store.duplicates[1].Mirrored = true // 1 and 2 are each other's nearest
+	// Book 4 has no neighbour in the index, which does not end the page.
+	pairs := func(page DuplicatesPage) [][2]int32 {
+		var ids [][2]int32
+		for _, p := range page.Pairs {
+			ids = append(ids, [2]int32{p.Book.ID, p.Duplicate.ID})
+		}
+		return ids
+	}
+
+	page, err := s.SuspectedDuplicates(ctx, 0.2, 0, 5)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]int32{{5, 6}, {1, 2}}, pairs(page), "closest first, each pair once, far ones dropped")
+	assert.Equal(t, int32(5), page.Next)
+
+	page, err = s.SuspectedDuplicates(ctx, 0.2, page.Next, 5)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]int32{{6, 3}}, pairs(page))
+	assert.Zero(t, page.Next, "the last page")
+}
+
+func TestSuspectedDuplicatesValidation(t *testing.T) {
+	s := &BookService{}
+
+	for _, maxDistance := range []float64{-0.1, 2.5} {
+		_, err := s.SuspectedDuplicates(context.Background(), maxDistance, 0, 10)
+
+		require.ErrorIs(t, err, ErrValidation)
+		domainErr, _ := AsError(err)
+		assert.Equal(t, "invalid_max_distance", domainErr.Code)
+	}
+}
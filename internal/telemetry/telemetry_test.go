@@ -0,0 +1,43 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestSetup(t *testing.T) {
+	t.Run("Stdout exporter", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout})
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(context.Background(), "span")
+		assert.True(t, span.SpanContext().IsValid(), "expected a recording span")
+		span.End()
+
+		assert.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("W3C propagation", func(t *testing.T) {
+		_, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+		require.NoError(t, err)
+
+		carrier := propagation.MapCarrier{
+			"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		}
+		ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+
+		out := propagation.MapCarrier{}
+		otel.GetTextMapPropagator().Inject(ctx, out)
+		assert.Equal(t, carrier["traceparent"], out["traceparent"])
+	})
+
+	t.Run("Unknown exporter", func(t *testing.T) {
+		_, err := Setup(context.Background(), Config{Exporter: "zipkin"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,276 @@
+package vectorindex
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
+)
+
+// benchTable holds the copy of the embeddings that Bench indexes, so that
+// benchmarking never touches the indexes serving books.
+const benchTable = "vectorindex_bench"
+
+// BenchOptions configures Bench.
+type BenchOptions struct {
+	Modes        []Mode // modes to compare; empty means all
+	Rows         int    // embedded books copied for the benchmark; zero means all
+	Queries      int    // books sampled as queries; zero means 100
+	K            int    // neighbours per query; zero means 10
+	EfSearch     int    // hnsw.ef_search; zero means 100
+	RerankFactor int    // candidates per neighbour re-ranked; zero means 4
+}
+
+func (o BenchOptions) withDefaults() BenchOptions {
+	if len(o.Modes) == 0 {
+		o.Modes = Modes
+	}
+	if o.Queries <= 0 {
+		o.Queries = 100
+	}
+	if o.K <= 0 {
+		o.K = 10
+	}
+	if o.EfSearch <= 0 {
+		o.EfSearch = 100
+	}
+	if o.RerankFactor <= 0 {
+		o.RerankFactor = 4
+	}
+	return o
+}
+
+// BenchResult measures one mode.
+type BenchResult struct {
+	Mode       Mode
+	Rows       int
+	BuildTime  time.Duration
+	IndexBytes int64
+	// Recall is the mean fraction of the exact K nearest neighbours found.
+	Recall float64
+	P50    time.Duration
+	P95    time.Duration
+}
+
+// Bench copies the embeddings into a scratch table and, for each mode,
+// builds its index and measures build time, size, recall@K against exact
+// search and query latency. Each sampled book is a query; it is not counted
+// as its own neighbour.
+func Bench(ctx context.Context, pool *pgxpool.Pool, opts BenchOptions) ([]BenchResult, error) {
+	opts = opts.withDefaults()
+
+	// Session settings and the scratch table need a single connection.
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := createBenchTable(ctx, conn.Conn(), opts.Rows)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// Drop the table even if ctx was cancelled.
+		_, _ = conn.Exec(context.WithoutCancel(ctx), "DROP TABLE IF EXISTS "+benchTable)
+	}()
+
+	ids, queries, err := sampleQueries(ctx, conn.Conn(), opts.Queries)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without an index, every search is exact.
+	exact := make([][]int32, len(queries))
+	for i, q := range queries {
+		if exact[i], err = nearest(ctx, conn.Conn(), Vector, q, ids[i], opts); err != nil {
+			return nil, fmt.Errorf("exact search failed: %w", err)
+		}
+	}
+
+	for _, setting := range []string{
+		fmt.Sprintf("SET hnsw.ef_search = %d", opts.EfSearch),
+		"SET enable_seqscan = off", // use the index even on a small sample
+	} {
+		if _, err := conn.Exec(ctx, setting); err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		_, _ = conn.Exec(context.WithoutCancel(ctx), "RESET ALL")
+	}()
+
+	results := make([]BenchResult, 0, len(opts.Modes))
+	for _, m := range opts.Modes {
+		result, err := benchMode(ctx, conn.Conn(), m, ids, queries, exact, opts)
+		if err != nil {
+			return nil, err
+		}
+		result.Rows = rows
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func createBenchTable(ctx context.Context, conn *pgx.Conn, rows int) (int, error) {
+	if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS "+benchTable); err != nil {
+		return 0, err
+	}
+	// CREATE TABLE AS takes no parameters.
+	limit := "ALL"
+	if rows > 0 {
+		limit = strconv.Itoa(rows)
+	}
+	tag, err := conn.Exec(ctx, fmt.Sprintf(`
+		CREATE UNLOGGED TABLE %s AS
+		SELECT id, embedding, subvector(embedding, 1, %d)::vector(%d) AS embedding_short FROM books
+		WHERE embedding IS NOT NULL
+		ORDER BY id
+		LIMIT %s
+	`, benchTable, ShortDimensions, ShortDimensions, limit))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy embeddings: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, fmt.Errorf("no embedded books to benchmark")
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func sampleQueries(ctx context.Context, conn *pgx.Conn, n int) ([]int32, []pgvector.Vector, error) {
+	rows, err := conn.Query(ctx, fmt.Sprintf("SELECT id, embedding FROM %s ORDER BY random() LIMIT $1", benchTable), n)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sample queries: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int32
+	var vectors []pgvector.Vector
+	for rows.Next() {
+		var id int32
+		var v pgvector.Vector
+		if err := rows.Scan(&id, &v); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		vectors = append(vectors, v)
+	}
+	return ids, vectors, rows.Err()
+}
+
+func benchMode(ctx context.Context, conn *pgx.Conn, m Mode, ids []int32, queries []pgvector.Vector, exact [][]int32, opts BenchOptions) (BenchResult, error) {
+	result := BenchResult{Mode: m}
+	name := benchTable + "_" + string(m)
+
+	start := time.Now()
+	if _, err := conn.Exec(ctx, m.createIndexSQL(name, benchTable, false)); err != nil {
+		return result, fmt.Errorf("failed to build %s index: %w", m, err)
+	}
+	result.BuildTime = time.Since(start)
+	defer func() {
+		_, _ = conn.Exec(context.WithoutCancel(ctx), "DROP INDEX IF EXISTS "+name)
+	}()
+
+	if err := conn.QueryRow(ctx, "SELECT pg_relation_size($1::regclass)", name).Scan(&result.IndexBytes); err != nil {
+		return result, err
+	}
+
+	latencies := make([]time.Duration, len(queries))
+	var recall float64
+	for i, q := range queries {
+		start := time.Now()
+		found, err := nearest(ctx, conn, m, q, ids[i], opts)
+		if err != nil {
+			return result, fmt.Errorf("%s search failed: %w", m, err)
+		}
+		latencies[i] = time.Since(start)
+		recall += overlap(found, exact[i])
+	}
+	result.Recall = recall / float64(len(queries))
+
+	slices.Sort(latencies)
+	result.P50 = percentile(latencies, 0.50)
+	result.P95 = percentile(latencies, 0.95)
+	return result, nil
+}
+
+// searchSQL finds the $2 nearest rows of table to $1 through the mode's
+// index; modes that re-rank take $3 candidates. It mirrors the search
+// queries in db/books.sql.
+func (m Mode) searchSQL(table string) string {
+	switch m {
+	case HalfVec:
+		return fmt.Sprintf("SELECT id FROM %s ORDER BY embedding::halfvec(%d) <=> $1::halfvec(%d) LIMIT $2", table, Dimensions, Dimensions)
+	case Binary:
+		return fmt.Sprintf(`
+			SELECT id FROM (
+				SELECT id, embedding FROM %s
+				ORDER BY binary_quantize(embedding)::bit(%d) <~> binary_quantize($1::vector)
+				LIMIT $3
+			) AS candidates
+			ORDER BY embedding <=> $1::vector
+			LIMIT $2`, table, Dimensions)
+	case Matryoshka:
+		return fmt.Sprintf(`
+			SELECT id FROM (
+				SELECT id, embedding FROM %s
+				ORDER BY embedding_short <=> subvector($1::vector, 1, %d)::vector(%d)
+				LIMIT $3
+			) AS candidates
+			ORDER BY embedding <=> $1::vector
+			LIMIT $2`, table, ShortDimensions, ShortDimensions)
+	default:
+		return fmt.Sprintf("SELECT id FROM %s ORDER BY embedding <=> $1 LIMIT $2", table)
+	}
+}
+
+// nearest returns the K nearest ids to q other than self, searching the
+// bench table as mode m does.
+func nearest(ctx context.Context, conn *pgx.Conn, m Mode, q pgvector.Vector, self int32, opts BenchOptions) ([]int32, error) {
+	args := []any{q, opts.K + 1}
+	if m.reranks() {
+		args = append(args, (opts.K+1)*opts.RerankFactor)
+	}
+	rows, err := conn.Query(ctx, m.searchSQL(benchTable), args...)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil {
+		return nil, err
+	}
+
+	ids = slices.DeleteFunc(ids, func(id int32) bool { return id == self })
+	if len(ids) > opts.K {
+		ids = ids[:opts.K]
+	}
+	return ids, nil
+}
+
+// overlap is the fraction of want found in got.
+func overlap(got, want []int32) float64 {
+	if len(want) == 0 {
+		return 1
+	}
+	var hits int
+	for _, id := range want {
+		if slices.Contains(got, id) {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(want))
+}
+
+// percentile returns the p-th percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p * float64(len(sorted)-1))
+	return sorted[i]
+}
@@ -0,0 +1,193 @@
+// Package vectorindex manages the HNSW indexes that serve semantic search.
+// Besides the float32 index on books.embedding, the embeddings can be
+// indexed as halfvec, halving the index, or binary quantized to one bit per
+// dimension, shrinking it 32 times at the cost of a re-rank with the full
+// vectors. Those are expression indexes over the same column, so a catalog
+// can switch modes without rewriting its rows; the rows keep their float32
+// embeddings, so the modes shrink the index but not the table. The
+// matryoshka mode indexes books.embedding_short, the leading dimensions of
+// each embedding, and also re-ranks with the full vectors.
+package vectorindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Mode selects how embeddings are indexed and searched.
+type Mode string
+
+const (
+	// Vector indexes the float32 embeddings with vector_cosine_ops.
+	Vector Mode = "vector"
+	// HalfVec indexes the embeddings cast to half precision.
+	HalfVec Mode = "halfvec"
+	// Binary indexes binary quantized embeddings by Hamming distance;
+	// candidates are re-ranked by cosine distance of the full vectors.
+	Binary Mode = "binary"
+	// Matryoshka indexes the truncated embeddings in books.embedding_short;
+	// candidates are re-ranked by cosine distance of the full vectors.
+	Matryoshka Mode = "matryoshka"
+)
+
+// Modes lists every mode, the default first.
+var Modes = []Mode{Vector, HalfVec, Binary, Matryoshka}
+
+// Dimensions of books.embedding and books.embedding_short.
+const (
+	Dimensions      = 768
+	ShortDimensions = 256
+)
+
+// HNSW build parameters, as in migration 000003.
+const (
+	hnswM              = 16
+	hnswEfConstruction = 128
+)
+
+// ParseMode returns the mode named s.
+func ParseMode(s string) (Mode, error) {
+	for _, m := range Modes {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown vector index mode %q (want %s)", s, joinModes())
+}
+
+func joinModes() string {
+	names := make([]string, len(Modes))
+	for i, m := range Modes {
+		names[i] = string(m)
+	}
+	return strings.Join(names, "|")
+}
+
+// IndexName is the name of the mode's index on books.
+func (m Mode) IndexName() string {
+	if m == Vector {
+		return "idx_books_embedding_hnsw"
+	}
+	return "idx_books_embedding_" + string(m)
+}
+
+// indexExpression is the indexed expression and operator class. The search
+// queries in db/books.sql must use the same expression to be served by it.
+func (m Mode) indexExpression() string {
+	switch m {
+	case HalfVec:
+		return fmt.Sprintf("(embedding::halfvec(%d)) halfvec_cosine_ops", Dimensions)
+	case Binary:
+		return fmt.Sprintf("(binary_quantize(embedding)::bit(%d)) bit_hamming_ops", Dimensions)
+	case Matryoshka:
+		return "embedding_short vector_cosine_ops"
+	default:
+		return "embedding vector_cosine_ops"
+	}
+}
+
+// reranks reports whether the mode's index only finds candidates, which are
+// then ordered by the full vectors.
+func (m Mode) reranks() bool {
+	return m == Binary || m == Matryoshka
+}
+
+func (m Mode) createIndexSQL(name, table string, concurrently bool) string {
+	var opt string
+	if concurrently {
+		opt = "CONCURRENTLY "
+	}
+	return fmt.Sprintf("CREATE INDEX %sIF NOT EXISTS %s ON %s USING hnsw (%s) WITH (m = %d, ef_construction = %d)",
+		opt, name, table, m.indexExpression(), hnswM, hnswEfConstruction)
+}
+
+// Create builds the mode's index on books without blocking writes. It can
+// take a long time on a large catalog; an interrupted build leaves an
+// invalid index that Drop removes. The matryoshka index is built after
+// Backfill has filled embedding_short.
+func Create(ctx context.Context, pool *pgxpool.Pool, m Mode) error {
+	if m == Matryoshka {
+		if _, err := Backfill(ctx, pool); err != nil {
+			return err
+		}
+	}
+	if _, err := pool.Exec(ctx, m.createIndexSQL(m.IndexName(), "books", true)); err != nil {
+		return fmt.Errorf("failed to create %s index: %w", m, err)
+	}
+	return nil
+}
+
+// backfillBatch is the range of book IDs Backfill updates per statement, so
+// that each holds its row locks briefly.
+const backfillBatch = 1000
+
+// Backfill fills books.embedding_short for books embedded before migration
+// 000017 added it, one range of IDs at a time, and returns the number of
+// books filled. The migration's trigger fills it on every later write, so
+// books added meanwhile need no backfill and a rerun finds nothing to do.
+func Backfill(ctx context.Context, pool *pgxpool.Pool) (int64, error) {
+	var maxID int32
+	if err := pool.QueryRow(ctx, "SELECT coalesce(max(id), 0) FROM books").Scan(&maxID); err != nil {
+		return 0, fmt.Errorf("failed to backfill embedding_short: %w", err)
+	}
+	update := fmt.Sprintf(`
+		UPDATE books
+		SET embedding_short = subvector(embedding, 1, %d)::vector(%d)
+		WHERE id > $1 AND id <= $2
+		  AND embedding IS NOT NULL
+		  AND embedding_short IS NULL
+	`, ShortDimensions, ShortDimensions)
+
+	var total int64
+	for from := int32(0); from < maxID; from += backfillBatch {
+		tag, err := pool.Exec(ctx, update, from, from+backfillBatch)
+		if err != nil {
+			return total, fmt.Errorf("failed to backfill embedding_short: %w", err)
+		}
+		total += tag.RowsAffected()
+	}
+	return total, nil
+}
+
+// Drop removes the mode's index from books without blocking writes.
+func Drop(ctx context.Context, pool *pgxpool.Pool, m Mode) error {
+	if _, err := pool.Exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+m.IndexName()); err != nil {
+		return fmt.Errorf("failed to drop %s index: %w", m, err)
+	}
+	return nil
+}
+
+// Status describes a mode's index on books.
+type Status struct {
+	Mode   Mode
+	Exists bool
+	Valid  bool  // false while a concurrent build runs or after it failed
+	Bytes  int64 // on-disk size
+}
+
+// Stat reports the index of every mode.
+func Stat(ctx context.Context, pool *pgxpool.Pool) ([]Status, error) {
+	statuses := make([]Status, 0, len(Modes))
+	for _, m := range Modes {
+		s := Status{Mode: m}
+		err := pool.QueryRow(ctx, `
+			SELECT i.indisvalid, pg_relation_size(i.indexrelid)
+			FROM pg_index AS i
+			JOIN pg_class AS c ON c.oid = i.indexrelid
+			WHERE c.relname = $1
+		`, m.IndexName()).Scan(&s.Valid, &s.Bytes)
+		switch {
+		case err == nil:
+			s.Exists = true
+		case !errors.Is(err, pgx.ErrNoRows):
+			return nil, fmt.Errorf("failed to stat %s index: %w", m, err)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,70 @@
+package vectorindex
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, m := range Modes {
+		got, err := ParseMode(string(m))
+		require.NoError(t, err)
+		assert.Equal(t, m, got)
+	}
+
+	_, err := ParseMode("pq")
+	assert.ErrorContains(t, err, "vector|halfvec|binary|matryoshka")
+}
+
+func TestIndexName(t *testing.T) {
+	// The float32 index keeps the name given by migration 000003.
+	assert.Equal(t, "idx_books_embedding_hnsw", Vector.IndexName())
+	assert.Equal(t, "idx_books_embedding_halfvec", HalfVec.IndexName())
+	assert.Equal(t, "idx_books_embedding_binary", Binary.IndexName())
+	assert.Equal(t, "idx_books_embedding_matryoshka", Matryoshka.IndexName())
+}
+
+func TestCreateIndexSQL(t *testing.T) {
+	assert.Equal(t,
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_books_embedding_halfvec ON books USING hnsw ((embedding::halfvec(768)) halfvec_cosine_ops) WITH (m = 16, ef_construction = 128)",
+		HalfVec.createIndexSQL(HalfVec.IndexName(), "books", true))
+	assert.Equal(t,
+		"CREATE INDEX IF NOT EXISTS b ON t USING hnsw (embedding vector_cosine_ops) WITH (m = 16, ef_construction = 128)",
+		Vector.createIndexSQL("b", "t", false))
+	assert.Equal(t,
+		"CREATE INDEX IF NOT EXISTS b ON t USING hnsw (embedding_short vector_cosine_ops) WITH (m = 16, ef_construction = 128)",
+		Matryoshka.createIndexSQL("b", "t", false))
+}
+
+// The planner only uses an expression index for the same expression.
+func TestSearchSQLUsesIndexExpression(t *testing.T) {
+	for _, m := range Modes {
+		t.Run(string(m), func(t *testing.T) {
+			expr, _, _ := strings.Cut(m.indexExpression(), " ")
+			expr = strings.TrimSuffix(strings.TrimPrefix(expr, "("), ")")
+			assert.Contains(t, m.searchSQL("t"), expr)
+		})
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	assert.Equal(t, 1.0, overlap([]int32{3, 2, 1}, []int32{1, 2, 3}))
+	assert.Equal(t, 0.5, overlap([]int32{1, 9}, []int32{1, 2}))
+	assert.Equal(t, 0.0, overlap(nil, []int32{1}))
+	assert.Equal(t, 1.0, overlap(nil, nil))
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 0.50))
+	assert.Equal(t, 95*time.Millisecond, percentile(sorted, 0.95))
+	assert.Zero(t, percentile(nil, 0.5))
+}
@@ -0,0 +1,283 @@
+package vectorstore
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// ErrDimension is returned for vectors whose length differs from those
+// already in the graph, such as queries embedded by a different model.
+var ErrDimension = errors.New("vector dimension mismatch")
+
+// HNSW is a hierarchical navigable small world graph over unit vectors,
+// searched by cosine distance (Malkov & Yashunin, 2016). It is not safe for
+// concurrent use; Memory guards it.
+type HNSW struct {
+	m              int // neighbours per node above layer 0; 2m on layer 0
+	efConstruction int
+
+	nodes    []hnswNode
+	dim      int // length of every vector, set by the first one
+	entry    int // index into nodes, -1 if empty
+	maxLevel int
+	levelMul float64
+	rng      *rand.Rand
+}
+
+type hnswNode struct {
+	id      int32
+	vec     []float32 // normalised
+	friends [][]int   // per layer, indexes into nodes
+}
+
+// NewHNSW returns an empty graph. m is the number of links per node (16 is
+// typical) and efConstruction the candidate list size while inserting; both
+// trade build time and memory for recall, as in pgvector.
+func NewHNSW(m, efConstruction int, seed uint64) *HNSW {
+	m = max(m, 2)
+	return &HNSW{
+		m:              m,
+		efConstruction: max(efConstruction, m),
+		entry:          -1,
+		levelMul:       1 / math.Log(float64(m)),
+		rng:            rand.New(rand.NewPCG(seed, seed)),
+	}
+}
+
+// Len returns the number of vectors in the graph.
+func (h *HNSW) Len() int { return len(h.nodes) }
+
+// checkDim fails unless vectors of length n can join or search the graph.
+func (h *HNSW) checkDim(n int) error {
+	if len(h.nodes) > 0 && n != h.dim {
+		return fmt.Errorf("%w: got %d dimensions, want %d", ErrDimension, n, h.dim)
+	}
+	return nil
+}
+
+// Insert adds vec under id. The vector is normalised, so only its direction
+// counts, and must have as many dimensions as the vectors already added.
+// Ids are not checked for uniqueness.
+func (h *HNSW) Insert(id int32, vec []float32) error {
+	if err := h.checkDim(len(vec)); err != nil {
+		return err
+	}
+	level := int(-math.Log(1-h.rng.Float64()) * h.levelMul)
+	n := len(h.nodes)
+	h.nodes = append(h.nodes, hnswNode{
+		id:      id,
+		vec:     normalize(vec),
+		friends: make([][]int, level+1),
+	})
+	if h.entry < 0 {
+		h.entry, h.maxLevel, h.dim = n, level, len(vec)
+		return nil
+	}
+
+	q := h.nodes[n].vec
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	eps := []int{ep}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		found := h.searchLayer(q, eps, h.efConstruction, l)
+		neighbours := nearestOf(found, h.m)
+		h.nodes[n].friends[l] = neighbours
+		for _, f := range neighbours {
+			h.link(f, n, l)
+		}
+		eps = eps[:0]
+		for _, c := range found {
+			eps = append(eps, c.node)
+		}
+	}
+
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = n, level
+	}
+	return nil
+}
+
+// link adds to's edge to from on layer l, keeping from's closest links if it
+// has too many.
+func (h *HNSW) link(from, to, l int) {
+	friends := append(h.nodes[from].friends[l], to)
+	limit := h.m
+	if l == 0 {
+		limit = 2 * h.m
+	}
+	if len(friends) > limit {
+		candidates := make([]candidate, len(friends))
+		for i, f := range friends {
+			candidates[i] = candidate{node: f, dist: h.distance(h.nodes[from].vec, f)}
+		}
+		friends = nearestOf(candidates, limit)
+	}
+	h.nodes[from].friends[l] = friends
+}
+
+// Result is a search hit.
+type Result struct {
+	ID       int32
+	Distance float64 // cosine distance, 0 for identical directions
+}
+
+// Search returns up to k ids nearest to q, nearest first. ef is the size of
+// the candidate list on the bottom layer; larger values improve recall.
+func (h *HNSW) Search(q []float32, k, ef int) ([]Result, error) {
+	if h.entry < 0 || k <= 0 {
+		return nil, nil
+	}
+	if err := h.checkDim(len(q)); err != nil {
+		return nil, err
+	}
+	q = normalize(q)
+
+	ep := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	found := h.searchLayer(q, []int{ep}, max(ef, k), 0)
+
+	results := make([]Result, 0, min(k, len(found)))
+	for _, c := range found[:min(k, len(found))] {
+		results = append(results, Result{ID: h.nodes[c.node].id, Distance: c.dist})
+	}
+	return results, nil
+}
+
+// greedy walks layer l from ep towards q and returns the closest node found.
+func (h *HNSW) greedy(q []float32, ep, l int) int {
+	best := h.distance(q, ep)
+	for changed := true; changed; {
+		changed = false
+		for _, f := range h.nodes[ep].friends[l] {
+			if d := h.distance(q, f); d < best {
+				ep, best, changed = f, d, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns the ef nodes of layer l closest to q reachable from
+// eps, nearest first.
+func (h *HNSW) searchLayer(q []float32, eps []int, ef, l int) []candidate {
+	visited := make(map[int]bool, ef*4)
+	var frontier minHeap // closest unexpanded first
+	var found maxHeap    // farthest kept first
+	for _, ep := range eps {
+		if visited[ep] {
+			continue
+		}
+		visited[ep] = true
+		c := candidate{node: ep, dist: h.distance(q, ep)}
+		heap.Push(&frontier, c)
+		heap.Push(&found, c)
+	}
+	for found.Len() > ef {
+		heap.Pop(&found)
+	}
+
+	for frontier.Len() > 0 {
+		c := heap.Pop(&frontier).(candidate)
+		if found.Len() >= ef && c.dist > found[0].dist {
+			break
+		}
+		for _, f := range h.nodes[c.node].friends[l] {
+			if visited[f] {
+				continue
+			}
+			visited[f] = true
+			d := h.distance(q, f)
+			if found.Len() < ef || d < found[0].dist {
+				heap.Push(&frontier, candidate{node: f, dist: d})
+				heap.Push(&found, candidate{node: f, dist: d})
+				if found.Len() > ef {
+					heap.Pop(&found)
+				}
+			}
+		}
+	}
+
+	sorted := make([]candidate, found.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(&found).(candidate)
+	}
+	return sorted
+}
+
+func (h *HNSW) distance(q []float32, node int) float64 {
+	return cosineDistance(q, h.nodes[node].vec)
+}
+
+// cosineDistance of two normalised vectors.
+func cosineDistance(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return 1 - dot
+}
+
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	scale := 1 / math.Sqrt(norm)
+	for i, x := range v {
+		out[i] = float32(float64(x) * scale)
+	}
+	return out
+}
+
+type candidate struct {
+	node int
+	dist float64
+}
+
+// nearestOf returns the nodes of the k closest candidates.
+func nearestOf(candidates []candidate, k int) []int {
+	sorted := append([]candidate(nil), candidates...)
+	heap.Init((*minHeap)(&sorted))
+	nodes := make([]int, 0, min(k, len(sorted)))
+	for len(nodes) < k && len(sorted) > 0 {
+		nodes = append(nodes, heap.Pop((*minHeap)(&sorted)).(candidate).node)
+	}
+	return nodes
+}
+
+type minHeap []candidate
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type maxHeap []candidate
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,231 @@
+// Package vectorstore keeps books and their embeddings in process, indexed
+// by a pure-Go HNSW graph, for running semantic search without Postgres or
+// pgvector: at the edge, from a snapshot exported from Postgres, and in
+// tests.
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pgvector/pgvector-go"
+)
+
+// Options configure the HNSW graph of a Memory store. Zero values take the
+// defaults of pgvector: m 16, ef_construction 64 and ef_search 40.
+type Options struct {
+	M              int
+	EfConstruction int
+	EfSearch       int
+	Seed           uint64 // for the random layer assignment
+}
+
+func (o Options) withDefaults() Options {
+	if o.M <= 0 {
+		o.M = 16
+	}
+	if o.EfConstruction <= 0 {
+		o.EfConstruction = 64
+	}
+	if o.EfSearch <= 0 {
+		o.EfSearch = 40
+	}
+	return o
+}
+
+// ErrDuplicateISBN is returned when adding a book whose ISBN is already
+// stored.
+var ErrDuplicateISBN = errors.New("isbn already stored")
+
+// Book is a book as stored in memory.
+type Book struct {
+	ID            int32
+	ISBN          string
+	Title         string
+	Description   string
+	Embedding     []float32
+	WorkID        int32
+	Language      string
+	Author        string
+	Genres        []string
+	PublishedYear int
+}
+
+// Memory is an in-process book store. It implements service.VectorStore,
+// so a BookService can search and add books without Postgres. It is safe
+// for concurrent use.
+type Memory struct {
+	opts Options
+
+	mu     sync.RWMutex
+	graph  *HNSW
+	books  map[int32]*Book
+	isbns  map[string]int32
+	nextID int32
+}
+
+// NewMemory returns an empty store.
+func NewMemory(opts Options) *Memory {
+	opts = opts.withDefaults()
+	return &Memory{
+		opts:   opts,
+		graph:  NewHNSW(opts.M, opts.EfConstruction, opts.Seed),
+		books:  make(map[int32]*Book),
+		isbns:  make(map[string]int32),
+		nextID: 1,
+	}
+}
+
+// Len returns the number of books in the store.
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.books)
+}
+
+// Add stores a book under its own ID, as when loading books exported from
+// Postgres. Books cannot be replaced; a second book with the same ID or
+// ISBN is rejected, as is an embedding with a different number of
+// dimensions than the books already stored.
+func (m *Memory) Add(book Book) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.add(book)
+}
+
+func (m *Memory) add(book Book) error {
+	if _, ok := m.books[book.ID]; ok {
+		return fmt.Errorf("book %d already stored", book.ID)
+	}
+	if _, ok := m.isbns[book.ISBN]; ok && book.ISBN != "" {
+		return fmt.Errorf("%w: %s", ErrDuplicateISBN, book.ISBN)
+	}
+	if err := m.graph.Insert(book.ID, book.Embedding); err != nil {
+		return fmt.Errorf("book %d: %w", book.ID, err)
+	}
+	if book.ISBN != "" {
+		m.isbns[book.ISBN] = book.ID
+	}
+	m.books[book.ID] = &book
+	m.nextID = max(m.nextID, book.ID+1)
+	return nil
+}
+
+// InsertBook stores a new book and returns its ID, like the Postgres query.
+// A duplicate ISBN fails with ErrDuplicateISBN.
+func (m *Memory) InsertBook(ctx context.Context, arg repository.InsertBookParams) (int32, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book := Book{
+		ID:            m.nextID,
+		ISBN:          arg.Isbn.String,
+		Title:         arg.Title,
+		Description:   arg.Description,
+		Embedding:     slices.Clone(arg.Embedding.Slice()),
+		WorkID:        arg.WorkID.Int32,
+		Language:      arg.Language,
+		Author:        arg.Author.String,
+		Genres:        slices.Clone(arg.Genres),
+		PublishedYear: int(arg.PublishedYear.Int32),
+	}
+	if err := m.add(book); err != nil {
+		return 0, err
+	}
+	return book.ID, nil
+}
+
+// SearchBooks returns the books nearest to arg.Embedding, like the Postgres
+// query. Unfiltered searches walk the HNSW graph; filtered ones compare
+// every matching book, so they are exact and never miss matches that the
+// graph search would have crowded out.
+func (m *Memory) SearchBooks(ctx context.Context, arg repository.SearchBooksParams) ([]repository.SearchBooksRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	k := int(arg.MaxResults)
+	var ids []int32
+	if !filtered(arg) {
+		hits, err := m.graph.Search(arg.Embedding.Slice(), k, m.opts.EfSearch)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range hits {
+			ids = append(ids, r.ID)
+		}
+	} else {
+		if err := m.graph.checkDim(len(arg.Embedding.Slice())); err != nil {
+			return nil, err
+		}
+		ids = m.scan(arg, k)
+	}
+
+	rows := make([]repository.SearchBooksRow, 0, len(ids))
+	for _, id := range ids {
+		book := m.books[id]
+		rows = append(rows, repository.SearchBooksRow{
+			ID:          book.ID,
+			Isbn:        pgtype.Text{String: book.ISBN, Valid: book.ISBN != ""},
+			Title:       book.Title,
+			Description: book.Description,
+			Embedding:   pgvector.NewVector(book.Embedding),
+			WorkID:      pgtype.Int4{Int32: book.WorkID, Valid: book.WorkID != 0},
+		})
+	}
+	return rows, nil
+}
+
+func filtered(arg repository.SearchBooksParams) bool {
+	return arg.Genre.Valid || arg.Author.Valid || arg.Language.Valid || arg.Decade.Valid
+}
+
+// scan returns the k books matching the filters nearest to arg.Embedding.
+func (m *Memory) scan(arg repository.SearchBooksParams, k int) []int32 {
+	q := normalize(arg.Embedding.Slice())
+	var hits []Result
+	for _, book := range m.books {
+		if matches(book, arg) {
+			hits = append(hits, Result{ID: book.ID, Distance: cosineDistance(q, normalize(book.Embedding))})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Distance < hits[j].Distance })
+
+	ids := make([]int32, 0, min(k, len(hits)))
+	for _, h := range hits[:min(k, len(hits))] {
+		ids = append(ids, h.ID)
+	}
+	return ids
+}
+
+// matches applies the drill-down filters as the SearchBooks query does.
+func matches(book *Book, arg repository.SearchBooksParams) bool {
+	if arg.Genre.Valid && !slices.Contains(book.Genres, arg.Genre.String) {
+		return false
+	}
+	if arg.Author.Valid && !strings.EqualFold(book.Author, arg.Author.String) {
+		return false
+	}
+	if arg.Language.Valid && book.Language != arg.Language.String {
+		return false
+	}
+	if arg.Decade.Valid && (book.PublishedYear == 0 || int32(book.PublishedYear/10*10) != arg.Decade.Int32) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package vectorstore
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// snapshotVersion changes whenever the snapshot layout does.
+const snapshotVersion = 1
+
+// snapshot is the on-disk form of a Memory store. The graph is stored with
+// the books, so loading does not rebuild it.
+type snapshot struct {
+	Version int
+	Options Options
+	NextID  int32
+	Books   []Book
+
+	// Graph nodes in insertion order, by book ID, with their links per
+	// layer as indexes into Nodes.
+	Nodes    []int32
+	Friends  [][][]int
+	Entry    int
+	MaxLevel int
+}
+
+// Save writes a snapshot of the store to w.
+func (m *Memory) Save(w io.Writer) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snap := snapshot{
+		Version:  snapshotVersion,
+		Options:  m.opts,
+		NextID:   m.nextID,
+		Books:    make([]Book, 0, len(m.graph.nodes)),
+		Nodes:    make([]int32, 0, len(m.graph.nodes)),
+		Friends:  make([][][]int, 0, len(m.graph.nodes)),
+		Entry:    m.graph.entry,
+		MaxLevel: m.graph.maxLevel,
+	}
+	for _, node := range m.graph.nodes {
+		snap.Books = append(snap.Books, *m.books[node.id])
+		snap.Nodes = append(snap.Nodes, node.id)
+		snap.Friends = append(snap.Friends, node.friends)
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := gob.NewEncoder(bw).Encode(snap); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return bw.Flush()
+}
+
+// Load reads a store saved with Save.
+func Load(r io.Reader) (*Memory, error) {
+	var snap snapshot
+	if err := gob.NewDecoder(bufio.NewReader(r)).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	if len(snap.Nodes) != len(snap.Books) || len(snap.Friends) != len(snap.Books) {
+		return nil, fmt.Errorf("corrupt snapshot: %d books, %d nodes", len(snap.Books), len(snap.Nodes))
+	}
+
+	m := NewMemory(snap.Options)
+	m.nextID = snap.NextID
+	g := m.graph
+	g.entry, g.maxLevel = snap.Entry, snap.MaxLevel
+	g.nodes = make([]hnswNode, len(snap.Nodes))
+	for i := range snap.Books {
+		book := snap.Books[i]
+		if book.ID != snap.Nodes[i] {
+			return nil, fmt.Errorf("corrupt snapshot: node %d is book %d, not %d", i, snap.Nodes[i], book.ID)
+		}
+		if len(book.Embedding) != len(snap.Books[0].Embedding) {
+			return nil, fmt.Errorf("corrupt snapshot: book %d has %d dimensions, not %d", book.ID, len(book.Embedding), len(snap.Books[0].Embedding))
+		}
+		if len(snap.Friends[i]) == 0 || len(snap.Friends[i]) > snap.MaxLevel+1 {
+			return nil, fmt.Errorf("corrupt snapshot: node %d has %d layers", i, len(snap.Friends[i]))
+		}
+		for l, friends := range snap.Friends[i] {
+			for _, f := range friends {
+				// A link on layer l must point at a node that reaches it.
+				if f < 0 || f >= len(snap.Nodes) || len(snap.Friends[f]) <= l {
+					return nil, fmt.Errorf("corrupt snapshot: node %d links to %d on layer %d", i, f, l)
+				}
+			}
+		}
+		g.nodes[i] = hnswNode{id: book.ID, vec: normalize(book.Embedding), friends: snap.Friends[i]}
+		m.books[book.ID] = &book
+		if book.ISBN != "" {
+			m.isbns[book.ISBN] = book.ID
+		}
+	}
+	if len(g.nodes) > 0 {
+		if g.entry < 0 || g.entry >= len(g.nodes) || len(snap.Friends[g.entry]) != g.maxLevel+1 {
+			return nil, fmt.Errorf("corrupt snapshot: entry point %d", g.entry)
+		}
+		g.dim = len(g.nodes[0].vec)
+	}
+	return m, nil
+}
+
+// SaveFile writes a snapshot to path atomically: readers see the old file
+// or the new one, never a partial write.
+func (m *Memory) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := m.Save(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile reads a snapshot written by SaveFile.
+func LoadFile(path string) (*Memory, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return Load(f)
+}
@@ -0,0 +1,250 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomVectors(n, dim int, seed uint64) [][]float32 {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	return vectors
+}
+
+func exactNearest(vectors [][]float32, q []float32, k int) []int32 {
+	q = normalize(q)
+	hits := make([]Result, len(vectors))
+	for i, v := range vectors {
+		hits[i] = Result{ID: int32(i), Distance: cosineDistance(q, normalize(v))}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Distance < hits[j].Distance })
+	ids := make([]int32, k)
+	for i := range ids {
+		ids[i] = hits[i].ID
+	}
+	return ids
+}
+
+func TestHNSWRecall(t *testing.T) {
+	const k = 10
+	vectors := randomVectors(2000, 32, 1)
+	g := NewHNSW(16, 64, 1)
+	for i, v := range vectors {
+		require.NoError(t, g.Insert(int32(i), v))
+	}
+	require.Equal(t, len(vectors), g.Len())
+
+	var found, total int
+	for _, q := range randomVectors(50, 32, 2) {
+		want := exactNearest(vectors, q, k)
+		got, err := g.Search(q, k, 64)
+		require.NoError(t, err)
+		require.Len(t, got, k)
+		for i := 1; i < len(got); i++ {
+			assert.LessOrEqual(t, got[i-1].Distance, got[i].Distance, "results must be nearest first")
+		}
+		for _, r := range got {
+			for _, id := range want {
+				if r.ID == id {
+					found++
+				}
+			}
+		}
+		total += k
+	}
+
+	recall := float64(found) / float64(total)
+	assert.Greater(t, recall, 0.9, "recall@%d", k)
+}
+
+func TestHNSWSmallGraphs(t *testing.T) {
+	g := NewHNSW(16, 64, 1)
+	got, err := g.Search([]float32{1, 0}, 5, 40)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	require.NoError(t, g.Insert(7, []float32{1, 0}))
+	got, err = g.Search([]float32{2, 0}, 5, 40)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, int32(7), got[0].ID)
+	assert.InDelta(t, 0, got[0].Distance, 1e-6)
+
+	require.NoError(t, g.Insert(8, []float32{0, 1}))
+	got, err = g.Search([]float32{0, 3}, 5, 40)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, int32(8), got[0].ID)
+
+	assert.ErrorIs(t, g.Insert(9, []float32{1, 0, 0}), ErrDimension)
+	_, err = g.Search([]float32{1}, 5, 40)
+	assert.ErrorIs(t, err, ErrDimension)
+	assert.Equal(t, 2, g.Len())
+}
+
+func insertParams(isbn, title string, vec []float32) repository.InsertBookParams {
+	return repository.InsertBookParams{
+		Isbn:        pgtype.Text{String: isbn, Valid: true},
+		Title:       title,
+		Description: title,
+		Embedding:   pgvector.NewVector(vec),
+		Language:    "english",
+		Genres:      []string{},
+	}
+}
+
+func TestMemoryInsertAndSearch(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(Options{Seed: 1})
+
+	id1, err := m.InsertBook(ctx, insertParams("9780000000001", "North", []float32{1, 0, 0}))
+	require.NoError(t, err)
+	id2, err := m.InsertBook(ctx, insertParams("9780000000002", "East", []float32{0, 1, 0}))
+	require.NoError(t, err)
+	assert.NotEqual(t, id1, id2)
+
+	_, err = m.InsertBook(ctx, insertParams("9780000000001", "North again", []float32{1, 0, 0}))
+	assert.ErrorIs(t, err, ErrDuplicateISBN)
+	_, err = m.InsertBook(ctx, insertParams("9780000000003", "Up", []float32{0, 0, 1, 0}))
+	assert.ErrorIs(t, err, ErrDimension)
+	assert.Equal(t, 2, m.Len())
+
+	_, err = m.SearchBooks(ctx, repository.SearchBooksParams{Embedding: pgvector.NewVector([]float32{1, 0}), MaxResults: 1})
+	assert.ErrorIs(t, err, ErrDimension)
+	_, err = m.SearchBooks(ctx, repository.SearchBooksParams{Embedding: pgvector.NewVector([]float32{1, 0}), MaxResults: 1, Language: pgtype.Text{String: "english", Valid: true}})
+	assert.ErrorIs(t, err, ErrDimension, "filtered searches check too")
+
+	rows, err := m.SearchBooks(ctx, repository.SearchBooksParams{
+		Embedding:  pgvector.NewVector([]float32{0.1, 1, 0}),
+		MaxResults: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, id2, rows[0].ID)
+	assert.Equal(t, "East", rows[0].Title)
+	assert.Equal(t, "9780000000002", rows[0].Isbn.String)
+	assert.Equal(t, []float32{0, 1, 0}, rows[0].Embedding.Slice())
+}
+
+func TestMemorySearchFilters(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(Options{Seed: 1})
+	books := []Book{
+		{ID: 1, ISBN: "a", Embedding: []float32{1, 0}, Language: "english", Author: "Ursula K. Le Guin", Genres: []string{"fantasy"}, PublishedYear: 1968},
+		{ID: 2, ISBN: "b", Embedding: []float32{1, 0.1}, Language: "spanish", Genres: []string{"fantasy"}, PublishedYear: 1982},
+		{ID: 3, ISBN: "c", Embedding: []float32{0, 1}, Language: "english", Genres: []string{"science fiction"}, PublishedYear: 1969},
+	}
+	for _, b := range books {
+		require.NoError(t, m.Add(b))
+	}
+	require.Error(t, m.Add(Book{ID: 1, Embedding: []float32{1, 1}}), "IDs are unique")
+
+	tests := []struct {
+		name string
+		arg  repository.SearchBooksParams
+		want []int32
+	}{
+		{"Genre", repository.SearchBooksParams{Genre: pgtype.Text{String: "fantasy", Valid: true}}, []int32{1, 2}},
+		{"Author ignores case", repository.SearchBooksParams{Author: pgtype.Text{String: "ursula k. le guin", Valid: true}}, []int32{1}},
+		{"Language", repository.SearchBooksParams{Language: pgtype.Text{String: "english", Valid: true}}, []int32{1, 3}},
+		{"Decade", repository.SearchBooksParams{Decade: pgtype.Int4{Int32: 1960, Valid: true}}, []int32{1, 3}},
+		{"Combined", repository.SearchBooksParams{
+			Genre:    pgtype.Text{String: "fantasy", Valid: true},
+			Language: pgtype.Text{String: "spanish", Valid: true},
+		}, []int32{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.arg.Embedding = pgvector.NewVector([]float32{1, 0})
+			tt.arg.MaxResults = 10
+			rows, err := m.SearchBooks(ctx, tt.arg)
+			require.NoError(t, err)
+
+			var ids []int32
+			for _, r := range rows {
+				ids = append(ids, r.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestMemoryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m := NewMemory(Options{})
+
+	_, err := m.InsertBook(ctx, insertParams("a", "A", []float32{1}))
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = m.SearchBooks(ctx, repository.SearchBooksParams{Embedding: pgvector.NewVector([]float32{1}), MaxResults: 1})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(Options{Seed: 3})
+	for i, v := range randomVectors(300, 16, 3) {
+		require.NoError(t, m.Add(Book{ID: int32(i + 1), ISBN: fmt.Sprintf("isbn-%d", i), Title: "t", Embedding: v, Genres: []string{}}))
+	}
+
+	path := filepath.Join(t.TempDir(), "books.snap")
+	require.NoError(t, m.SaveFile(path))
+	loaded, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, m.Len(), loaded.Len())
+
+	for _, q := range randomVectors(10, 16, 4) {
+		arg := repository.SearchBooksParams{Embedding: pgvector.NewVector(q), MaxResults: 5}
+		want, err := m.SearchBooks(ctx, arg)
+		require.NoError(t, err)
+		got, err := loaded.SearchBooks(ctx, arg)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "the graph must survive the snapshot unchanged")
+	}
+
+	// New IDs continue after the loaded ones.
+	id, err := loaded.InsertBook(ctx, insertParams("new", "New", randomVectors(1, 16, 5)[0]))
+	require.NoError(t, err)
+	assert.Equal(t, int32(301), id)
+}
+
+func TestLoadRejectsGarbage(t *testing.T) {
+	_, err := Load(bytes.NewReader([]byte("not a snapshot")))
+	assert.Error(t, err)
+
+	// Node 0 reaches layer 1 but links there to node 1, which is only on
+	// layer 0.
+	snap := snapshot{
+		Version:  snapshotVersion,
+		NextID:   3,
+		Books:    []Book{{ID: 1, Embedding: []float32{1, 0}}, {ID: 2, Embedding: []float32{0, 1}}},
+		Nodes:    []int32{1, 2},
+		Friends:  [][][]int{{{1}, {1}}, {{0}}},
+		Entry:    0,
+		MaxLevel: 1,
+	}
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(snap))
+	_, err = Load(&buf)
+	assert.ErrorContains(t, err, "links to 1 on layer 1")
+}
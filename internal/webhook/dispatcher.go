@@ -0,0 +1,210 @@
+// Package webhook delivers book change events from the Postgres outbox to
+// subscribed HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Headers set on every delivery. The event ID is stable across retries and
+// replays, so receivers can use it to drop duplicates.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Envelope is the JSON body POSTed to subscribers.
+type Envelope struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatcher delivers pending webhook deliveries. Deliveries are leased with
+// FOR UPDATE SKIP LOCKED, so several dispatchers can share the outbox. Failed
+// deliveries are retried with exponential backoff and end up dead after
+// MaxAttempts; dead deliveries are only retried when replayed.
+type Dispatcher struct {
+	Queries *repository.Queries
+	Client  *http.Client // zero means http.DefaultClient
+	Logger  *slog.Logger
+
+	Concurrency  int           // parallel senders; zero means 1
+	PollInterval time.Duration // idle wait between empty polls; zero means 1s
+	MaxAttempts  int           // attempts before a delivery is dead; zero means 8
+	Timeout      time.Duration // deadline for one HTTP request; zero means 10s
+}
+
+// Run delivers events until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	concurrency := max(d.Concurrency, 1)
+	d.Logger.Info("Webhook dispatcher started", "concurrency", concurrency)
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.loop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	d.Logger.Info("Webhook dispatcher stopped")
+}
+
+func (d *Dispatcher) loop(ctx context.Context) {
+	poll := d.PollInterval
+	if poll <= 0 {
+		poll = time.Second
+	}
+
+	for {
+		processed, err := d.ProcessNext(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.Logger.Warn("Webhook delivery poll failed", "error", err)
+		}
+		if processed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(poll):
+		}
+	}
+}
+
+// ProcessNext leases and attempts at most one due delivery. It reports
+// whether a delivery was found.
+func (d *Dispatcher) ProcessNext(ctx context.Context) (bool, error) {
+	timeout := d.timeout()
+
+	deliveries, err := d.Queries.ClaimWebhookDeliveries(ctx, repository.ClaimWebhookDeliveriesParams{
+		LeaseSeconds: (2 * timeout).Seconds(),
+		BatchSize:    1,
+	})
+	if err != nil || len(deliveries) == 0 {
+		return false, err
+	}
+	delivery := deliveries[0]
+
+	sendCtx, cancel := context.WithTimeout(ctx, timeout)
+	status, err := d.send(sendCtx, delivery, time.Now())
+	cancel()
+
+	statusCode := pgtype.Int4{Int32: int32(status), Valid: status != 0}
+	if err == nil {
+		d.Logger.Debug("Delivered webhook", "delivery", delivery.ID, "event", delivery.EventType, "url", delivery.Url)
+		return true, d.Queries.MarkWebhookDelivered(ctx, repository.MarkWebhookDeliveredParams{
+			ID:             delivery.ID,
+			LastStatusCode: statusCode,
+		})
+	}
+
+	if ctx.Err() != nil {
+		// Shutting down; the lease expires and the delivery is retried.
+		return true, nil
+	}
+
+	lastError := pgtype.Text{String: err.Error(), Valid: true}
+	if int(delivery.Attempts) >= d.maxAttempts() {
+		d.Logger.Error("Webhook delivery failed permanently", "delivery", delivery.ID, "url", delivery.Url, "attempts", delivery.Attempts, "error", err)
+		return true, d.Queries.DeadLetterWebhookDelivery(ctx, repository.DeadLetterWebhookDeliveryParams{
+			ID:             delivery.ID,
+			LastStatusCode: statusCode,
+			LastError:      lastError,
+		})
+	}
+
+	delay := retryDelay(int(delivery.Attempts))
+	d.Logger.Warn("Webhook delivery failed, will retry", "delivery", delivery.ID, "url", delivery.Url, "attempt", delivery.Attempts, "delay", delay, "error", err)
+	return true, d.Queries.RetryWebhookDelivery(ctx, repository.RetryWebhookDeliveryParams{
+		DelaySeconds:   delay.Seconds(),
+		LastStatusCode: statusCode,
+		LastError:      lastError,
+		ID:             delivery.ID,
+	})
+}
+
+// send POSTs the signed event and returns the response status, or zero if
+// no response was received. Any status outside 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, delivery repository.ClaimWebhookDeliveriesRow, now time.Time) (int, error) {
+	body, err := json.Marshal(Envelope{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.EventCreatedAt.Time,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "semantic-search-webhooks/1")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderEventID, strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, now, body))
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// Drain a little so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryDelay doubles from 10s per attempt, capped at one hour.
+func retryDelay(attempt int) time.Duration {
+	delay := 10 * time.Second
+	for i := 1; i < attempt && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, time.Hour)
+}
+
+func (d *Dispatcher) timeout() time.Duration {
+	if d.Timeout > 0 {
+		return d.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (d *Dispatcher) maxAttempts() int {
+	if d.MaxAttempts > 0 {
+		return d.MaxAttempts
+	}
+	return 8
+}
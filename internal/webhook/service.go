@@ -0,0 +1,238 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+	"github.com/nmdra/Semantic-Search/internal/service"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Delivery states.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Service manages webhook subscriptions and their deliveries.
+type Service struct {
+	Queries *repository.Queries
+
+	// Resolver checks that endpoints resolve to public addresses; nil means
+	// net.DefaultResolver.
+	Resolver Resolver
+	// AllowPrivate accepts endpoints on loopback and private networks, for
+	// development.
+	AllowPrivate bool
+	// Retention is how long delivered and dead events are kept, and so how
+	// long dead deliveries can be replayed; zero means 7 days.
+	Retention time.Duration
+}
+
+type Subscription struct {
+	ID  int32  `json:"id"`
+	URL string `json:"url"`
+	// Secret is only returned when the subscription is created.
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Delivery struct {
+	ID             int64      `json:"id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	SubscriptionID int32      `json:"subscription_id"`
+	Status         string     `json:"status"`
+	Attempts       int32      `json:"attempts"`
+	LastStatusCode *int32     `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Create registers an endpoint for the given event types; none means every
+// event. A signing secret is generated if secret is empty. Endpoints must
+// resolve to public addresses unless AllowPrivate is set.
+func (s *Service) Create(ctx context.Context, endpoint, secret string, eventTypes []string) (Subscription, error) {
+	var fields []service.FieldError
+	u, err := url.Parse(endpoint)
+	switch {
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "":
+		fields = append(fields, service.FieldError{Field: "url", Code: "invalid_url", Message: "url must be an absolute http or https URL"})
+	case !s.AllowPrivate:
+		if err := checkHost(ctx, s.resolver(), u.Hostname()); errors.Is(err, ErrPrivateTarget) {
+			fields = append(fields, service.FieldError{Field: "url", Code: "private_url", Message: "url must not point to a loopback, private or link-local address"})
+		} else if err != nil {
+			fields = append(fields, service.FieldError{Field: "url", Code: "unresolvable_url", Message: "url host cannot be resolved"})
+		}
+	}
+	for _, t := range eventTypes {
+		if !slices.Contains(service.EventTypes, t) {
+			fields = append(fields, service.FieldError{Field: "event_types", Code: "unknown_event", Message: fmt.Sprintf("unknown event type %q", t)})
+		}
+	}
+	if len(fields) > 0 {
+		err := service.Validation("invalid_webhook", "webhook subscription is invalid")
+		err.Fields = fields
+		return Subscription{}, err
+	}
+
+	if secret == "" {
+		if secret, err = newSecret(); err != nil {
+			return Subscription{}, err
+		}
+	}
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	row, err := s.Queries.CreateWebhookSubscription(ctx, repository.CreateWebhookSubscriptionParams{
+		Url:        endpoint,
+		Secret:     secret,
+		EventTypes: eventTypes,
+	})
+	if err != nil {
+		return Subscription{}, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	sub := toSubscription(row)
+	sub.Secret = row.Secret
+	return sub, nil
+}
+
+func (s *Service) resolver() Resolver {
+	if s.Resolver != nil {
+		return s.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// List returns all subscriptions without their secrets.
+func (s *Service) List(ctx context.Context) ([]Subscription, error) {
+	rows, err := s.Queries.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+
+	subs := make([]Subscription, 0, len(rows))
+	for _, row := range rows {
+		subs = append(subs, toSubscription(row))
+	}
+	return subs, nil
+}
+
+// Delete removes a subscription together with its pending deliveries.
+func (s *Service) Delete(ctx context.Context, id int32) error {
+	n, err := s.Queries.DeleteWebhookSubscription(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if n == 0 {
+		return service.NotFound("webhook_not_found", fmt.Sprintf("no webhook subscription with id %d", id))
+	}
+	return nil
+}
+
+// Deliveries lists the most recent deliveries in the given state.
+func (s *Service) Deliveries(ctx context.Context, status string, limit int32) ([]Delivery, error) {
+	if status != StatusPending && status != StatusDelivered && status != StatusDead {
+		return nil, service.Validation("invalid_status", "status must be one of pending, delivered, dead")
+	}
+
+	rows, err := s.Queries.ListWebhookDeliveries(ctx, repository.ListWebhookDeliveriesParams{
+		Status:     status,
+		MaxResults: limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	deliveries := make([]Delivery, 0, len(rows))
+	for _, row := range rows {
+		d := Delivery{
+			ID:             row.ID,
+			EventID:        row.EventID,
+			EventType:      row.EventType,
+			SubscriptionID: row.SubscriptionID,
+			Status:         row.Status,
+			Attempts:       row.Attempts,
+			LastError:      row.LastError.String,
+			CreatedAt:      row.CreatedAt.Time,
+		}
+		if row.LastStatusCode.Valid {
+			d.LastStatusCode = &row.LastStatusCode.Int32
+		}
+		if row.DeliveredAt.Valid {
+			d.DeliveredAt = &row.DeliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// ReplayDelivery queues a delivery to be sent again, whatever its state.
+func (s *Service) ReplayDelivery(ctx context.Context, id int64) error {
+	n, err := s.Queries.ReplayWebhookDelivery(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to replay webhook delivery: %w", err)
+	}
+	if n == 0 {
+		return service.NotFound("delivery_not_found", fmt.Sprintf("no webhook delivery with id %d", id))
+	}
+	return nil
+}
+
+// ReplayDead queues every dead delivery of a subscription to be sent again
+// and returns how many were requeued.
+func (s *Service) ReplayDead(ctx context.Context, subscriptionID int32) (int64, error) {
+	n, err := s.Queries.ReplayDeadWebhookDeliveries(ctx, subscriptionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay webhook deliveries: %w", err)
+	}
+	return n, nil
+}
+
+// Purge deletes events older than the retention period that have no
+// pending delivery, with their deliveries, and returns how many events were
+// removed.
+func (s *Service) Purge(ctx context.Context) (int64, error) {
+	retention := s.Retention
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+	n, err := s.Queries.DeleteSettledWebhookEvents(ctx, pgtype.Timestamptz{Time: time.Now().Add(-retention), Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge webhook events: %w", err)
+	}
+	return n, nil
+}
+
+func toSubscription(row repository.WebhookSubscription) Subscription {
+	return Subscription{
+		ID:         row.ID,
+		URL:        row.Url,
+		EventTypes: row.EventTypes,
+		Active:     row.Active,
+		CreatedAt:  row.CreatedAt.Time,
+	}
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	ErrStaleSignature   = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// Sign returns the X-Webhook-Signature value for body sent at t. The
+// signature is an HMAC-SHA256 of "<unix seconds>.<body>" keyed with the
+// subscription secret, formatted as "t=<unix seconds>,v1=<hex digest>".
+// Including the timestamp lets receivers reject replayed requests.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, digest(secret, ts, body))
+}
+
+// Verify checks a signature header produced by Sign. Receivers written in Go
+// can use it directly; tolerance bounds the accepted clock difference and
+// zero disables the check.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts string
+	var sigs []string
+	for part := range strings.SplitSeq(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sigs = append(sigs, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		if skew := now.Sub(time.Unix(unix, 0)).Abs(); skew > tolerance {
+			return ErrStaleSignature
+		}
+	}
+
+	want := []byte(digest(secret, ts, body))
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), want) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func digest(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
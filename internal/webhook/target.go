@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateTarget is returned for webhook endpoints on loopback, private,
+// link-local or otherwise internal addresses, which would let subscribers
+// reach services behind the firewall.
+var ErrPrivateTarget = errors.New("webhook target is not a public address")
+
+// Resolver looks up the addresses of a host; *net.Resolver satisfies it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// publicAddr reports whether ip may receive webhooks.
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is carrier-grade NAT space (RFC 6598), internal to
+// providers and often to cloud networks.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// checkHost resolves host and fails if any of its addresses is not public.
+func checkHost(ctx context.Context, r Resolver, host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !publicAddr(ip) {
+			return ErrPrivateTarget
+		}
+		return nil
+	}
+	addrs, err := r.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, ip := range addrs {
+		if !publicAddr(ip) {
+			return ErrPrivateTarget
+		}
+	}
+	return nil
+}
+
+// NewClient returns an HTTP client for delivering webhooks that refuses to
+// connect to non-public addresses. The check runs on the address actually
+// dialled, so it also covers redirects and hosts whose DNS has changed
+// since the subscription was created. allowPrivate turns it off, for
+// development.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(addr.Addr()) {
+				return ErrPrivateTarget
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would dial the target on our behalf
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
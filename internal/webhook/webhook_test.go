@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/repository"
+	"github.com/nmdra/Semantic-Search/internal/service"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"id":1}`)
+	header := Sign("whsec_test", now, body)
+
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, header)
+	assert.NoError(t, Verify("whsec_test", header, body, 5*time.Minute, now.Add(time.Minute)))
+
+	assert.ErrorIs(t, Verify("other", header, body, 0, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("whsec_test", header, []byte(`{"id":2}`), 0, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("whsec_test", "v1=abc", body, 0, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("whsec_test", header, body, 5*time.Minute, now.Add(time.Hour)), ErrStaleSignature)
+}
+
+func TestDispatcherSend(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	delivery := repository.ClaimWebhookDeliveriesRow{
+		ID:             7,
+		EventID:        42,
+		EventType:      "book.created",
+		Payload:        []byte(`{"id":1,"isbn":"9780306406157","title":"T"}`),
+		EventCreatedAt: pgtype.Timestamptz{Time: now, Valid: true},
+		Secret:         "whsec_test",
+	}
+
+	t.Run("signed envelope", func(t *testing.T) {
+		var got *http.Request
+		var body []byte
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		d := delivery
+		d.Url = srv.URL
+		status, err := (&Dispatcher{}).send(context.Background(), d, now)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, status)
+
+		assert.Equal(t, "book.created", got.Header.Get(HeaderEvent))
+		assert.Equal(t, "42", got.Header.Get(HeaderEventID))
+		assert.Equal(t, "7", got.Header.Get(HeaderDelivery))
+		assert.NoError(t, Verify("whsec_test", got.Header.Get(HeaderSignature), body, 0, now))
+
+		var env Envelope
+		require.NoError(t, json.Unmarshal(body, &env))
+		assert.Equal(t, int64(42), env.ID)
+		assert.Equal(t, "book.created", env.Type)
+		assert.JSONEq(t, string(delivery.Payload), string(env.Data))
+	})
+
+	t.Run("private targets are refused", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		d := delivery
+		d.Url = srv.URL
+		_, err := (&Dispatcher{Client: NewClient(time.Second, false)}).send(context.Background(), d, now)
+		assert.ErrorIs(t, err, ErrPrivateTarget)
+
+		status, err := (&Dispatcher{Client: NewClient(time.Second, true)}).send(context.Background(), d, now)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, status)
+	})
+
+	t.Run("non-2xx is an error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		d := delivery
+		d.Url = srv.URL
+		status, err := (&Dispatcher{}).send(context.Background(), d, now)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadGateway, status)
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 10*time.Second, retryDelay(1))
+	assert.Equal(t, 20*time.Second, retryDelay(2))
+	assert.Equal(t, 80*time.Second, retryDelay(4))
+	assert.Equal(t, time.Hour, retryDelay(20))
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // cloud metadata
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, publicAddr(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+// staticResolver resolves every host to the same addresses.
+type staticResolver []netip.Addr
+
+func (r staticResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	return r, nil
+}
+
+func TestCreateRejectsPrivateTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		resolver staticResolver
+	}{
+		{"Loopback literal", "http://127.0.0.1:8080/hook", nil},
+		{"IPv6 loopback", "http://[::1]/hook", nil},
+		{"Metadata service", "http://169.254.169.254/latest/meta-data", nil},
+		{"Host resolving to a private address", "https://hooks.example.com/in", staticResolver{netip.MustParseAddr("10.0.0.5")}},
+		{"Host with one private address", "https://hooks.example.com/in", staticResolver{
+			netip.MustParseAddr("93.184.216.34"),
+			netip.MustParseAddr("127.0.0.1"),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{Resolver: tt.resolver}
+			_, err := s.Create(context.Background(), tt.url, "", nil)
+
+			require.ErrorIs(t, err, service.ErrValidation)
+			domainErr, _ := service.AsError(err)
+			require.Len(t, domainErr.Fields, 1)
+			assert.Equal(t, "private_url", domainErr.Fields[0].Code)
+		})
+	}
+}
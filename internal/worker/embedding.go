@@ -0,0 +1,218 @@
+// Package worker runs background jobs that are queued in Postgres.
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nmdra/Semantic-Search/internal/embed"
+	"github.com/nmdra/Semantic-Search/internal/repository"
+	"github.com/nmdra/Semantic-Search/internal/service"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pgvector/pgvector-go"
+)
+
+// Store is the storage the embedding worker runs on. *repository.Queries
+// implements it on Postgres; tests substitute an in-memory fake.
+type Store interface {
+	service.WorkMatchStore
+	service.EventStore
+	ClaimEmbeddingJobs(ctx context.Context, arg repository.ClaimEmbeddingJobsParams) ([]repository.ClaimEmbeddingJobsRow, error)
+	CompleteEmbeddingJob(ctx context.Context, arg repository.CompleteEmbeddingJobParams) (repository.CompleteEmbeddingJobRow, error)
+	ReleaseEmbeddingJob(ctx context.Context, arg repository.ReleaseEmbeddingJobParams) error
+	RetryEmbeddingJob(ctx context.Context, arg repository.RetryEmbeddingJobParams) error
+	FailEmbeddingJob(ctx context.Context, arg repository.FailEmbeddingJobParams) error
+	SetBookWork(ctx context.Context, arg repository.SetBookWorkParams) error
+}
+
+var _ Store = (*repository.Queries)(nil)
+
+// releaseDelay is how long a job waits after the embedder's circuit breaker
+// turned it away; it is about the breaker's shortest cooldown.
+const releaseDelay = 5 * time.Second
+
+// EmbeddingWorker embeds books stored by asynchronous ingest. Jobs are leased
+// with FOR UPDATE SKIP LOCKED, so any number of workers across replicas can
+// consume the same queue.
+type EmbeddingWorker struct {
+	Queries  Store
+	Embedder embed.Embedder
+	Logger   *slog.Logger
+	DB       service.TxBeginner  // optional; commits the book.embedded event with the vector
+	Works    service.WorkMatcher // groups each embedded book with its other editions
+
+	Concurrency  int           // parallel consumers; zero means 1
+	PollInterval time.Duration // idle wait between empty polls; zero means 1s
+	MaxAttempts  int           // attempts before a book is marked failed; zero means 5
+	Timeout      time.Duration // deadline for one embedding call; zero means 30s
+}
+
+// Run consumes jobs until ctx is cancelled.
+func (w *EmbeddingWorker) Run(ctx context.Context) {
+	concurrency := max(w.Concurrency, 1)
+	w.Logger.Info("Embedding worker started", "concurrency", concurrency)
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	w.Logger.Info("Embedding worker stopped")
+}
+
+func (w *EmbeddingWorker) loop(ctx context.Context) {
+	poll := w.PollInterval
+	if poll <= 0 {
+		poll = time.Second
+	}
+
+	for {
+		processed, err := w.ProcessNext(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.Logger.Warn("Embedding job poll failed", "error", err)
+		}
+		if processed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(poll):
+		}
+	}
+}
+
+// ProcessNext leases and processes at most one due job. It reports whether a
+// job was found.
+func (w *EmbeddingWorker) ProcessNext(ctx context.Context) (bool, error) {
+	timeout := w.timeout()
+
+	// Lease for longer than one attempt can take, so a live job is never
+	// handed to a second worker.
+	jobs, err := w.Queries.ClaimEmbeddingJobs(ctx, repository.ClaimEmbeddingJobsParams{
+		LeaseSeconds: (2 * timeout).Seconds(),
+		BatchSize:    1,
+	})
+	if err != nil || len(jobs) == 0 {
+		return false, err
+	}
+	job := jobs[0]
+
+	embedCtx, cancel := context.WithTimeout(ctx, timeout)
+	vector, err := w.Embedder.Embed(embedCtx, job.Description)
+	cancel()
+
+	if err == nil {
+		err = w.withTx(ctx, func(q Store) error {
+			book, err := q.CompleteEmbeddingJob(ctx, repository.CompleteEmbeddingJobParams{
+				ID:        job.ID,
+				Embedding: pgvector.NewVector(vector),
+			})
+			if err != nil {
+				return err
+			}
+			if !book.WorkID.Valid {
+				workID, err := w.Works.Assign(ctx, q, book.Isbn.String, book.Title, vector)
+				if err != nil {
+					return err
+				}
+				err = q.SetBookWork(ctx, repository.SetBookWorkParams{
+					ID:     book.ID,
+					WorkID: pgtype.Int4{Int32: workID, Valid: true},
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return service.EmitEvent(ctx, q, service.EventBookEmbedded, service.BookEvent{
+				ID:              book.ID,
+				ISBN:            book.Isbn.String,
+				Title:           book.Title,
+				EmbeddingStatus: service.EmbeddingReady,
+			})
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The lease expired and another worker finished the job first.
+			return true, nil
+		}
+		if err == nil {
+			w.Logger.Debug("Embedded book", "bookID", job.BookID, "attempt", job.Attempts)
+		}
+		return true, err
+	}
+
+	if ctx.Err() != nil {
+		// Shutting down; the lease expires and another worker retries.
+		return true, nil
+	}
+
+	if errors.Is(err, embed.ErrCircuitOpen) {
+		// The embedder was not called, so the attempt does not count
+		// towards MaxAttempts.
+		w.Logger.Debug("Embedding job released, circuit open", "bookID", job.BookID, "delay", releaseDelay)
+		return true, w.Queries.ReleaseEmbeddingJob(ctx, repository.ReleaseEmbeddingJobParams{
+			DelaySeconds: releaseDelay.Seconds(),
+			ID:           job.ID,
+		})
+	}
+
+	if int(job.Attempts) >= w.maxAttempts() {
+		w.Logger.Error("Embedding job failed permanently", "bookID", job.BookID, "attempts", job.Attempts, "error", err)
+		return true, w.Queries.FailEmbeddingJob(ctx, repository.FailEmbeddingJobParams{
+			ID:             job.ID,
+			EmbeddingError: pgtype.Text{String: service.EmbeddingFailure(err), Valid: true},
+		})
+	}
+
+	delay := retryDelay(int(job.Attempts))
+	w.Logger.Warn("Embedding job failed, will retry", "bookID", job.BookID, "attempt", job.Attempts, "delay", delay, "error", err)
+	return true, w.Queries.RetryEmbeddingJob(ctx, repository.RetryEmbeddingJobParams{
+		ID:           job.ID,
+		LastError:    pgtype.Text{String: err.Error(), Valid: true},
+		DelaySeconds: delay.Seconds(),
+	})
+}
+
+// withTx runs fn in a transaction on DB, like service.WithTx. Any Store
+// other than *repository.Queries, or a nil DB, runs fn on Queries directly.
+func (w *EmbeddingWorker) withTx(ctx context.Context, fn func(Store) error) error {
+	q, ok := w.Queries.(*repository.Queries)
+	if !ok || w.DB == nil {
+		return fn(w.Queries)
+	}
+	return service.WithTx(ctx, w.DB, q, func(q *repository.Queries) error { return fn(q) })
+}
+
+// retryDelay doubles from 5s per attempt, capped at 10 minutes.
+func retryDelay(attempt int) time.Duration {
+	delay := 5 * time.Second
+	for i := 1; i < attempt && delay < 10*time.Minute; i++ {
+		delay *= 2
+	}
+	return min(delay, 10*time.Minute)
+}
+
+func (w *EmbeddingWorker) timeout() time.Duration {
+	if w.Timeout > 0 {
+		return w.Timeout
+	}
+	return 30 * time.Second
+}
+
+func (w *EmbeddingWorker) maxAttempts() int {
+	if w.MaxAttempts > 0 {
+		return w.MaxAttempts
+	}
+	return 5
+}
@@ -0,0 +1,240 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/nmdra/Semantic-Search/internal/embed"
+	"github.com/nmdra/Semantic-Search/internal/repository"
+	"github.com/nmdra/Semantic-Search/internal/service"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJob is a queued embedding job. It is due until it is claimed, and
+// again once it has been retried or released.
+type fakeJob struct {
+	repository.ClaimEmbeddingJobsRow
+	due       bool
+	delay     float64
+	lastError string
+}
+
+// fakeStore is an in-memory Store with a queue of embedding jobs, one per
+// book; the other methods panic through the nil embedded Store.
+type fakeStore struct {
+	Store
+
+	jobs      []*fakeJob
+	embedded  map[int32][]float32 // by book ID
+	failed    map[int32]string    // embedding errors by book ID
+	bookWorks map[int32]int32     // work IDs by book ID
+	works     int32
+	events    []string
+}
+
+func newFakeStore(descriptions ...string) *fakeStore {
+	f := &fakeStore{
+		embedded:  map[int32][]float32{},
+		failed:    map[int32]string{},
+		bookWorks: map[int32]int32{},
+	}
+	for i, desc := range descriptions {
+		f.jobs = append(f.jobs, &fakeJob{
+			ClaimEmbeddingJobsRow: repository.ClaimEmbeddingJobsRow{ID: int64(i + 1), BookID: int32(i + 1), Description: desc},
+			due:                   true,
+		})
+	}
+	return f
+}
+
+func (f *fakeStore) job(id int64) (*fakeJob, int) {
+	for i, j := range f.jobs {
+		if j.ID == id {
+			return j, i
+		}
+	}
+	return nil, -1
+}
+
+func (f *fakeStore) ClaimEmbeddingJobs(ctx context.Context, arg repository.ClaimEmbeddingJobsParams) ([]repository.ClaimEmbeddingJobsRow, error) {
+	var rows []repository.ClaimEmbeddingJobsRow
+	for _, j := range f.jobs {
+		if j.due && len(rows) < int(arg.BatchSize) {
+			j.due = false
+			j.Attempts++
+			rows = append(rows, j.ClaimEmbeddingJobsRow)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) CompleteEmbeddingJob(ctx context.Context, arg repository.CompleteEmbeddingJobParams) (repository.CompleteEmbeddingJobRow, error) {
+	j, i := f.job(arg.ID)
+	if j == nil {
+		return repository.CompleteEmbeddingJobRow{}, pgx.ErrNoRows
+	}
+	f.jobs = append(f.jobs[:i], f.jobs[i+1:]...)
+	f.embedded[j.BookID] = arg.Embedding.Slice()
+	return repository.CompleteEmbeddingJobRow{
+		ID:    j.BookID,
+		Isbn:  pgtype.Text{String: "9780441172719", Valid: true},
+		Title: "Dune",
+	}, nil
+}
+
+func (f *fakeStore) ReleaseEmbeddingJob(ctx context.Context, arg repository.ReleaseEmbeddingJobParams) error {
+	j, _ := f.job(arg.ID)
+	j.Attempts--
+	j.due, j.delay = true, arg.DelaySeconds
+	return nil
+}
+
+func (f *fakeStore) RetryEmbeddingJob(ctx context.Context, arg repository.RetryEmbeddingJobParams) error {
+	j, _ := f.job(arg.ID)
+	j.due, j.delay, j.lastError = true, arg.DelaySeconds, arg.LastError.String
+	return nil
+}
+
+func (f *fakeStore) FailEmbeddingJob(ctx context.Context, arg repository.FailEmbeddingJobParams) error {
+	j, i := f.job(arg.ID)
+	f.jobs = append(f.jobs[:i], f.jobs[i+1:]...)
+	f.failed[j.BookID] = arg.EmbeddingError.String
+	return nil
+}
+
+func (f *fakeStore) FindWorkCandidates(ctx context.Context, arg repository.FindWorkCandidatesParams) ([]repository.FindWorkCandidatesRow, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) CreateWork(ctx context.Context, arg repository.CreateWorkParams) (int32, error) {
+	f.works++
+	return f.works, nil
+}
+
+func (f *fakeStore) SetBookWork(ctx context.Context, arg repository.SetBookWorkParams) error {
+	f.bookWorks[arg.ID] = arg.WorkID.Int32
+	return nil
+}
+
+func (f *fakeStore) EnqueueWebhookEvent(ctx context.Context, arg repository.EnqueueWebhookEventParams) (int64, error) {
+	f.events = append(f.events, arg.EventType)
+	return int64(len(f.events)), nil
+}
+
+// embedderFunc adapts a function to embed.Embedder.
+type embedderFunc func(ctx context.Context, input string) ([]float32, error)
+
+func (fn embedderFunc) Embed(ctx context.Context, input string) ([]float32, error) {
+	return fn(ctx, input)
+}
+
+func newTestWorker(store *fakeStore, embedder embed.Embedder) *EmbeddingWorker {
+	return &EmbeddingWorker{
+		Queries:     store,
+		Embedder:    embedder,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		MaxAttempts: 3,
+	}
+}
+
+func TestProcessNext(t *testing.T) {
+	ctx := context.Background()
+	vector := []float32{0.6, 0.8}
+	errQuota := errors.New("quota exceeded")
+
+	t.Run("Embeds the book, assigns its work and emits the event", func(t *testing.T) {
+		store := newFakeStore("Politics and spice on a desert planet.")
+		var embedded string
+		w := newTestWorker(store, embedderFunc(func(ctx context.Context, input string) ([]float32, error) {
+			embedded = input
+			return vector, nil
+		}))
+
+		processed, err := w.ProcessNext(ctx)
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		assert.Equal(t, "Politics and spice on a desert planet.", embedded)
+		assert.Equal(t, vector, store.embedded[1])
+		assert.Equal(t, int32(1), store.bookWorks[1])
+		assert.Equal(t, []string{service.EventBookEmbedded}, store.events)
+		assert.Empty(t, store.jobs)
+	})
+
+	t.Run("Reports an empty queue", func(t *testing.T) {
+		w := newTestWorker(newFakeStore(), embedderFunc(func(ctx context.Context, input string) ([]float32, error) {
+			t.Fatal("nothing to embed")
+			return nil, nil
+		}))
+
+		processed, err := w.ProcessNext(ctx)
+
+		require.NoError(t, err)
+		assert.False(t, processed)
+	})
+
+	t.Run("Retries with backoff, then fails the book", func(t *testing.T) {
+		store := newFakeStore("A burglar, thirteen dwarves and a dragon.")
+		w := newTestWorker(store, embedderFunc(func(ctx context.Context, input string) ([]float32, error) {
+			return nil, errQuota
+		}))
+
+		for attempt := 1; attempt < w.MaxAttempts; attempt++ {
+			processed, err := w.ProcessNext(ctx)
+			require.NoError(t, err)
+			assert.True(t, processed)
+			require.Len(t, store.jobs, 1)
+			assert.Equal(t, retryDelay(attempt).Seconds(), store.jobs[0].delay)
+			assert.Equal(t, errQuota.Error(), store.jobs[0].lastError)
+		}
+
+		processed, err := w.ProcessNext(ctx)
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		assert.Empty(t, store.jobs, "a failed job leaves the queue")
+		assert.Equal(t, "embedding_unavailable", store.failed[1], "a code, not the upstream message")
+		assert.Empty(t, store.embedded)
+	})
+
+	t.Run("Releases the job without an attempt while the circuit is open", func(t *testing.T) {
+		store := newFakeStore("A novel of manners in Regency society.")
+		w := newTestWorker(store, embedderFunc(func(ctx context.Context, input string) ([]float32, error) {
+			return nil, embed.ErrCircuitOpen
+		}))
+
+		for range w.MaxAttempts + 2 {
+			processed, err := w.ProcessNext(ctx)
+			require.NoError(t, err)
+			assert.True(t, processed)
+		}
+
+		require.Len(t, store.jobs, 1)
+		assert.Zero(t, store.jobs[0].Attempts)
+		assert.Equal(t, releaseDelay.Seconds(), store.jobs[0].delay)
+		assert.Empty(t, store.jobs[0].lastError)
+		assert.Empty(t, store.failed)
+	})
+
+	t.Run("Drops the result of a job replaced while embedding", func(t *testing.T) {
+		store := newFakeStore("Politics and spice on a desert planet.")
+		w := newTestWorker(store, embedderFunc(func(ctx context.Context, input string) ([]float32, error) {
+			store.jobs = nil // an upsert with a new description requeued the book
+			return vector, nil
+		}))
+
+		processed, err := w.ProcessNext(ctx)
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		assert.Empty(t, store.embedded)
+		assert.Empty(t, store.events)
+	})
+}